@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/conductorone/baton-argo-cd/pkg/client"
 	cfg "github.com/conductorone/baton-argo-cd/pkg/config"
 	"github.com/conductorone/baton-argo-cd/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/config"
@@ -52,7 +53,96 @@ func getConnector[T field.Configurable](ctx context.Context, config T) (types.Co
 	username := config.GetString(cfg.UsernameField.FieldName)
 	password := config.GetString(cfg.PasswordField.FieldName)
 	apiUrl := config.GetString(cfg.ApiUrlField.FieldName)
-	cb, err := connector.New(ctx, apiUrl, username, password)
+	dryRun := config.GetBool(cfg.DryRunField.FieldName)
+	namespace := config.GetString(cfg.NamespaceField.FieldName)
+	if namespace == "" {
+		namespace = client.ArgocdNamespace
+	}
+	opts := []client.Option{
+		client.WithDryRun(dryRun),
+		client.WithControllerWritePolicy(client.ControllerWritePolicy(config.GetString(cfg.ControllerManagedWritesField.FieldName))),
+		client.WithNamespace(namespace),
+		client.WithInstallType(
+			client.InstallType(config.GetString(cfg.InstallTypeField.FieldName)),
+			config.GetString(cfg.ArgoCDNameField.FieldName),
+		),
+	}
+
+	if emailsFile := config.GetString(cfg.AccountEmailsFileField.FieldName); emailsFile != "" {
+		emails, err := client.ReadAccountEmails(emailsFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithAccountEmails(emails))
+	}
+
+	if lifetime := config.GetString(cfg.AccountTokenLifetimeField.FieldName); lifetime != "" {
+		d, err := client.ParseTokenLifetime(lifetime)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithAccountTokenLifetime(d))
+	}
+
+	if mappingFile := config.GetString(cfg.GroupMappingFileField.FieldName); mappingFile != "" {
+		mapping, err := client.ReadGroupMapping(mappingFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithGroupMapping(mapping))
+	}
+
+	// The gitops-* flags configure the single instance of --api-url; an instances file sets them per instance.
+	var gitOps *connector.GitOpsConfig
+	if repoUrl := config.GetString(cfg.GitOpsRepoUrlField.FieldName); repoUrl != "" {
+		gitOps = &connector.GitOpsConfig{
+			RepoURL:            repoUrl,
+			Branch:             config.GetString(cfg.GitOpsBranchField.FieldName),
+			RBACConfigMapPath:  config.GetString(cfg.GitOpsRBACConfigMapPathField.FieldName),
+			ConfigMapPath:      config.GetString(cfg.GitOpsConfigMapPathField.FieldName),
+			ReviewBranchPrefix: config.GetString(cfg.GitOpsReviewBranchPrefixField.FieldName),
+		}
+	}
+
+	var instances []*connector.InstanceConfig
+	var err error
+	discover := config.GetBool(cfg.DiscoverInstancesField.FieldName)
+	if instancesFile := config.GetString(cfg.InstancesFileField.FieldName); instancesFile != "" {
+		instances, err = connector.ReadInstances(instancesFile)
+		if err != nil {
+			return nil, err
+		}
+	} else if discover {
+		// Discovery syncs several instances, so the configured one is named after its namespace.
+		instances = []*connector.InstanceConfig{{
+			Name:     namespace,
+			APIURL:   apiUrl,
+			Username: username,
+			Password: password,
+			GitOps:   gitOps,
+		}}
+	}
+	if discover {
+		for _, instance := range instances {
+			if instance.Namespace == "" {
+				instance.Namespace = namespace
+			}
+		}
+		instances, err = connector.DiscoverInstances(ctx, instances, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cb *connector.Connector
+	if instances != nil {
+		cb, err = connector.NewWithInstances(ctx, instances, opts...)
+	} else {
+		if gitOps != nil {
+			opts = append(opts, client.WithGitOps(gitOps.Repository()))
+		}
+		cb, err = connector.New(ctx, apiUrl, username, password, opts...)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Argo CD custom resources.
+const (
+	AppProjectResource     = "appprojects.argoproj.io"
+	ApplicationResource    = "applications.argoproj.io"
+	ApplicationSetResource = "applicationsets.argoproj.io"
+
+	// ApplicationSetKind is the kind of the owner reference ApplicationSets set on the Applications they generate.
+	ApplicationSetKind = "ApplicationSet"
+
+	// DefaultProject is the project of Applications that don't name one.
+	DefaultProject = "default"
+
+	// ApplicationNamespacesKey and ApplicationSetNamespacesKey are the argocd-cmd-params-cm keys listing the
+	// namespaces outside its own that Argo CD reconciles Applications and ApplicationSets in.
+	ApplicationNamespacesKey    = "application.namespaces"
+	ApplicationSetNamespacesKey = "applicationsetcontroller.namespaces"
+
+	allNamespacesFlag = "--all-namespaces"
+)
+
+// GetApplications returns the Applications this Argo CD instance manages: those in its namespace and in the
+// namespaces of application.namespaces, so instances sharing a cluster don't report each other's Applications.
+// When the client may not list them cluster-wide, only the Applications in the Argo CD namespace are returned.
+// Command: kubectl get applications.argoproj.io --all-namespaces -o json.
+// Command: kubectl get cm argocd-cmd-params-cm -n argocd -o json.
+func (c *Client) GetApplications(ctx context.Context) ([]*Application, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx, GetCommand, ApplicationResource, allNamespacesFlag, OutputFlag, JSONOutput)
+	if err != nil && strings.Contains(err.Error(), "forbidden") {
+		output, err = c.runKubectlCommandWithOutput(ctx, GetCommand, ApplicationResource, NamespaceFlag, c.namespace, OutputFlag, JSONOutput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applications: %w", err)
+	}
+
+	applications, err := c.parseApplications(output)
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := c.sourceNamespaces(ctx, ApplicationNamespacesKey)
+	if err != nil {
+		return nil, err
+	}
+	return c.managedApplications(applications, namespaces), nil
+}
+
+// managedApplications keeps the Applications in the Argo CD namespace or a namespace matching sourceNamespaces.
+func (c *Client) managedApplications(applications []*Application, sourceNamespaces []string) []*Application {
+	return slices.DeleteFunc(applications, func(app *Application) bool {
+		return !managedNamespace(app.Namespace, c.namespace, sourceNamespaces)
+	})
+}
+
+// parseApplications unmarshals a list of Application resources.
+func (c *Client) parseApplications(output []byte) ([]*Application, error) {
+	var list struct {
+		Items []struct {
+			Metadata ObjectMeta `json:"metadata"`
+			Spec     struct {
+				Project string `json:"project"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal application list: %w", err)
+	}
+
+	applications := make([]*Application, 0, len(list.Items))
+	for _, item := range list.Items {
+		app := &Application{
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			Project:   item.Spec.Project,
+		}
+		if app.Project == "" {
+			app.Project = DefaultProject
+		}
+		app.RBACObject = ApplicationRBACObject(app.Project, app.Namespace, app.Name, c.namespace)
+		for _, owner := range item.Metadata.OwnerReferences {
+			if owner.Kind == ApplicationSetKind {
+				app.ApplicationSet = owner.Name
+			}
+		}
+		applications = append(applications, app)
+	}
+	return applications, nil
+}
+
+// GetApplicationSets returns the ApplicationSets this Argo CD instance manages: those in its namespace and in the
+// namespaces of applicationsetcontroller.namespaces. When the client may not list them cluster-wide, only the
+// ApplicationSets in the Argo CD namespace are returned.
+// Command: kubectl get applicationsets.argoproj.io --all-namespaces -o json.
+// Command: kubectl get cm argocd-cmd-params-cm -n argocd -o json.
+func (c *Client) GetApplicationSets(ctx context.Context) ([]*ApplicationSet, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx, GetCommand, ApplicationSetResource, allNamespacesFlag, OutputFlag, JSONOutput)
+	if err != nil && strings.Contains(err.Error(), "forbidden") {
+		output, err = c.runKubectlCommandWithOutput(ctx, GetCommand, ApplicationSetResource, NamespaceFlag, c.namespace, OutputFlag, JSONOutput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applicationsets: %w", err)
+	}
+
+	appSets, err := c.parseApplicationSets(output)
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := c.sourceNamespaces(ctx, ApplicationSetNamespacesKey)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(appSets, func(appSet *ApplicationSet) bool {
+		return !managedNamespace(appSet.Namespace, c.namespace, namespaces)
+	}), nil
+}
+
+// parseApplicationSets unmarshals a list of ApplicationSet resources.
+func (c *Client) parseApplicationSets(output []byte) ([]*ApplicationSet, error) {
+	var list struct {
+		Items []struct {
+			Metadata ObjectMeta `json:"metadata"`
+			Spec     struct {
+				Template struct {
+					Spec struct {
+						Project string `json:"project"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal applicationset list: %w", err)
+	}
+
+	appSets := make([]*ApplicationSet, 0, len(list.Items))
+	for _, item := range list.Items {
+		appSet := &ApplicationSet{
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			Project:   item.Spec.Template.Spec.Project,
+		}
+		if appSet.Project == "" {
+			appSet.Project = DefaultProject
+		}
+		appSet.RBACObject = ApplicationRBACObject(appSet.Project, appSet.Namespace, appSet.Name, c.namespace)
+		appSets = append(appSets, appSet)
+	}
+	return appSets, nil
+}
+
+// ApplicationRBACObject returns the object RBAC policies for an application are matched against:
+// `<project>/<application>`, or `<project>/<namespace>/<application>` for applications outside
+// the Argo CD namespace.
+func ApplicationRBACObject(project string, namespace string, name string, argoCDNamespace string) string {
+	if namespace == "" || namespace == argoCDNamespace {
+		return project + "/" + name
+	}
+	return project + "/" + namespace + "/" + name
+}
+
+// sourceNamespaces returns the patterns of the namespaces outside its own that Argo CD reconciles resources in,
+// from the given key of argocd-cmd-params-cm, or for operator installs from the ArgoCD resource's sourceNamespaces.
+// A missing argocd-cmd-params-cm is read as empty.
+func (c *Client) sourceNamespaces(ctx context.Context, key string) ([]string, error) {
+	install, err := c.DetectInstallation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if install.Type == InstallOperator {
+		cr, err := c.getArgoCD(ctx, install.ArgoCD)
+		if err != nil {
+			return nil, err
+		}
+		if cr.Spec == nil {
+			return nil, nil
+		}
+		if key == ApplicationSetNamespacesKey {
+			if cr.Spec.ApplicationSet == nil {
+				return nil, nil
+			}
+			return cr.Spec.ApplicationSet.SourceNamespaces, nil
+		}
+		return cr.Spec.SourceNamespaces, nil
+	}
+
+	params, err := c.getObject(ctx, ConfigMapResource, CmdParamsConfigMapName)
+	if err != nil {
+		if !strings.Contains(err.Error(), "NotFound") {
+			return nil, fmt.Errorf("failed to get %s configmap: %w", CmdParamsConfigMapName, err)
+		}
+		return nil, nil
+	}
+
+	var namespaces []string
+	for _, namespace := range strings.Split(params.Data[key], ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// managedNamespace reports whether an Argo CD instance installed in argoCDNamespace reconciles resources in a
+// namespace: its own, or one matching a source namespace pattern. As in Argo CD, patterns are names, shell globs,
+// or regular expressions between slashes.
+func managedNamespace(namespace string, argoCDNamespace string, patterns []string) bool {
+	if namespace == "" || namespace == argoCDNamespace {
+		return true
+	}
+	for _, pattern := range patterns {
+		if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			if re, err := regexp.Compile(pattern[1 : len(pattern)-1]); err == nil && re.MatchString(namespace) {
+				return true
+			}
+			continue
+		}
+		if ok, err := path.Match(pattern, namespace); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
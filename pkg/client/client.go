@@ -1,14 +1,17 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/conductorone/baton-argo-cd/pkg/gitops"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
@@ -18,10 +21,14 @@ import (
 
 const (
 	argoCDCommand              = "argocd"
-	argoCDSecretName           = "argocd-secret"
-	argoCDConfigMapName        = "argocd-cm"
 	defaultAccountCapabilities = "apiKey, login"
-	userGrantPrefix            = "g"
+	apiKeyOnlyCapabilities     = "apiKey"
+	adminAccount               = "admin"
+
+	// PasswordPatternKey is the key of the pattern Argo CD validates new local account passwords against in argocd-cm.
+	PasswordPatternKey = "passwordPattern"
+	// DefaultPasswordPattern is the password pattern Argo CD uses when none is set.
+	DefaultPasswordPattern = "^.{8,32}$"
 )
 
 // Client provides methods to interact with Argo CD, primarily through its command-line interface (CLI).
@@ -31,21 +38,100 @@ type Client struct {
 	apiUrl   string
 	username string
 	password string
+	dryRun   bool
+	gitops   *gitops.Repository
+
+	controllerWritePolicy ControllerWritePolicy
+
+	kubeContext  string
+	argoCDConfig string
+
+	accountEmails        map[string]string
+	groupMapping         *GroupMapping
+	accountTokenLifetime time.Duration
+
+	namespace   string
+	installType InstallType
+	argoCDName  string
+	installMu   sync.Mutex
+	install     *Installation
+}
+
+// Option configures optional Client behavior.
+type Option func(*Client)
+
+// WithDryRun makes every write a server-side dry run: the change is validated and its diff logged,
+// but nothing is mutated.
+func WithDryRun(dryRun bool) Option {
+	return func(c *Client) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithKubeContext runs kubectl against the given kubeconfig context instead of the current one.
+func WithKubeContext(kubeContext string) Option {
+	return func(c *Client) {
+		c.kubeContext = kubeContext
+	}
+}
+
+// WithArgoCDConfig keeps the ArgoCD CLI session in the given configuration file instead of the user's
+// default one, so that clients of different instances can be logged in at the same time.
+func WithArgoCDConfig(path string) Option {
+	return func(c *Client) {
+		c.argoCDConfig = path
+	}
+}
+
+// WithGitOps writes changes to the ConfigMaps managed by the given repository as commits to it,
+// instead of patching them in the cluster where a GitOps controller would revert them.
+func WithGitOps(repo *gitops.Repository) Option {
+	return func(c *Client) {
+		c.gitops = repo
+	}
+}
+
+// WithAccountTokenLifetime sets the lifetime of account API tokens issued without one. Zero, the default,
+// issues tokens that never expire.
+func WithAccountTokenLifetime(lifetime time.Duration) Option {
+	return func(c *Client) {
+		c.accountTokenLifetime = lifetime
+	}
 }
 
 // NewClient creates a new Client instance.
 // The credentials are used for authenticating with the Argo CD CLI.
-func NewClient(ctx context.Context, apiUrl string, username string, password string) *Client {
-	return &Client{
+func NewClient(ctx context.Context, apiUrl string, username string, password string, opts ...Option) *Client {
+	c := &Client{
 		apiUrl:   apiUrl,
 		username: username,
 		password: password,
+
+		namespace:   ArgocdNamespace,
+		installType: InstallConfigMap,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // GetAccounts fetches a list of real accounts from ArgoCD using the CLI.
+// Clients without an API URL, such as those of discovered installations, read them from argocd-cm instead.
+// Owner emails come from argocd-cm and password change times from argocd-secret.
 // Command: argocd account list --output json.
 func (c *Client) GetAccounts(ctx context.Context) ([]*Account, error) {
+	if c.apiUrl == "" {
+		cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s configmap: %w", ArgoCDConfigMapName, err)
+		}
+		accounts := parseConfigMapAccounts(cm.Data)
+		setAccountEmails(accounts, cm.Data, c.accountEmails)
+		c.addPasswordMtimes(ctx, accounts)
+		return accounts, nil
+	}
+
 	output, err := c.runArgoCDCommandWithOutput(ctx, AccountCommand, ListCommand, OutputFlagLong, JSONOutput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accounts: %w", err)
@@ -56,14 +142,107 @@ func (c *Client) GetAccounts(ctx context.Context) ([]*Account, error) {
 		return nil, fmt.Errorf("failed to parse accounts JSON: %w (original output: %s)", err, string(output))
 	}
 
+	c.addAccountEmails(ctx, accounts)
+	c.addPasswordMtimes(ctx, accounts)
 	return accounts, nil
 }
 
+// addPasswordMtimes sets when each account's password was last set from the passwordMtime keys of argocd-secret.
+// Only those keys are decoded. Failing to read the Secret is only logged, leaving the times unset.
+// Command: kubectl get secret argocd-secret -n argocd -o json.
+func (c *Client) addPasswordMtimes(ctx context.Context, accounts []*Account) {
+	secret, err := c.getObject(ctx, SecretResource, ArgoCDSecretName)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to read password change times", zap.Error(err))
+		return
+	}
+
+	keys := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		keys = append(keys, passwordMtimeKey(account.Name))
+	}
+	data, err := decodeSecretData(secret, keys...)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to read password change times", zap.Error(err))
+		return
+	}
+	setPasswordMtimes(accounts, data)
+}
+
+// setPasswordMtimes sets the password change times of accounts from the decoded passwordMtime keys of argocd-secret.
+func setPasswordMtimes(accounts []*Account, data map[string]string) {
+	for _, account := range accounts {
+		mtime, err := time.Parse(time.RFC3339, data[passwordMtimeKey(account.Name)])
+		if err != nil {
+			continue
+		}
+		account.PasswordMtime = mtime.UTC()
+	}
+}
+
+// passwordMtimeKey returns the argocd-secret key holding when an account's password was last set.
+func passwordMtimeKey(account string) string {
+	if account == adminAccount {
+		return adminAccount + ".passwordMtime"
+	}
+	return "accounts." + account + ".passwordMtime"
+}
+
+// GetPasswordPattern returns the pattern Argo CD validates new local account passwords against,
+// or DefaultPasswordPattern if argocd-cm doesn't set one.
+// Command: kubectl get cm argocd-cm -n argocd -o json.
+func (c *Client) GetPasswordPattern(ctx context.Context) (string, error) {
+	cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s configmap: %w", ArgoCDConfigMapName, err)
+	}
+	if pattern := cm.Data[PasswordPatternKey]; pattern != "" {
+		return pattern, nil
+	}
+	return DefaultPasswordPattern, nil
+}
+
+// parseConfigMapAccounts returns the accounts declared in argocd-cm: the built-in admin account, disabled
+// by `admin.enabled: "false"`, and an account per `accounts.<name>` key, holding its capabilities and
+// disabled by `accounts.<name>.enabled: "false"`. Accounts are sorted by name.
+func parseConfigMapAccounts(data map[string]string) []*Account {
+	accounts := []*Account{{
+		Name:         adminAccount,
+		Enabled:      data[adminAccount+".enabled"] != "false",
+		Capabilities: []string{"login"},
+	}}
+
+	var names []string
+	for key := range data {
+		name, ok := strings.CutPrefix(key, "accounts.")
+		if !ok || strings.Contains(name, ".") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var capabilities []string
+		for _, capability := range strings.Split(data["accounts."+name], ",") {
+			if capability = strings.TrimSpace(capability); capability != "" {
+				capabilities = append(capabilities, capability)
+			}
+		}
+		accounts = append(accounts, &Account{
+			Name:         name,
+			Enabled:      data["accounts."+name+".enabled"] != "false",
+			Capabilities: capabilities,
+		})
+	}
+	return accounts
+}
+
 // GetRoles fetches a list of roles from the ArgoCD RBAC config map.
 // Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
 func (c *Client) GetRoles(ctx context.Context) ([]*Role, annotations.Annotations, error) {
 	var annos annotations.Annotations
-	cm, err := getRBACConfigMap(ctx)
+	cm, err := c.getRBACConfigMap(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -93,7 +272,7 @@ func (c *Client) GetRoles(ctx context.Context) ([]*Role, annotations.Annotations
 // GetDefaultRole fetches the default role from the ArgoCD RBAC config map.
 // Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
 func (c *Client) GetDefaultRole(ctx context.Context) (string, error) {
-	cm, err := getRBACConfigMap(ctx)
+	cm, err := c.getRBACConfigMap(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -111,173 +290,95 @@ func (c *Client) GetDefaultRole(ctx context.Context) (string, error) {
 
 // UpdateUserRole updates the role for a user within the `argocd-rbac-cm` ConfigMap.
 // It works by reading the existing `policy.csv`, checking if the user already has the role,
-// and if not, adding a new line for the new role assignment.
+// and if not, inserting a single new line for the role assignment, preceded by a provenance marker.
+// All other lines, including comments and formatting, are preserved as they are.
 // Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "replace", "path": "/data/policy.csv", "value": "g, USER_ID, ROLE_ID"}]'.
 func (c *Client) UpdateUserRole(ctx context.Context, userID string, roleID string) (annotations.Annotations, error) {
-	cm, err := getRBACConfigMap(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
-	}
-
-	policyCsv, ok := cm.Data[PolicyCSVKey]
-	if !ok {
-		policyCsv = ""
-	}
+	change := changeRecordFromContext(ctx)
+	lastChange := &LastChange{Operation: "grant", Subject: userID, Role: roleID, ChangeRecord: change}
 
-	reader := csv.NewReader(strings.NewReader(policyCsv))
-	reader.Comment = '#'
-	reader.TrimLeadingSpace = true
-	reader.FieldsPerRecord = -1
-
-	records, err := reader.ReadAll()
+	changed, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		return doc.AddBinding(userID, roleID, change), nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse policy csv: %w", err)
-	}
-
-	prefixedRoleID := roleID
-	if !strings.HasPrefix(roleID, RolePrefix) {
-		prefixedRoleID = RolePrefix + roleID
+		return nil, err
 	}
-
-	roleExists := false
-	for _, record := range records {
-		if len(record) > 2 && record[0] == userGrantPrefix && record[1] == userID && record[2] == prefixedRoleID {
-			roleExists = true
-			break
-		}
-	}
-
-	if roleExists {
+	if !changed {
 		return annotations.New(&v2.GrantAlreadyExists{}), nil
 	}
 
-	records = append(records, []string{userGrantPrefix, userID, prefixedRoleID})
-
-	var buf bytes.Buffer
-	writer := csv.NewWriter(&buf)
-	if err := writer.WriteAll(records); err != nil {
-		return nil, fmt.Errorf("failed to write policy csv: %w", err)
-	}
-
-	updatedPolicyCsv := buf.String()
-
-	marshaledCsv, err := json.Marshal(updatedPolicyCsv)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal policy csv for patch: %w", err)
-	}
-
-	var patch string
-	if ok {
-		patch = fmt.Sprintf(`[{"op": "replace", "path": "/data/%s", "value": %s}]`, PolicyCSVKey, string(marshaledCsv))
-	} else {
-		patch = fmt.Sprintf(`[{"op": "add", "path": "/data/%s", "value": %s}]`, PolicyCSVKey, string(marshaledCsv))
-	}
-
-	if err := c.runKubectlCommand(
-		ctx,
-		"patch",
-		"configmap",
-		RBACConfigMapName,
-		NamespaceFlag,
-		ArgocdNamespace,
-		"--type=json",
-		fmt.Sprintf("-p=%s", patch),
-	); err != nil {
-		return nil, fmt.Errorf("failed to patch rbac configmap: %w", err)
-	}
-
-	return nil, nil
+	return c.notApplied(lastChange), nil
 }
 
 // RemoveUserRole removes a role from a user within the `argocd-rbac-cm` ConfigMap.
+// Every line binding the user to the role is removed, including duplicates; all other lines are preserved.
 // Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "replace", "path": "/data/policy.csv", "value": "g, USER_ID, ROLE_ID"}]'.
 func (c *Client) RemoveUserRole(ctx context.Context, userID string, roleID string) (annotations.Annotations, error) {
-	cm, err := getRBACConfigMap(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
-	}
-
-	policyCsv, ok := cm.Data[PolicyCSVKey]
-	if !ok {
-		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
-	}
+	lastChange := &LastChange{Operation: "revoke", Subject: userID, Role: roleID, ChangeRecord: changeRecordFromContext(ctx)}
 
-	reader := csv.NewReader(strings.NewReader(policyCsv))
-	reader.Comment = '#'
-	reader.TrimLeadingSpace = true
-	reader.FieldsPerRecord = -1
-
-	records, err := reader.ReadAll()
+	changed, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		return doc.RemoveBinding(userID, roleID) > 0, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse policy csv: %w", err)
-	}
-
-	var newRecords [][]string
-	var roleRemoved bool
-
-	for _, record := range records {
-		if len(record) > 2 && record[0] == userGrantPrefix && record[1] == userID {
-			policyRole := strings.TrimPrefix(record[2], RolePrefix)
-			if policyRole == roleID {
-				roleRemoved = true
-				break
-			}
-		}
-		newRecords = append(newRecords, record)
+		return nil, err
 	}
-
-	if !roleRemoved {
+	if !changed {
 		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
 	}
 
-	var buf bytes.Buffer
-	writer := csv.NewWriter(&buf)
-	if err := writer.WriteAll(newRecords); err != nil {
-		return nil, fmt.Errorf("failed to write policy csv: %w", err)
-	}
-
-	updatedPolicyCsv := buf.String()
-	marshaledCsv, err := json.Marshal(updatedPolicyCsv)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal policy csv for patch: %w", err)
-	}
-
-	patch := fmt.Sprintf(`[{"op": "replace", "path": "/data/%s", "value": %s}]`, PolicyCSVKey, string(marshaledCsv))
-
-	if err := c.runKubectlCommand(
-		ctx,
-		"patch",
-		"configmap",
-		RBACConfigMapName,
-		NamespaceFlag,
-		ArgocdNamespace,
-		"--type=json",
-		fmt.Sprintf("-p=%s", patch),
-	); err != nil {
-		return nil, fmt.Errorf("failed to patch rbac configmap: %w", err)
-	}
-
-	return nil, nil
+	return c.notApplied(lastChange), nil
 }
 
 // CreateAccount creates a new local user in ArgoCD with the provided username and password.
+// Without a password, the account can only use API tokens: it gets the apiKey capability alone and
+// nothing is written to argocd-secret. With a password, its hash and passwordMtime are written to argocd-secret,
+// as Argo CD does when a password is updated. A non-empty email is recorded as the owner's in argocd-cm.
 // Command: kubectl patch configmap argocd-cm -n argocd --type=json -p '[{"op": "add", "path": "/data/accounts.USERNAME", "value": "apiKey, login"}]'.
-// Command: kubectl patch secret argocd-secret -n argocd --type=json -p '[{"op": "add", "path": "/data/accounts.USERNAME.password", "value": "ENCODED_PASSWORD"}]'.
-func (c *Client) CreateAccount(ctx context.Context, username string, password string) (*Account, annotations.Annotations, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to hash password: %w", err)
+// Command: kubectl patch secret argocd-secret -n argocd --type=json -p '[{"op": "add", "path": "/data/accounts.USERNAME.password", "value": "ENCODED_PASSWORD"}, ...]'.
+func (c *Client) CreateAccount(ctx context.Context, username string, password string, email string) (*Account, annotations.Annotations, error) {
+	capabilities := apiKeyOnlyCapabilities
+	var secretOps []jsonPatchOp
+	var passwordMtime time.Time
+	if password != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		encodedPassword := base64.StdEncoding.EncodeToString(hashedPassword)
+
+		// Check the Secret before anything is written, so a refused write doesn't leave a half-created account.
+		secret, err := c.getObject(ctx, SecretResource, ArgoCDSecretName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get Secret: %w", err)
+		}
+		if err := c.checkControllerOwner(ctx, SecretResource, secret.Metadata); err != nil {
+			return nil, nil, err
+		}
+
+		capabilities = defaultAccountCapabilities
+		passwordMtime = time.Now().UTC().Truncate(time.Second)
+		encodedMtime := base64.StdEncoding.EncodeToString([]byte(passwordMtime.Format(time.RFC3339)))
+		secretOps = []jsonPatchOp{
+			{Op: "add", Path: "/data/" + escapeJSONPointer("accounts."+username+".password"), Value: encodedPassword},
+			{Op: "add", Path: "/data/" + escapeJSONPointer(passwordMtimeKey(username)), Value: encodedMtime},
+		}
 	}
-	encodedPassword := base64.StdEncoding.EncodeToString(hashedPassword)
 
-	cmPatch := fmt.Sprintf(`[{"op": "add", "path": "/data/accounts.%s", "value": "%s"}]`, username, defaultAccountCapabilities)
-	if err := c.runKubectlCommand(ctx, "patch", "configmap", argoCDConfigMapName, NamespaceFlag, ArgocdNamespace, "--type=json", "-p", cmPatch); err != nil {
+	lastChange := &LastChange{Operation: "create-account", Subject: username, ChangeRecord: changeRecordFromContext(ctx)}
+	if _, err := c.editConfigMap(ctx, ArgoCDConfigMapName, lastChange, func(data map[string]string) (map[string]string, []string, error) {
+		set := map[string]string{"accounts." + username: capabilities}
+		if email != "" {
+			set[accountEmailKey(username)] = email
+		}
+		return set, nil, nil
+	}); err != nil {
 		return nil, nil, fmt.Errorf("failed to update ConfigMap: %w", err)
 	}
 
-	secretPatch := fmt.Sprintf(`[{"op": "add", "path": "/data/accounts.%s.password", "value": "%s"}]`, username, encodedPassword)
-	if err := c.runKubectlCommand(ctx, "patch", "secret", argoCDSecretName, NamespaceFlag, ArgocdNamespace, "--type=json", "-p", secretPatch); err != nil {
-		return nil, nil, fmt.Errorf("failed to update Secret: %w", err)
+	if secretOps != nil {
+		if err := c.patchResource(ctx, SecretResource, ArgoCDSecretName, secretOps); err != nil {
+			return nil, nil, fmt.Errorf("failed to update Secret: %w", err)
+		}
 	}
 
 	l := ctxzap.Extract(ctx)
@@ -300,38 +401,108 @@ func (c *Client) CreateAccount(ctx context.Context, username string, password st
 	}
 
 	account := &Account{
-		Name:         username,
-		Enabled:      true,
-		Capabilities: strings.Split(defaultAccountCapabilities, ", "),
+		Name:          username,
+		Enabled:       true,
+		Capabilities:  strings.Split(capabilities, ", "),
+		PasswordMtime: passwordMtime,
+		Email:         email,
 	}
 
-	return account, nil, nil
+	return account, c.notApplied(lastChange), nil
 }
 
-// GetRoleUsers returns a list of users that have the given role.
-// Command: kubectl get cm argocd-rbac-cm ... | grep ...
-func (c *Client) GetRoleUsers(ctx context.Context, roleID string) ([]*Account, error) {
-	// Use grep to fetch only policy lines relevant to the role.
-	// It checks for the role with and without the "role:" prefix.
-	grepCmd := fmt.Sprintf("grep -E '^%s,[^,]+,(%s)?%s$'", PolicyTypeGrant, RolePrefix, roleID)
-	command := fmt.Sprintf("kubectl get cm %s -n %s -o jsonpath='{.data.policy\\.csv}' | %s",
-		RBACConfigMapName,
-		ArgocdNamespace,
-		grepCmd,
-	)
-
-	policyDataBytes, err := executeShellCommandWithOutput(ctx, command)
+// CreateAccountToken issues an API token for a local account with the apiKey capability and returns it.
+// A zero expiresIn issues a token with the lifetime set by WithAccountTokenLifetime, or one that never expires
+// if none is set; an empty id lets Argo CD generate one. In dry-run mode no token is issued, and an empty token
+// is returned with a NotApplied annotation.
+// Command: argocd account generate-token --account <account> --expires-in <duration> --id <id>.
+func (c *Client) CreateAccountToken(ctx context.Context, account string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+	if expiresIn == 0 {
+		expiresIn = c.accountTokenLifetime
+	}
+	args := []string{AccountCommand, GenerateTokenCommand, AccountFlag, account}
+	if expiresIn > 0 {
+		args = append(args, ExpiresInFlag, expiresIn.String())
+	}
+	if id != "" {
+		args = append(args, TokenIDFlag, id)
+	}
+
+	if c.dryRun {
+		ctxzap.Extract(ctx).Info("dry run: skipped issuing account token",
+			zap.String("account", account),
+			zap.Duration("expires_in", expiresIn),
+			zap.String("id", id),
+		)
+		return "", c.notApplied(nil), nil
+	}
+
+	output, err := c.runArgoCDCommandWithOutput(ctx, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute command to get role users: %w", err)
+		return "", nil, fmt.Errorf("failed to generate token for account %s: %w", account, err)
 	}
+	return strings.TrimSpace(string(output)), nil, nil
+}
 
-	if len(policyDataBytes) == 0 {
-		return nil, nil
+// DeleteAccountToken revokes the API token of a local account with the given ID.
+// In dry-run mode nothing is revoked and a NotApplied annotation is returned.
+// Command: argocd account delete-token --account <account> <id>.
+func (c *Client) DeleteAccountToken(ctx context.Context, account string, id string) (annotations.Annotations, error) {
+	args := []string{AccountCommand, DeleteTokenCommand, AccountFlag, account, id}
+
+	if c.dryRun {
+		ctxzap.Extract(ctx).Info("dry run: skipped revoking account token",
+			zap.String("account", account),
+			zap.String("id", id),
+		)
+		return c.notApplied(nil), nil
 	}
 
-	bindings, _, err := ParseArgoCDPolicyCSV(string(policyDataBytes))
+	if _, err := c.runArgoCDCommandWithOutput(ctx, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete token %s of account %s: %w", id, account, err)
+	}
+	return nil, nil
+}
+
+// ParseTokenLifetime parses a token lifetime: a Go duration, or a number of days such as 30d.
+// An empty lifetime is a token that never expires.
+func ParseTokenLifetime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid token lifetime %q: %w", s, err)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid token lifetime %q: %w", s, err)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid token lifetime %q: must be positive", s)
+	}
+	return d, nil
+}
+
+// GetRoleUsers returns a list of users that have the given role: local accounts bound by name
+// and Dex static users bound by email.
+// Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
+func (c *Client) GetRoleUsers(ctx context.Context, roleID string) ([]*Account, error) {
+	cm, err := c.getRBACConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
+	}
+
+	bindings, _, err := ParseArgoCDPolicyCSV(cm.Data[PolicyCSVKey])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse filtered policy csv for role users: %w", err)
+		return nil, fmt.Errorf("failed to parse policy csv for role users: %w", err)
 	}
 
 	allAccounts, err := c.GetAccounts(ctx)
@@ -343,8 +514,20 @@ func (c *Client) GetRoleUsers(ctx context.Context, roleID string) ([]*Account, e
 		userMap[acc.Name] = struct{}{}
 	}
 
+	// Dex static users are bound by email.
+	dexUsers, err := c.GetDexUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dex users for filtering: %w", err)
+	}
+	for _, user := range dexUsers {
+		userMap[user.Email] = struct{}{}
+	}
+
 	var accounts []*Account
 	for _, binding := range bindings {
+		if !sameRole(binding.Role, roleID) {
+			continue
+		}
 		if _, isUser := userMap[binding.Subject]; isUser {
 			accounts = append(accounts, &Account{Name: binding.Subject})
 		}
@@ -353,43 +536,52 @@ func (c *Client) GetRoleUsers(ctx context.Context, roleID string) ([]*Account, e
 	return accounts, nil
 }
 
-// GetUserRoles returns a list of roles for a given user.
-// Command: kubectl get cm argocd-rbac-cm ... | grep ...
-func (c *Client) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
-	// Use grep to fetch only policy lines relevant to the user.
-	grepCmd := fmt.Sprintf("grep -E '^%s,%s,'", PolicyTypeGrant, userID)
-	command := fmt.Sprintf("kubectl get cm %s -n %s -o jsonpath='{.data.policy\\.csv}' | %s",
-		RBACConfigMapName,
-		ArgocdNamespace,
-		grepCmd,
-	)
-
-	policyDataBytes, err := executeShellCommandWithOutput(ctx, command)
+// GetRoleBindings returns the `g` lines binding subjects to the given role,
+// noting which of them were written by the connector.
+// Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
+func (c *Client) GetRoleBindings(ctx context.Context, roleID string) ([]*PolicyBinding, error) {
+	cm, err := c.getRBACConfigMap(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute command to get user roles: %w", err)
+		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
 	}
 
-	// If grep returns no results, the user has no explicit roles.
-	// In this case, they may have a default role.
-	if len(policyDataBytes) == 0 {
-		defaultRole, err := c.GetDefaultRole(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get default role: %w", err)
-		}
-		if defaultRole != "" {
-			return []string{defaultRole}, nil
+	var bindings []*PolicyBinding
+	for _, binding := range ParsePolicyDocument(cm.Data[PolicyCSVKey]).Bindings() {
+		if sameRole(binding.Role, roleID) {
+			bindings = append(bindings, binding)
 		}
-		return nil, nil
 	}
 
-	bindings, _, err := ParseArgoCDPolicyCSV(string(policyDataBytes))
+	return bindings, nil
+}
+
+// GetUserRoles returns a list of roles for a given user.
+// Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
+func (c *Client) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	cm, err := c.getRBACConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
+	}
+
+	bindings, _, err := ParseArgoCDPolicyCSV(cm.Data[PolicyCSVKey])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse filtered policy csv for user roles: %w", err)
+		return nil, fmt.Errorf("failed to parse policy csv for user roles: %w", err)
 	}
 
 	var roles []string
 	for _, binding := range bindings {
-		roles = append(roles, binding.Role)
+		if binding.Subject == userID {
+			roles = append(roles, binding.Role)
+		}
+	}
+
+	// A user without explicit roles may still have the default role.
+	if len(roles) == 0 {
+		defaultRole := strings.TrimPrefix(cm.Data[PolicyDefaultKey], RolePrefix)
+		if defaultRole != "" {
+			return []string{defaultRole}, nil
+		}
+		return nil, nil
 	}
 
 	return roles, nil
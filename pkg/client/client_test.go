@@ -2,7 +2,9 @@ package client
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,6 +20,13 @@ func TestNewClient(t *testing.T) {
 		assert.Equal(t, "https://test.com", client.apiUrl)
 		assert.Equal(t, "admin", client.username)
 		assert.Equal(t, "password", client.password)
+		assert.False(t, client.dryRun)
+	})
+
+	t.Run("with dry run", func(t *testing.T) {
+		client := NewClient(context.Background(), "https://test.com", "admin", "password", WithDryRun(true))
+
+		assert.True(t, client.dryRun)
 	})
 }
 
@@ -46,3 +55,49 @@ func TestUpdateUserRole_AlreadyExists(t *testing.T) {
 	_, err = client.UpdateUserRole(ctx, userID, roleID)
 	assert.NoError(t, err)
 }
+
+// TestSetPasswordMtimes tests reading when account passwords were last set from argocd-secret.
+func TestSetPasswordMtimes(t *testing.T) {
+	accounts := []*Account{{Name: "admin"}, {Name: "alice"}, {Name: "ci"}, {Name: "broken"}}
+	setPasswordMtimes(accounts, map[string]string{
+		"admin.passwordMtime":           "2024-03-01T10:00:00Z",
+		"accounts.alice.passwordMtime":  "2024-05-20T08:30:00+02:00",
+		"accounts.broken.passwordMtime": "yesterday",
+	})
+
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), accounts[0].PasswordMtime)
+	assert.Equal(t, time.Date(2024, 5, 20, 6, 30, 0, 0, time.UTC), accounts[1].PasswordMtime)
+	assert.True(t, accounts[2].PasswordMtime.IsZero())
+	assert.True(t, accounts[3].PasswordMtime.IsZero())
+}
+
+// TestResourceVersionTestOps tests that patches are conditional on the resourceVersion they were computed from.
+func TestResourceVersionTestOps(t *testing.T) {
+	assert.Equal(t, []jsonPatchOp{{Op: "test", Path: "/metadata/resourceVersion", Value: "4711"}}, resourceVersionTestOps(ObjectMeta{ResourceVersion: "4711"}))
+	assert.Empty(t, resourceVersionTestOps(ObjectMeta{}))
+
+	err := patchError("argocd-rbac-cm configmap", errors.New(`kubectl command failed: exit status 1, stderr: The request is invalid: testing value /metadata/resourceVersion failed: test failed`))
+	assert.ErrorContains(t, err, "argocd-rbac-cm configmap was changed since it was read, nothing was written")
+	err = patchError("argocd-rbac-cm configmap", errors.New("forbidden"))
+	assert.EqualError(t, err, "failed to patch argocd-rbac-cm configmap: forbidden")
+}
+
+// TestParseTokenLifetime tests parsing token lifetimes as durations or days.
+func TestParseTokenLifetime(t *testing.T) {
+	d, err := ParseTokenLifetime("30d")
+	require.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, d)
+
+	d, err = ParseTokenLifetime(" 720h ")
+	require.NoError(t, err)
+	assert.Equal(t, 720*time.Hour, d)
+
+	d, err = ParseTokenLifetime("")
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	_, err = ParseTokenLifetime("soon")
+	assert.ErrorContains(t, err, `invalid token lifetime "soon"`)
+	_, err = ParseTokenLifetime("-1h")
+	assert.ErrorContains(t, err, "must be positive")
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Labels and values identifying Argo CD's declarative secrets.
+const (
+	SecretTypeLabel   = "argocd.argoproj.io/secret-type"
+	SecretTypeCluster = "cluster"
+
+	// InClusterServer is the server URL of the cluster Argo CD runs in.
+	InClusterServer = "https://kubernetes.default.svc"
+	// InClusterName is the name Argo CD gives the cluster it runs in.
+	InClusterName = "in-cluster"
+	// inClusterEnabledKey is the argocd-cm key that disables the in-cluster cluster when "false".
+	inClusterEnabledKey = "cluster.inClusterEnabled"
+)
+
+// Cluster is a Kubernetes cluster Argo CD deploys into, as defined by a cluster secret.
+// Credentials are never read.
+type Cluster struct {
+	Name       string
+	Server     string
+	Project    string
+	Namespaces []string
+	// SecretName is the secret defining the cluster, empty for the implicit in-cluster cluster.
+	SecretName string
+}
+
+// GetClusters returns the clusters defined by cluster secrets, plus the in-cluster cluster unless
+// it is disabled or redefined by a secret.
+// Command: kubectl get secret -n argocd -l argocd.argoproj.io/secret-type=cluster -o json.
+func (c *Client) GetClusters(ctx context.Context) ([]*Cluster, error) {
+	secrets, err := c.listSecrets(ctx, SecretTypeCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	inClusterDefined := false
+	clusters := make([]*Cluster, 0, len(secrets)+1)
+	for _, secret := range secrets {
+		data, err := decodeSecretData(secret, "name", "server", "project", "namespaces")
+		if err != nil {
+			return nil, err
+		}
+		cluster := &Cluster{
+			Name:       data["name"],
+			Server:     data["server"],
+			Project:    data["project"],
+			SecretName: secret.Metadata.Name,
+		}
+		if namespaces := data["namespaces"]; namespaces != "" {
+			for _, namespace := range strings.Split(namespaces, ",") {
+				cluster.Namespaces = append(cluster.Namespaces, strings.TrimSpace(namespace))
+			}
+		}
+		if cluster.Name == "" {
+			cluster.Name = cluster.Server
+		}
+		inClusterDefined = inClusterDefined || cluster.Server == InClusterServer
+		clusters = append(clusters, cluster)
+	}
+
+	if !inClusterDefined {
+		cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s configmap: %w", ArgoCDConfigMapName, err)
+		}
+		if cm.Data[inClusterEnabledKey] != "false" {
+			clusters = append(clusters, &Cluster{Name: InClusterName, Server: InClusterServer})
+		}
+	}
+
+	return clusters, nil
+}
+
+// ClusterRBACObject returns the object RBAC policies for a cluster are matched against: its server URL,
+// prefixed with `<project>/` for project-scoped clusters.
+func ClusterRBACObject(project string, server string) string {
+	if project == "" {
+		return server
+	}
+	return project + "/" + server
+}
+
+// listSecrets returns the secrets of the given Argo CD secret type in the Argo CD namespace.
+func (c *Client) listSecrets(ctx context.Context, secretType string) ([]*ConfigMap, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx,
+		GetCommand,
+		SecretResource,
+		NamespaceFlag,
+		c.namespace,
+		"-l",
+		SecretTypeLabel+"="+secretType,
+		OutputFlag,
+		JSONOutput,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s secrets: %w", secretType, err)
+	}
+
+	var list struct {
+		Items []*ConfigMap `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s secret list: %w", secretType, err)
+	}
+	return list.Items, nil
+}
+
+// decodeSecretData base64-decodes only the given keys of a secret, so that credentials held
+// in its other keys are never decoded.
+func decodeSecretData(secret *ConfigMap, keys ...string) (map[string]string, error) {
+	data := make(map[string]string, len(keys))
+	for _, key := range keys {
+		encoded, ok := secret.Data[key]
+		if !ok {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s of secret %s: %w", key, secret.Metadata.Name, err)
+		}
+		data[key] = string(decoded)
+	}
+	return data, nil
+}
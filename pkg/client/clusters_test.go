@@ -0,0 +1,36 @@
+package client
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeSecretData tests that only the requested secret keys are decoded.
+func TestDecodeSecretData(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	secret := &ConfigMap{
+		Metadata: ObjectMeta{Name: "cluster-prod"},
+		Data: map[string]string{
+			"name":   encode("prod"),
+			"server": encode("https://prod.example.com"),
+			"config": encode(`{"bearerToken":"secret"}`),
+		},
+	}
+
+	data, err := decodeSecretData(secret, "name", "server", "project")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "prod", "server": "https://prod.example.com"}, data)
+
+	secret.Data["name"] = "not base64!"
+	_, err = decodeSecretData(secret, "name")
+	assert.ErrorContains(t, err, "failed to decode name of secret cluster-prod")
+}
+
+// TestClusterRBACObject tests the RBAC object of global and project-scoped clusters.
+func TestClusterRBACObject(t *testing.T) {
+	assert.Equal(t, "https://prod.example.com", ClusterRBACObject("", "https://prod.example.com"))
+	assert.Equal(t, "team-a/https://prod.example.com", ClusterRBACObject("team-a", "https://prod.example.com"))
+}
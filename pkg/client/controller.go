@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Labels and annotations GitOps controllers use to track the objects they manage.
+const (
+	ArgoCDInstanceLabel        = "app.kubernetes.io/instance"
+	ArgoCDTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+	FluxKustomizationNameLabel = "kustomize.toolkit.fluxcd.io/name"
+	FluxKustomizationNSLabel   = "kustomize.toolkit.fluxcd.io/namespace"
+)
+
+// ResourceTrackingMethodKey is the argocd-cm key selecting how Argo CD tracks the objects of its Applications.
+const ResourceTrackingMethodKey = "application.resourceTrackingMethod"
+
+// Tracking methods of ResourceTrackingMethodKey. Under TrackingMethodLabel, Argo CD tracks objects by
+// ArgoCDInstanceLabel alone; it is the default before Argo CD 3.0, and TrackingMethodAnnotation from it.
+const (
+	TrackingMethodLabel      = "label"
+	TrackingMethodAnnotation = "annotation"
+)
+
+// Controllers reported in ControllerOwner.
+const (
+	ControllerArgoCD = "argocd"
+	ControllerFlux   = "flux"
+)
+
+// ControllerWritePolicy decides what happens when the connector is about to patch an object
+// a GitOps controller manages, which would revert the change on its next sync.
+type ControllerWritePolicy string
+
+const (
+	// ControllerWriteWarn writes anyway and logs a warning.
+	ControllerWriteWarn ControllerWritePolicy = "warn"
+	// ControllerWriteRefuse fails the operation with ErrControllerManaged.
+	ControllerWriteRefuse ControllerWritePolicy = "refuse"
+)
+
+// ErrControllerManaged is returned when a write is refused because a GitOps controller manages the object.
+var ErrControllerManaged = errors.New("managed by a GitOps controller")
+
+// ControllerOwner identifies the GitOps controller and application managing a ConfigMap or Secret.
+type ControllerOwner struct {
+	// Object is the managed object, e.g. "cm/argocd-rbac-cm".
+	Object string
+	// Controller is ControllerArgoCD or ControllerFlux.
+	Controller string
+	// Application is the Argo CD Application, or the Flux Kustomization as "<namespace>/<name>".
+	Application string
+	// Source is the label or annotation the owner was read from.
+	Source string
+}
+
+// String describes the owner for logs and error messages.
+func (o *ControllerOwner) String() string {
+	if o.Controller == ControllerFlux {
+		return fmt.Sprintf("Flux Kustomization '%s'", o.Application)
+	}
+	return fmt.Sprintf("Argo CD Application '%s'", o.Application)
+}
+
+// WithControllerWritePolicy sets what happens when writing to objects managed by a GitOps controller.
+// The default is ControllerWriteWarn.
+func WithControllerWritePolicy(policy ControllerWritePolicy) Option {
+	return func(c *Client) {
+		c.controllerWritePolicy = policy
+	}
+}
+
+// GetControllerOwner returns the GitOps controller managing the named ConfigMap or Secret in the Argo CD
+// namespace, or nil if it carries no tracking label or annotation.
+func (c *Client) GetControllerOwner(ctx context.Context, kind string, name string) (*ControllerOwner, error) {
+	obj, err := c.getObject(ctx, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	return c.controllerOwner(ctx, kind, obj.Metadata)
+}
+
+// controllerOwner reads the GitOps controller tracking an object from its metadata, reading Argo CD's tracking
+// method only when the object carries the instance label without a tracking-id annotation, and its version only
+// when argocd-cm doesn't set the method.
+func (c *Client) controllerOwner(ctx context.Context, kind string, meta ObjectMeta) (*ControllerOwner, error) {
+	var trackingMethod, version string
+	if meta.Labels[ArgoCDInstanceLabel] != "" && meta.Annotations[ArgoCDTrackingIDAnnotation] == "" {
+		cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the resource tracking method: %w", err)
+		}
+		trackingMethod = cm.Data[ResourceTrackingMethodKey]
+		if trackingMethod == "" {
+			version, err = c.getServerVersion(ctx)
+			if err != nil {
+				ctxzap.Extract(ctx).Warn("failed to read the Argo CD version, assuming label tracking", zap.Error(err))
+			}
+		}
+	}
+	return controllerOwner(kind, meta, trackingMethod, version), nil
+}
+
+// controllerOwner reads the GitOps controller tracking an object from its metadata. The Argo CD tracking-id
+// annotation is enough on its own. The instance label is also set by Helm on every object it renders, so it
+// only counts when Argo CD's tracking method is TrackingMethodLabel: when argocd-cm sets it, or by default
+// before Argo CD 3.0, where such installs mark their objects with the label alone. The default is taken from
+// version, the image tag of the API server; an unknown version is assumed to predate 3.0, so that objects
+// tracked by the label aren't missed.
+func controllerOwner(kind string, meta ObjectMeta, trackingMethod string, version string) *ControllerOwner {
+	object := kind + "/" + meta.Name
+
+	// The tracking id has the form `<application>:<group>/<kind>:<namespace>/<name>`.
+	if trackingID := meta.Annotations[ArgoCDTrackingIDAnnotation]; trackingID != "" {
+		application, _, _ := strings.Cut(trackingID, ":")
+		return &ControllerOwner{Object: object, Controller: ControllerArgoCD, Application: application, Source: ArgoCDTrackingIDAnnotation}
+	}
+
+	if name := meta.Labels[FluxKustomizationNameLabel]; name != "" {
+		if namespace := meta.Labels[FluxKustomizationNSLabel]; namespace != "" {
+			name = namespace + "/" + name
+		}
+		return &ControllerOwner{Object: object, Controller: ControllerFlux, Application: name, Source: FluxKustomizationNameLabel}
+	}
+
+	if trackingMethod == "" {
+		trackingMethod = defaultTrackingMethod(version)
+	}
+	if instance := meta.Labels[ArgoCDInstanceLabel]; instance != "" && trackingMethod == TrackingMethodLabel {
+		return &ControllerOwner{Object: object, Controller: ControllerArgoCD, Application: instance, Source: ArgoCDInstanceLabel}
+	}
+
+	return nil
+}
+
+// defaultTrackingMethod returns the tracking method Argo CD uses when argocd-cm sets none: TrackingMethodAnnotation
+// from version 3.0, and TrackingMethodLabel before it or when the version, such as `latest`, isn't a release.
+func defaultTrackingMethod(version string) string {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	if n, err := strconv.Atoi(major); err == nil && n >= 3 {
+		return TrackingMethodAnnotation
+	}
+	return TrackingMethodLabel
+}
+
+// checkControllerOwner applies the controller write policy before an object is patched in the cluster.
+func (c *Client) checkControllerOwner(ctx context.Context, kind string, meta ObjectMeta) error {
+	owner, err := c.controllerOwner(ctx, kind, meta)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return nil
+	}
+
+	if c.controllerWritePolicy == ControllerWriteRefuse {
+		return fmt.Errorf("refusing to write %s: %w (%s, from %s); the change would be reverted on its next sync, "+
+			"make it in Git or configure GitOps write-back", owner.Object, ErrControllerManaged, owner, owner.Source)
+	}
+
+	ctxzap.Extract(ctx).Warn("writing to an object managed by a GitOps controller, the change may be reverted on its next sync",
+		zap.String("resource", owner.Object),
+		zap.String("controller", owner.Controller),
+		zap.String("application", owner.Application),
+		zap.String("source", owner.Source),
+	)
+	return nil
+}
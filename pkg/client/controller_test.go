@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestControllerOwner tests reading the managing GitOps controller from object metadata.
+func TestControllerOwner(t *testing.T) {
+	tests := []struct {
+		name           string
+		meta           ObjectMeta
+		trackingMethod string
+		version        string
+		want           *ControllerOwner
+	}{
+		{
+			name: "unmanaged",
+			meta: ObjectMeta{Name: RBACConfigMapName, Labels: map[string]string{"app.kubernetes.io/part-of": "argocd"}},
+		},
+		{
+			name: "argo cd tracking id",
+			meta: ObjectMeta{
+				Name:        RBACConfigMapName,
+				Labels:      map[string]string{ArgoCDInstanceLabel: "argocd"},
+				Annotations: map[string]string{ArgoCDTrackingIDAnnotation: "argocd-config:/ConfigMap:argocd/argocd-rbac-cm"},
+			},
+			want: &ControllerOwner{Object: "cm/argocd-rbac-cm", Controller: ControllerArgoCD, Application: "argocd-config", Source: ArgoCDTrackingIDAnnotation},
+		},
+		{
+			name:           "argo cd instance label",
+			meta:           ObjectMeta{Name: ArgoCDConfigMapName, Labels: map[string]string{ArgoCDInstanceLabel: "argocd-config"}},
+			trackingMethod: TrackingMethodLabel,
+			want:           &ControllerOwner{Object: "cm/argocd-cm", Controller: ControllerArgoCD, Application: "argocd-config", Source: ArgoCDInstanceLabel},
+		},
+		{
+			name: "helm labels without label tracking",
+			meta: ObjectMeta{Name: RBACConfigMapName, Labels: map[string]string{
+				ArgoCDInstanceLabel:            "argocd",
+				"app.kubernetes.io/managed-by": "Helm",
+				"helm.sh/chart":                "argo-cd-7.7.0",
+			}},
+			trackingMethod: TrackingMethodAnnotation,
+		},
+		{
+			name: "helm labels with default tracking from argo cd 3",
+			meta: ObjectMeta{Name: RBACConfigMapName, Labels: map[string]string{
+				ArgoCDInstanceLabel:            "argocd",
+				"app.kubernetes.io/managed-by": "Helm",
+			}},
+			version: "v3.0.6",
+		},
+		{
+			name:    "instance label with default tracking before argo cd 3",
+			meta:    ObjectMeta{Name: RBACConfigMapName, Labels: map[string]string{ArgoCDInstanceLabel: "argocd-config"}},
+			version: "v2.13.1",
+			want:    &ControllerOwner{Object: "cm/argocd-rbac-cm", Controller: ControllerArgoCD, Application: "argocd-config", Source: ArgoCDInstanceLabel},
+		},
+		{
+			name: "instance label with default tracking and unknown version",
+			meta: ObjectMeta{Name: RBACConfigMapName, Labels: map[string]string{ArgoCDInstanceLabel: "argocd-config"}},
+			want: &ControllerOwner{Object: "cm/argocd-rbac-cm", Controller: ControllerArgoCD, Application: "argocd-config", Source: ArgoCDInstanceLabel},
+		},
+		{
+			name: "flux kustomization",
+			meta: ObjectMeta{
+				Name: RBACConfigMapName,
+				Labels: map[string]string{
+					FluxKustomizationNameLabel: "argocd",
+					FluxKustomizationNSLabel:   "flux-system",
+				},
+			},
+			want: &ControllerOwner{Object: "cm/argocd-rbac-cm", Controller: ControllerFlux, Application: "flux-system/argocd", Source: FluxKustomizationNameLabel},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, controllerOwner(ConfigMapResource, tt.meta, tt.trackingMethod, tt.version))
+		})
+	}
+}
+
+// TestDefaultTrackingMethod tests the tracking method Argo CD versions use when argocd-cm sets none.
+func TestDefaultTrackingMethod(t *testing.T) {
+	assert.Equal(t, TrackingMethodLabel, defaultTrackingMethod("v2.14.11"))
+	assert.Equal(t, TrackingMethodLabel, defaultTrackingMethod("latest"))
+	assert.Equal(t, TrackingMethodLabel, defaultTrackingMethod(""))
+	assert.Equal(t, TrackingMethodAnnotation, defaultTrackingMethod("v3.0.0"))
+	assert.Equal(t, TrackingMethodAnnotation, defaultTrackingMethod("3.1.2"))
+}
+
+// TestCheckControllerOwner tests the write policy for objects managed by a GitOps controller.
+func TestCheckControllerOwner(t *testing.T) {
+	ctx := context.Background()
+	managed := ObjectMeta{Name: ArgoCDSecretName, Labels: map[string]string{FluxKustomizationNameLabel: "argocd"}}
+
+	t.Run("warn by default", func(t *testing.T) {
+		c := NewClient(ctx, "https://test.com", "admin", "password")
+		assert.NoError(t, c.checkControllerOwner(ctx, SecretResource, managed))
+	})
+
+	t.Run("refuse", func(t *testing.T) {
+		c := NewClient(ctx, "https://test.com", "admin", "password", WithControllerWritePolicy(ControllerWriteRefuse))
+
+		err := c.checkControllerOwner(ctx, SecretResource, managed)
+		require.ErrorIs(t, err, ErrControllerManaged)
+		assert.Contains(t, err.Error(), "secret/argocd-secret")
+		assert.Contains(t, err.Error(), "Flux Kustomization 'argocd'")
+
+		assert.NoError(t, c.checkControllerOwner(ctx, SecretResource, ObjectMeta{Name: ArgoCDSecretName}))
+	})
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DexConfigKey is the key of the Dex configuration in argocd-cm.
+const DexConfigKey = "dex.config"
+
+// DexUser is a static user of Argo CD's bundled Dex, defined under `staticPasswords` in `dex.config`.
+// Argo CD's RBAC matches these users by email.
+type DexUser struct {
+	Email    string `yaml:"email"`
+	Username string `yaml:"username"`
+	UserID   string `yaml:"userID"`
+	// Enabled reports whether Dex's password database, which static users log in through, is enabled.
+	Enabled bool `yaml:"-"`
+}
+
+// dexConfig is the part of `dex.config` describing static users. Password hashes are never read.
+type dexConfig struct {
+	EnablePasswordDB bool       `yaml:"enablePasswordDB"`
+	StaticPasswords  []*DexUser `yaml:"staticPasswords"`
+}
+
+// GetDexUsers returns the static users defined in the Dex configuration of argocd-cm.
+// Command: kubectl get cm argocd-cm -n argocd -o json.
+func (c *Client) GetDexUsers(ctx context.Context) ([]*DexUser, error) {
+	cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s configmap: %w", ArgoCDConfigMapName, err)
+	}
+
+	return parseDexUsers(cm.Data[DexConfigKey])
+}
+
+// parseDexUsers returns the static users of a `dex.config` value, skipping entries without an email.
+func parseDexUsers(config string) ([]*DexUser, error) {
+	if config == "" {
+		return nil, nil
+	}
+
+	var cfg dexConfig
+	if err := yaml.Unmarshal([]byte(config), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DexConfigKey, err)
+	}
+
+	var users []*DexUser
+	for _, user := range cfg.StaticPasswords {
+		if user == nil || user.Email == "" {
+			continue
+		}
+		user.Enabled = cfg.EnablePasswordDB
+		users = append(users, user)
+	}
+	return users, nil
+}
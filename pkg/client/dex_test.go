@@ -0,0 +1,56 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dexConfigWithStaticUsers = `connectors:
+- type: github
+  id: github
+  name: GitHub
+  config:
+    clientID: aabbccddeeff00112233
+    clientSecret: $dex.github.clientSecret
+enablePasswordDB: true
+staticPasswords:
+- email: "breakglass@example.com"
+  hash: "$2a$10$2b2cU8CPhOTaGrs1HRQuAueS7JTT5ZHsHSzYiFPm1leZck7Mc8T4W"
+  username: "breakglass"
+  userID: "08a8684b-db88-4b73-90a9-3cd1661f5466"
+- username: "no-email"
+`
+
+// TestParseDexUsers tests reading static users from dex.config.
+func TestParseDexUsers(t *testing.T) {
+	t.Run("static users", func(t *testing.T) {
+		users, err := parseDexUsers(dexConfigWithStaticUsers)
+		require.NoError(t, err)
+		assert.Equal(t, []*DexUser{{
+			Email:    "breakglass@example.com",
+			Username: "breakglass",
+			UserID:   "08a8684b-db88-4b73-90a9-3cd1661f5466",
+			Enabled:  true,
+		}}, users)
+	})
+
+	t.Run("password database disabled", func(t *testing.T) {
+		users, err := parseDexUsers("staticPasswords:\n- email: a@example.com\n  username: a\n")
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.False(t, users[0].Enabled)
+	})
+
+	t.Run("no dex config", func(t *testing.T) {
+		users, err := parseDexUsers("")
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+
+	t.Run("invalid dex config", func(t *testing.T) {
+		_, err := parseDexUsers("staticPasswords: [")
+		assert.ErrorContains(t, err, "failed to parse dex.config")
+	})
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const redactedValue = "<redacted>"
+
+// diffObjects describes how the data and annotations of a ConfigMap or Secret change between two versions.
+// Multi-line values such as `policy.csv` are diffed line by line. When redact is set, values are
+// never included, only which keys were added, removed or changed.
+func diffObjects(before *ConfigMap, after *ConfigMap, redact bool) []string {
+	var diff []string
+	diff = append(diff, diffMap("data", before.Data, after.Data, redact)...)
+	diff = append(diff, diffMap("annotations", before.Metadata.Annotations, after.Metadata.Annotations, false)...)
+	return diff
+}
+
+// diffMap describes the added, removed and changed keys of a string map.
+func diffMap(section string, before map[string]string, after map[string]string, redact bool) []string {
+	keys := make(map[string]struct{}, len(before)+len(after))
+	for k := range before {
+		keys[k] = struct{}{}
+	}
+	for k := range after {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	show := func(v string) string {
+		if redact {
+			return redactedValue
+		}
+		return fmt.Sprintf("%q", v)
+	}
+
+	var diff []string
+	for _, k := range sorted {
+		oldValue, hadOld := before[k]
+		newValue, hasNew := after[k]
+		switch {
+		case !hadOld:
+			diff = append(diff, fmt.Sprintf("+ %s[%s] = %s", section, k, show(newValue)))
+		case !hasNew:
+			diff = append(diff, fmt.Sprintf("- %s[%s]", section, k))
+		case oldValue == newValue:
+			continue
+		case redact:
+			diff = append(diff, fmt.Sprintf("~ %s[%s] = %s", section, k, redactedValue))
+		case strings.Contains(oldValue, "\n") || strings.Contains(newValue, "\n"):
+			diff = append(diff, fmt.Sprintf("~ %s[%s]:", section, k))
+			for _, line := range diffLines(oldValue, newValue) {
+				diff = append(diff, "    "+line)
+			}
+		default:
+			diff = append(diff, fmt.Sprintf("~ %s[%s] = %q -> %q", section, k, oldValue, newValue))
+		}
+	}
+	return diff
+}
+
+// diffLines returns the lines removed ("- ") from a and added ("+ ") in b, in document order,
+// based on their longest common subsequence.
+func diffLines(a string, b string) []string {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+x[i])
+			i++
+		default:
+			diff = append(diff, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, "+ "+y[j])
+	}
+	return diff
+}
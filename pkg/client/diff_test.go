@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiffObjects tests the diff logged for dry-run writes.
+func TestDiffObjects(t *testing.T) {
+	t.Run("policy lines", func(t *testing.T) {
+		before := &ConfigMap{Data: map[string]string{
+			PolicyCSVKey: "# comment\ng, alice, role:admin\ng, bob, role:admin\n",
+		}}
+		after := &ConfigMap{
+			Metadata: ObjectMeta{Annotations: map[string]string{LastChangeAnnotation: `{"operation":"revoke"}`}},
+			Data: map[string]string{
+				PolicyCSVKey: "# comment\ng, bob, role:admin\ng, carol, role:admin\n",
+			},
+		}
+
+		assert.Equal(t, []string{
+			"~ data[policy.csv]:",
+			"    - g, alice, role:admin",
+			"    + g, carol, role:admin",
+			`+ annotations[conductorone.io/last-change] = "{\"operation\":\"revoke\"}"`,
+		}, diffObjects(before, after, false))
+	})
+
+	t.Run("secret values are redacted", func(t *testing.T) {
+		before := &ConfigMap{Data: map[string]string{
+			"admin.password":   "b2xk",
+			"server.secretkey": "c2VjcmV0",
+		}}
+		after := &ConfigMap{Data: map[string]string{
+			"admin.password":            "bmV3",
+			"server.secretkey":          "c2VjcmV0",
+			"accounts.alice.password":   "aGFzaA==",
+			"accounts.alice.tokens.new": "dG9rZW4=",
+		}}
+
+		diff := diffObjects(before, after, true)
+		assert.Equal(t, []string{
+			"+ data[accounts.alice.password] = <redacted>",
+			"+ data[accounts.alice.tokens.new] = <redacted>",
+			"~ data[admin.password] = <redacted>",
+		}, diff)
+		for _, line := range diff {
+			assert.NotContains(t, line, "aGFzaA==")
+			assert.NotContains(t, line, "bmV3")
+		}
+	})
+}
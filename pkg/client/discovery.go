@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	// PartOfLabel is the label Argo CD's install manifests, Helm chart and operator put on the objects of an installation.
+	PartOfLabel  = "app.kubernetes.io/part-of"
+	partOfArgoCD = "argocd"
+)
+
+// DiscoverInstallations returns the namespaces of the cluster holding an Argo CD installation, found by
+// their `argocd-rbac-cm` and `argocd-cm` ConfigMaps labelled `app.kubernetes.io/part-of: argocd`.
+// Namespaces holding only one of the two are skipped.
+// Command: kubectl get cm --all-namespaces -l app.kubernetes.io/part-of=argocd -o json.
+func (c *Client) DiscoverInstallations(ctx context.Context) ([]string, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx,
+		GetCommand,
+		ConfigMapResource,
+		allNamespacesFlag,
+		"-l",
+		PartOfLabel+"="+partOfArgoCD,
+		OutputFlag,
+		JSONOutput,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Argo CD ConfigMaps: %w", err)
+	}
+
+	var list struct {
+		Items []*ConfigMap `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ConfigMap list: %w", err)
+	}
+	return discoveredNamespaces(list.Items), nil
+}
+
+// discoveredNamespaces returns, sorted, the namespaces holding both an `argocd-rbac-cm` and an `argocd-cm`.
+func discoveredNamespaces(configMaps []*ConfigMap) []string {
+	found := map[string]map[string]bool{}
+	for _, cm := range configMaps {
+		if cm.Metadata.Name != RBACConfigMapName && cm.Metadata.Name != ArgoCDConfigMapName {
+			continue
+		}
+		if found[cm.Metadata.Namespace] == nil {
+			found[cm.Metadata.Namespace] = map[string]bool{}
+		}
+		found[cm.Metadata.Namespace][cm.Metadata.Name] = true
+	}
+
+	var namespaces []string
+	for namespace, names := range found {
+		if names[RBACConfigMapName] && names[ArgoCDConfigMapName] {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiscoveredNamespaces tests finding Argo CD installations by their ConfigMaps.
+func TestDiscoveredNamespaces(t *testing.T) {
+	configMap := func(namespace string, name string) *ConfigMap {
+		return &ConfigMap{Metadata: ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	namespaces := discoveredNamespaces([]*ConfigMap{
+		configMap("team-b", RBACConfigMapName),
+		configMap("team-b", ArgoCDConfigMapName),
+		configMap("argocd", ArgoCDConfigMapName),
+		configMap("argocd", RBACConfigMapName),
+		configMap("argocd", "argocd-ssh-known-hosts-cm"),
+		configMap("half-installed", ArgoCDConfigMapName),
+	})
+	assert.Equal(t, []string{"argocd", "team-b"}, namespaces)
+}
+
+// TestParseConfigMapAccounts tests reading local accounts from argocd-cm.
+func TestParseConfigMapAccounts(t *testing.T) {
+	accounts := parseConfigMapAccounts(map[string]string{
+		"admin.enabled":          "false",
+		"accounts.deployer":      "apiKey",
+		"accounts.alice":         "apiKey, login",
+		"accounts.alice.enabled": "false",
+		"url":                    "https://argocd.example.com",
+	})
+	assert.Equal(t, []*Account{
+		{Name: "admin", Enabled: false, Capabilities: []string{"login"}},
+		{Name: "alice", Enabled: false, Capabilities: []string{"apiKey", "login"}},
+		{Name: "deployer", Enabled: true, Capabilities: []string{"apiKey"}},
+	}, accounts)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// WithAccountEmails sets the emails of the owners of local accounts that have no `accounts.<name>.email` key
+// in argocd-cm, by account name.
+func WithAccountEmails(emails map[string]string) Option {
+	return func(c *Client) {
+		c.accountEmails = emails
+	}
+}
+
+// ReadAccountEmails reads a YAML file mapping local account names to the email of their owner.
+func ReadAccountEmails(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account emails file: %w", err)
+	}
+
+	var emails map[string]string
+	if err := yaml.Unmarshal(data, &emails); err != nil {
+		return nil, fmt.Errorf("failed to parse account emails file %s: %w", path, err)
+	}
+	for name, email := range emails {
+		if !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("account emails file %s: invalid email %q for account %s", path, email, name)
+		}
+	}
+	return emails, nil
+}
+
+// addAccountEmails sets the owner emails of accounts from argocd-cm, falling back to the configured ones.
+// Failing to read argocd-cm is only logged, leaving only the configured emails.
+// Command: kubectl get cm argocd-cm -n argocd -o json.
+func (c *Client) addAccountEmails(ctx context.Context, accounts []*Account) {
+	var data map[string]string
+	cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to read account emails", zap.Error(err))
+	} else {
+		data = cm.Data
+	}
+	setAccountEmails(accounts, data, c.accountEmails)
+}
+
+// setAccountEmails sets the owner email of each account from its `accounts.<name>.email` key in argocd-cm,
+// or else from the configured emails.
+func setAccountEmails(accounts []*Account, data map[string]string, configured map[string]string) {
+	for _, account := range accounts {
+		if email := strings.TrimSpace(data[accountEmailKey(account.Name)]); email != "" {
+			account.Email = email
+		} else {
+			account.Email = configured[account.Name]
+		}
+	}
+}
+
+// accountEmailKey returns the argocd-cm key holding the email of the owner of an account. Argo CD ignores it.
+func accountEmailKey(account string) string {
+	if account == adminAccount {
+		return adminAccount + ".email"
+	}
+	return "accounts." + account + ".email"
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSetAccountEmails tests reading the emails of account owners from argocd-cm and the configured emails.
+func TestSetAccountEmails(t *testing.T) {
+	accounts := []*Account{{Name: "admin"}, {Name: "alice"}, {Name: "bob"}, {Name: "ci"}}
+	setAccountEmails(accounts, map[string]string{
+		"admin.email":          "platform@example.com",
+		"accounts.alice":       "apiKey, login",
+		"accounts.alice.email": " alice@example.com ",
+		"accounts.bob.email":   "bob@example.com",
+	}, map[string]string{
+		"alice": "alice@old.example.com",
+		"ci":    "release@example.com",
+	})
+
+	assert.Equal(t, "platform@example.com", accounts[0].Email)
+	assert.Equal(t, "alice@example.com", accounts[1].Email)
+	assert.Equal(t, "bob@example.com", accounts[2].Email)
+	assert.Equal(t, "release@example.com", accounts[3].Email)
+}
+
+// TestReadAccountEmails tests reading and validating an account emails file.
+func TestReadAccountEmails(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "emails.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	emails, err := ReadAccountEmails(write(t, "alice: alice@example.com\nci: release@example.com\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alice": "alice@example.com", "ci": "release@example.com"}, emails)
+
+	_, err = ReadAccountEmails(write(t, "alice: alice\n"))
+	assert.ErrorContains(t, err, `invalid email "alice" for account alice`)
+
+	_, err = ReadAccountEmails(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read account emails file")
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-argo-cd/pkg/gitops"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// gitopsPushAttempts bounds how often a change is re-applied to a fresh checkout when the push
+// loses a race with another commit to the branch.
+const gitopsPushAttempts = 3
+
+// editConfigMapInGit applies edit to the data of the named ConfigMap as defined in the configuration
+// repository and commits the result. In dry-run mode the diff of the manifest is logged instead.
+func (c *Client) editConfigMapInGit(
+	ctx context.Context,
+	name string,
+	lastChange *LastChange,
+	edit func(data map[string]string) (map[string]string, []string, error),
+) (bool, error) {
+	l := ctxzap.Extract(ctx)
+
+	for attempt := 1; ; attempt++ {
+		changed, err := c.commitConfigMapEdit(ctx, name, lastChange, edit)
+		if errors.Is(err, gitops.ErrPushRejected) && attempt < gitopsPushAttempts {
+			l.Info("push to configuration repository was rejected, retrying on a fresh checkout",
+				zap.String("configmap", name),
+				zap.Int("attempt", attempt),
+			)
+			continue
+		}
+		return changed, err
+	}
+}
+
+// commitConfigMapEdit makes a single attempt at editConfigMapInGit.
+func (c *Client) commitConfigMapEdit(
+	ctx context.Context,
+	name string,
+	lastChange *LastChange,
+	edit func(data map[string]string) (map[string]string, []string, error),
+) (bool, error) {
+	l := ctxzap.Extract(ctx)
+
+	checkout, err := c.gitops.Checkout(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := checkout.Close(); err != nil {
+			l.Warn("failed to remove configuration repository checkout", zap.Error(err))
+		}
+	}()
+
+	content, err := checkout.Read(name)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := gitops.ReadData(content, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s from manifest: %w", name, err)
+	}
+
+	set, remove, err := edit(data)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := gitops.EditData(content, name, set, remove)
+	if err != nil {
+		return false, fmt.Errorf("failed to edit %s manifest: %w", name, err)
+	}
+	if bytes.Equal(content, updated) {
+		return false, nil
+	}
+
+	if c.dryRun {
+		l.Info("dry run: skipped commit to configuration repository",
+			zap.String("configmap", name),
+			zap.Strings("diff", diffLines(string(content), string(updated))),
+		)
+		return true, nil
+	}
+
+	if err := checkout.Write(name, updated); err != nil {
+		return false, err
+	}
+
+	branch, err := checkout.CommitAndPush(ctx, commitMessage(name, lastChange))
+	if err != nil {
+		return false, err
+	}
+
+	l.Info("committed change to configuration repository",
+		zap.String("configmap", name),
+		zap.String("branch", branch),
+	)
+	if c.gitops.ReviewBranches() {
+		lastChange.PendingBranch = branch
+	}
+
+	return true, nil
+}
+
+// commitMessage describes a change, carrying its provenance as commit trailers.
+func commitMessage(name string, change *LastChange) string {
+	subject := []string{"baton-argo-cd:", change.Operation}
+	if change.Subject != "" {
+		subject = append(subject, change.Subject)
+	}
+	if change.Role != "" {
+		subject = append(subject, RolePrefix+strings.TrimPrefix(change.Role, RolePrefix))
+	}
+
+	var trailers []string
+	trailers = append(trailers, "ConfigMap: "+name)
+	if change.ChangeRecord != nil {
+		if change.GrantID != "" {
+			trailers = append(trailers, "Grant-Id: "+change.GrantID)
+		}
+		if change.RequestID != "" {
+			trailers = append(trailers, "Request-Id: "+change.RequestID)
+		}
+		if !change.Time.IsZero() {
+			trailers = append(trailers, "Changed-At: "+change.Time.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return strings.Join(subject, " ") + "\n\n" + strings.Join(trailers, "\n") + "\n"
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-argo-cd/pkg/gitops"
+)
+
+const rbacManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: argocd-rbac-cm
+  namespace: argocd
+data:
+  policy.default: role:readonly
+  # Managed in Git, see README.
+  policy.csv: |
+    p, role:deployer, applications, sync, */*, allow
+    g, alice, role:deployer
+`
+
+// newConfigRepository creates a bare repository whose main branch holds rbac-cm.yaml.
+func newConfigRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "config.git")
+	work := filepath.Join(root, "work")
+
+	runTestGit(t, root, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	runTestGit(t, root, "init", "--quiet", "--initial-branch=main", work)
+	require.NoError(t, os.WriteFile(filepath.Join(work, "rbac-cm.yaml"), []byte(rbacManifest), 0o600))
+	runTestGit(t, work, "add", "--all")
+	runTestGit(t, work, "-c", "user.name=test", "-c", "user.email=test@localhost", "commit", "--quiet", "--message", "initial")
+	runTestGit(t, work, "push", "--quiet", bare, "main")
+
+	return bare
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return string(output)
+}
+
+// TestClient_GitOps tests that role bindings are committed to the configuration repository.
+func TestClient_GitOps(t *testing.T) {
+	bare := newConfigRepository(t)
+	repo := gitops.NewRepository(bare, "main", "", map[string]string{RBACConfigMapName: "rbac-cm.yaml"})
+	client := NewClient(context.Background(), "https://test.com", "admin", "password", WithGitOps(repo))
+
+	ctx := WithChangeRecord(context.Background(), &ChangeRecord{
+		GrantID:   "role:deployer:assigned:user:bob",
+		RequestID: "REQ-7",
+		Time:      time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC),
+	})
+
+	t.Run("grant commits the binding", func(t *testing.T) {
+		annos, err := client.UpdateUserRole(ctx, "bob", "deployer")
+		require.NoError(t, err)
+		assert.Nil(t, annos)
+
+		manifest := runTestGit(t, bare, "show", "main:rbac-cm.yaml")
+		assert.Contains(t, manifest, "  # Managed in Git, see README.\n")
+		assert.Contains(t, manifest, "    g, alice, role:deployer\n    # managed-by: conductorone grant=role:deployer:assigned:user:bob request=REQ-7 at=2025-03-04T05:06:07Z\n    g, bob, role:deployer\n")
+
+		message := runTestGit(t, bare, "log", "-1", "--format=%B", "main")
+		assert.True(t, strings.HasPrefix(message, "baton-argo-cd: grant bob role:deployer\n"))
+		assert.Contains(t, message, "Grant-Id: role:deployer:assigned:user:bob\n")
+		assert.Contains(t, message, "Request-Id: REQ-7\n")
+	})
+
+	t.Run("existing binding is not committed again", func(t *testing.T) {
+		head := runTestGit(t, bare, "rev-parse", "main")
+
+		annos, err := client.UpdateUserRole(ctx, "bob", "deployer")
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		assert.Equal(t, head, runTestGit(t, bare, "rev-parse", "main"))
+	})
+
+	t.Run("dry run does not commit", func(t *testing.T) {
+		head := runTestGit(t, bare, "rev-parse", "main")
+		dryRun := NewClient(context.Background(), "https://test.com", "admin", "password", WithGitOps(repo), WithDryRun(true))
+
+		annos, err := dryRun.RemoveUserRole(ctx, "bob", "deployer")
+		require.NoError(t, err)
+		assert.Equal(t, &NotApplied{Reason: NotAppliedDryRun}, GetNotApplied(annos))
+		assert.Equal(t, head, runTestGit(t, bare, "rev-parse", "main"))
+	})
+
+	t.Run("review branch results name the pending branch", func(t *testing.T) {
+		head := runTestGit(t, bare, "rev-parse", "main")
+		review := gitops.NewRepository(bare, "main", "baton/", map[string]string{RBACConfigMapName: "rbac-cm.yaml"})
+		reviewed := NewClient(context.Background(), "https://test.com", "admin", "password", WithGitOps(review))
+
+		annos, err := reviewed.UpdateUserRole(ctx, "carol", "deployer")
+		require.NoError(t, err)
+		notApplied := GetNotApplied(annos)
+		require.NotNil(t, notApplied)
+		assert.Equal(t, NotAppliedPendingReview, notApplied.Reason)
+		assert.True(t, strings.HasPrefix(notApplied.Branch, "baton/"))
+		assert.Contains(t, runTestGit(t, bare, "show", notApplied.Branch+":rbac-cm.yaml"), "g, carol, role:deployer")
+		assert.Equal(t, head, runTestGit(t, bare, "rev-parse", "main"))
+	})
+
+	t.Run("revoke commits the removal", func(t *testing.T) {
+		annos, err := client.RemoveUserRole(ctx, "bob", "deployer")
+		require.NoError(t, err)
+		assert.Nil(t, annos)
+		assert.Equal(t, rbacManifest, runTestGit(t, bare, "show", "main:rbac-cm.yaml"))
+
+		annos, err = client.RemoveUserRole(ctx, "bob", "deployer")
+		require.NoError(t, err)
+		assert.Equal(t, annotations.New(&v2.GrantAlreadyRevoked{}), annos)
+	})
+
+	roleCtx := WithChangeRecord(context.Background(), &ChangeRecord{RequestID: "REQ-8", Time: time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)})
+
+	t.Run("create role commits its permissions", func(t *testing.T) {
+		_, err := client.CreateRole(roleCtx, "team-a", []*PolicyDefinition{
+			{Resource: "applications", Action: "get", Object: "team-a/*", Effect: "allow"},
+			{Resource: "applications", Action: "sync", Object: "team-a/*", Effect: "allow"},
+		})
+		require.NoError(t, err)
+
+		manifest := runTestGit(t, bare, "show", "main:rbac-cm.yaml")
+		assert.Contains(t, manifest, "    # managed-by: conductorone request=REQ-8 at=2025-03-05T00:00:00Z\n    p, role:team-a, applications, get, team-a/*, allow\n")
+		assert.Contains(t, manifest, "    p, role:team-a, applications, sync, team-a/*, allow\n")
+
+		message := runTestGit(t, bare, "log", "-1", "--format=%B", "main")
+		assert.True(t, strings.HasPrefix(message, "baton-argo-cd: create-role role:team-a\n"))
+
+		_, err = client.CreateRole(roleCtx, "deployer", []*PolicyDefinition{{Resource: "logs", Action: "get", Object: "*/*", Effect: "allow"}})
+		assert.ErrorContains(t, err, "role deployer already exists")
+		_, err = client.CreateRole(roleCtx, "admin", []*PolicyDefinition{{Resource: "logs", Action: "get", Object: "*/*", Effect: "allow"}})
+		assert.ErrorContains(t, err, "role admin is a built-in Argo CD role")
+	})
+
+	t.Run("role permissions are added and removed", func(t *testing.T) {
+		policy := &PolicyDefinition{Role: "team-a", Resource: "applications", Action: "sync", Object: "staging/*", Effect: "allow"}
+		changed, err := client.AddRolePolicy(roleCtx, policy)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Contains(t, runTestGit(t, bare, "show", "main:rbac-cm.yaml"), "    p, role:team-a, applications, sync, staging/*, allow\n")
+
+		changed, err = client.AddRolePolicy(roleCtx, policy)
+		require.NoError(t, err)
+		assert.False(t, changed)
+
+		changed, err = client.RemoveRolePolicy(roleCtx, policy)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.NotContains(t, runTestGit(t, bare, "show", "main:rbac-cm.yaml"), "staging/*")
+
+		_, err = client.AddRolePolicy(roleCtx, &PolicyDefinition{Role: "team-b", Resource: "logs", Action: "get", Object: "*/*", Effect: "allow"})
+		assert.ErrorContains(t, err, "role team-b not found")
+	})
+
+	t.Run("clone, rename and migrate roles", func(t *testing.T) {
+		change, err := client.CloneRole(roleCtx, "deployer", "deployer-v2")
+		require.NoError(t, err)
+		assert.Empty(t, change.Before)
+		assert.Equal(t, []string{"p, role:deployer-v2, applications, sync, */*, allow"}, change.After)
+
+		change, err = client.CloneRole(roleCtx, "readonly", "auditor")
+		require.NoError(t, err)
+		assert.Len(t, change.After, 9)
+
+		change, err = client.MigrateRoleMembers(roleCtx, "deployer", "deployer-v2")
+		require.NoError(t, err)
+		assert.True(t, change.Changed)
+		assert.Contains(t, change.Before, "g, alice, role:deployer")
+		assert.Contains(t, change.After, "g, alice, role:deployer-v2")
+		assert.NotContains(t, change.After, "g, alice, role:deployer")
+
+		change, err = client.RenameRole(roleCtx, "deployer-v2", "release")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"p, role:release, applications, sync, */*, allow", "g, alice, role:release"}, change.After)
+
+		manifest := runTestGit(t, bare, "show", "main:rbac-cm.yaml")
+		assert.NotContains(t, manifest, "deployer-v2")
+		assert.Contains(t, manifest, "    g, alice, role:release\n")
+
+		message := runTestGit(t, bare, "log", "-1", "--format=%B", "main")
+		assert.True(t, strings.HasPrefix(message, "baton-argo-cd: rename-role role:deployer-v2 role:release\n"))
+
+		_, err = client.RenameRole(roleCtx, "release", "team-a")
+		assert.ErrorContains(t, err, "role team-a already exists")
+		_, err = client.RenameRole(roleCtx, "readonly", "viewer")
+		assert.ErrorContains(t, err, "role readonly is a built-in Argo CD role and can't be renamed")
+		_, err = client.MigrateRoleMembers(roleCtx, "release", "missing")
+		assert.ErrorContains(t, err, "role missing not found")
+	})
+
+	t.Run("delete role removes its permissions and bindings", func(t *testing.T) {
+		_, err := client.DeleteRole(roleCtx, "deployer")
+		require.NoError(t, err)
+
+		manifest := runTestGit(t, bare, "show", "main:rbac-cm.yaml")
+		assert.NotContains(t, manifest, "role:deployer")
+		assert.Contains(t, manifest, "role:team-a")
+
+		_, err = client.DeleteRole(roleCtx, "readonly")
+		assert.ErrorContains(t, err, "role readonly is a built-in Argo CD role and can't be deleted")
+	})
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Group is an SSO group bound to roles by `g,` lines of policy.csv. Its name is the raw claim value Argo CD
+// matches, like `my-org:platform` or an Azure AD object ID.
+type Group struct {
+	Name  string
+	Roles []string
+	// ExternalID is the group's identifier in the identity provider, from the group mapping, empty if unmapped.
+	ExternalID string
+	// ExternalKey is the profile field ExternalID is matched against, empty to match the identity provider's
+	// resource ID.
+	ExternalKey string
+}
+
+// GroupMapping maps the group subjects of policy.csv to the groups of the identity provider. A subject is
+// mapped by its Static entry, else by the longest of Prefixes it starts with, replacing that prefix, else by
+// the first of Rewrites matching it. Subjects nothing matches are left unmapped.
+type GroupMapping struct {
+	// MatchKey is the profile field of the identity provider's groups the mapped values are matched against,
+	// empty to match them as resource IDs.
+	MatchKey string            `yaml:"matchKey"`
+	Static   map[string]string `yaml:"static"`
+	// Prefixes replaces the prefix a Dex connector puts on its groups, like `my-org:` for GitHub teams.
+	Prefixes map[string]string `yaml:"prefixes"`
+	Rewrites []*GroupRewrite   `yaml:"rewrites"`
+}
+
+// GroupRewrite maps the subjects matching Pattern to its expansion of Replacement, as regexp.ReplaceAllString does.
+type GroupRewrite struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+	re          *regexp.Regexp
+}
+
+// WithGroupMapping maps the group subjects of policy.csv to the groups of the identity provider.
+func WithGroupMapping(mapping *GroupMapping) Option {
+	return func(c *Client) {
+		c.groupMapping = mapping
+	}
+}
+
+// ReadGroupMapping reads a group mapping from a YAML file with `matchKey`, `static`, `prefixes` and `rewrites`.
+func ReadGroupMapping(path string) (*GroupMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group mapping file: %w", err)
+	}
+
+	mapping := &GroupMapping{}
+	if err := yaml.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse group mapping file %s: %w", path, err)
+	}
+	for _, rewrite := range mapping.Rewrites {
+		rewrite.re, err = regexp.Compile(rewrite.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("group mapping file %s: invalid rewrite pattern %q: %w", path, rewrite.Pattern, err)
+		}
+	}
+	return mapping, nil
+}
+
+// Map returns the identity provider's identifier of a group subject, or "" if the mapping doesn't cover it.
+func (m *GroupMapping) Map(subject string) string {
+	if m == nil {
+		return ""
+	}
+	if id, ok := m.Static[subject]; ok {
+		return id
+	}
+
+	longest := ""
+	for prefix := range m.Prefixes {
+		if strings.HasPrefix(subject, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest != "" {
+		return m.Prefixes[longest] + strings.TrimPrefix(subject, longest)
+	}
+
+	for _, rewrite := range m.Rewrites {
+		if rewrite.re != nil && rewrite.re.MatchString(subject) {
+			return rewrite.re.ReplaceAllString(subject, rewrite.Replacement)
+		}
+	}
+	return ""
+}
+
+// GetGroups returns the SSO groups bound to roles in policy.csv: the subjects of `g,` lines that are neither
+// roles, local accounts nor Dex static users, with the roles they are bound to and their mapped identifiers.
+// Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
+func (c *Client) GetGroups(ctx context.Context) ([]*Group, error) {
+	cm, err := c.getRBACConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
+	}
+
+	users := make(map[string]struct{})
+	accounts, err := c.GetAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts for filtering: %w", err)
+	}
+	for _, account := range accounts {
+		users[account.Name] = struct{}{}
+	}
+	dexUsers, err := c.GetDexUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dex users for filtering: %w", err)
+	}
+	for _, user := range dexUsers {
+		users[user.Email] = struct{}{}
+	}
+
+	return groupsFromBindings(ParsePolicyDocument(cm.Data[PolicyCSVKey]).Bindings(), users, c.groupMapping), nil
+}
+
+// groupsFromBindings returns the groups bound by the given `g,` lines, skipping roles and the given users.
+// Groups and their roles are sorted by name.
+func groupsFromBindings(bindings []*PolicyBinding, users map[string]struct{}, mapping *GroupMapping) []*Group {
+	roles := make(map[string]struct{})
+	for _, binding := range bindings {
+		roles[strings.TrimPrefix(binding.Role, RolePrefix)] = struct{}{}
+	}
+
+	bySubject := make(map[string]*Group)
+	for _, binding := range bindings {
+		subject := binding.Subject
+		if strings.HasPrefix(subject, RolePrefix) {
+			continue
+		}
+		if _, ok := roles[subject]; ok {
+			continue
+		}
+		if _, ok := users[subject]; ok {
+			continue
+		}
+		group, ok := bySubject[subject]
+		if !ok {
+			group = &Group{Name: subject}
+			if id := mapping.Map(subject); id != "" {
+				group.ExternalID = id
+				group.ExternalKey = mapping.MatchKey
+			}
+			bySubject[subject] = group
+		}
+		role := strings.TrimPrefix(binding.Role, RolePrefix)
+		if !slices.Contains(group.Roles, role) {
+			group.Roles = append(group.Roles, role)
+		}
+	}
+
+	groups := make([]*Group, 0, len(bySubject))
+	for _, group := range bySubject {
+		sort.Strings(group.Roles)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroupMapping tests mapping group subjects to identity provider groups.
+func TestGroupMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+matchKey: name
+static:
+  my-org:admins: Argo CD Administrators
+prefixes:
+  "my-org:": ""
+  "my-org:infra-": "infrastructure-"
+rewrites:
+  - pattern: '^okta-(.+)$'
+    replacement: '$1'
+`), 0o600))
+
+	mapping, err := ReadGroupMapping(path)
+	require.NoError(t, err)
+	assert.Equal(t, "name", mapping.MatchKey)
+	assert.Equal(t, "Argo CD Administrators", mapping.Map("my-org:admins"))
+	assert.Equal(t, "platform", mapping.Map("my-org:platform"))
+	assert.Equal(t, "infrastructure-network", mapping.Map("my-org:infra-network"))
+	assert.Equal(t, "developers", mapping.Map("okta-developers"))
+	assert.Equal(t, "", mapping.Map("contractors"))
+
+	var unset *GroupMapping
+	assert.Equal(t, "", unset.Map("my-org:platform"))
+
+	require.NoError(t, os.WriteFile(path, []byte("rewrites:\n  - pattern: '('\n"), 0o600))
+	_, err = ReadGroupMapping(path)
+	assert.ErrorContains(t, err, `invalid rewrite pattern "("`)
+}
+
+// TestGroupsFromBindings tests finding the SSO groups bound to roles.
+func TestGroupsFromBindings(t *testing.T) {
+	bindings := ParsePolicyDocument(`g, my-org:platform, role:admin
+g, my-org:platform, role:developer
+g, alice, role:developer
+g, role:developer, role:readonly
+g, developer, role:viewer
+g, 0f3c2a9e, role:developer
+`).Bindings()
+
+	groups := groupsFromBindings(bindings, map[string]struct{}{"alice": {}}, &GroupMapping{Static: map[string]string{"0f3c2a9e": "00g1"}})
+	assert.Equal(t, []*Group{
+		{Name: "0f3c2a9e", Roles: []string{"developer"}, ExternalID: "00g1"},
+		{Name: "my-org:platform", Roles: []string{"admin", "developer"}},
+	}, groups)
+}
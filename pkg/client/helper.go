@@ -5,9 +5,9 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strings"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
@@ -22,36 +22,41 @@ const (
 	// Role and policy parsing constants.
 	RolePrefix = "role:"
 
-	// Shell command constants.
-	ShellExecutable = "sh"
-	ShellFlag       = "-c"
-
 	// PolicyTypeGrant indicates a role grant ('g') policy line.
 	PolicyTypeGrant = "g"
 	// PolicyTypeDefinition indicates a policy definition ('p') line.
 	PolicyTypeDefinition = "p"
 
 	// Kubectl command constants for interacting with Kubernetes.
-	Kubectl           = "kubectl"
-	GetCommand        = "get"
-	ConfigMapResource = "cm"
-	RBACConfigMapName = "argocd-rbac-cm"
-	NamespaceFlag     = "-n"
-	ArgocdNamespace   = "argocd"
-	OutputFlag        = "-o"
-	JSONOutput        = "json"
+	Kubectl             = "kubectl"
+	GetCommand          = "get"
+	ConfigMapResource   = "cm"
+	SecretResource      = "secret"
+	ArgoCDResource      = "argocds.argoproj.io"
+	DryRunServerFlag    = "--dry-run=server"
+	RBACConfigMapName   = "argocd-rbac-cm"
+	ArgoCDConfigMapName = "argocd-cm"
+	ArgoCDSecretName    = "argocd-secret"
+	NamespaceFlag       = "-n"
+	ArgocdNamespace     = "argocd"
+	OutputFlag          = "-o"
+	JSONOutput          = "json"
+	KubeContextFlag     = "--context"
 
 	// ArgoCD CLI command constants.
-	AccountCommand     = "account"
-	ListCommand        = "list"
-	OutputFlagLong     = "--output"
-	GetUserInfoCommand = "get-user-info"
-	LoginCommand       = "login"
-	LogoutCommand      = "logout"
-	UsernameFlag       = "--username"
-	PasswordFlag       = "--password"
-	InsecureFlag       = "--insecure"
-	ArgoCDCommand      = "argocd"
+	AccountCommand       = "account"
+	ListCommand          = "list"
+	OutputFlagLong       = "--output"
+	GetUserInfoCommand   = "get-user-info"
+	GenerateTokenCommand = "generate-token"
+	AccountFlag          = "--account"
+	LoginCommand         = "login"
+	LogoutCommand        = "logout"
+	UsernameFlag         = "--username"
+	PasswordFlag         = "--password"
+	InsecureFlag         = "--insecure"
+	ArgoCDCommand        = "argocd"
+	ArgoCDConfigFlag     = "--config"
 )
 
 // ParseArgoCDPolicyCSV parses ArgoCD policy CSV data into group bindings and policies.
@@ -91,11 +96,18 @@ func ParseArgoCDPolicyCSV(csvData string) ([]*PolicyBinding, []*PolicyDefinition
 		case PolicyTypeDefinition:
 			if len(fields) >= 4 {
 				role := strings.TrimPrefix(fields[1], RolePrefix)
-				policies = append(policies, &PolicyDefinition{
+				policy := &PolicyDefinition{
 					Role:     role,
 					Resource: fields[2],
 					Action:   fields[3],
-				})
+				}
+				if len(fields) >= 5 {
+					policy.Object = fields[4]
+				}
+				if len(fields) >= 6 {
+					policy.Effect = fields[5]
+				}
+				policies = append(policies, policy)
 			}
 		default:
 			continue
@@ -134,56 +146,287 @@ func executeCommandWithOutput(ctx context.Context, name string, args ...string)
 	return stdout.Bytes(), nil
 }
 
-// executeShellCommandWithOutput executes a shell command string, which can include pipes.
-func executeShellCommandWithOutput(ctx context.Context, command string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, ShellExecutable, ShellFlag, command)
+// jsonPatchOp is a single RFC 6902 JSON patch operation, as accepted by `kubectl patch --type=json`.
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// escapeJSONPointer escapes a map key for use as a JSON pointer path segment.
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
 
-	if err := cmd.Run(); err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
-			return stdout.Bytes(), nil
+// getRBACConfigMap fetches and unmarshals the argocd-rbac-cm ConfigMap from the Kubernetes cluster.
+func (c *Client) getRBACConfigMap(ctx context.Context) (*ConfigMap, error) {
+	return c.getConfigMap(ctx, RBACConfigMapName)
+}
+
+// getConfigMap fetches and unmarshals the named ConfigMap from the Argo CD namespace.
+// For operator installations, argocd-rbac-cm is read from the ArgoCD resource the operator generates it from.
+func (c *Client) getConfigMap(ctx context.Context, name string) (*ConfigMap, error) {
+	if name == RBACConfigMapName {
+		install, err := c.DetectInstallation(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if install.Type == InstallOperator {
+			cr, err := c.getArgoCD(ctx, install.ArgoCD)
+			if err != nil {
+				return nil, err
+			}
+			return &ConfigMap{Metadata: cr.Metadata, Data: argoCDData(cr, name)}, nil
 		}
-		return nil, fmt.Errorf("shell command failed: %w, stderr: %s", err, stderr.String())
 	}
 
-	return stdout.Bytes(), nil
+	cm, err := c.getObject(ctx, ConfigMapResource, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if cm.Data == nil {
+		return nil, fmt.Errorf("ConfigMap '%s' has no data section", name)
+	}
+
+	return cm, nil
 }
 
-// getRBACConfigMap fetches and unmarshals the argocd-rbac-cm ConfigMap from the Kubernetes cluster.
-func getRBACConfigMap(ctx context.Context) (*ConfigMap, error) {
-	outputBytes, err := executeCommandWithOutput(ctx, Kubectl,
+// getObject fetches a ConfigMap or Secret from the Argo CD namespace. Both share the metadata and data
+// shape of ConfigMap; for Secrets the data values are base64 encoded. ArgoCD resources are flattened
+// by argoCDObject.
+func (c *Client) getObject(ctx context.Context, kind string, name string) (*ConfigMap, error) {
+	outputBytes, err := c.getJSON(ctx, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(kind, outputBytes)
+}
+
+// getJSON fetches a resource from the Argo CD namespace as JSON.
+func (c *Client) getJSON(ctx context.Context, kind string, name string) ([]byte, error) {
+	outputBytes, err := c.runKubectlCommandWithOutput(ctx,
 		GetCommand,
-		ConfigMapResource,
-		RBACConfigMapName,
+		kind,
+		name,
 		NamespaceFlag,
-		ArgocdNamespace,
+		c.namespace,
 		OutputFlag,
 		JSONOutput,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("kubectl command failed to fetch ConfigMap '%s' in namespace '%s': %w",
-			RBACConfigMapName, ArgocdNamespace, err)
+		return nil, fmt.Errorf("kubectl command failed to fetch %s '%s' in namespace '%s': %w",
+			kind, name, c.namespace, err)
 	}
 
 	if len(outputBytes) == 0 {
-		return nil, fmt.Errorf("kubectl command returned empty output for ConfigMap '%s'", RBACConfigMapName)
+		return nil, fmt.Errorf("kubectl command returned empty output for %s '%s'", kind, name)
 	}
 
-	var cm ConfigMap
-	if err := json.Unmarshal(outputBytes, &cm); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal ConfigMap JSON response: %w. Raw output: %s",
-			err, string(outputBytes))
+	return outputBytes, nil
+}
+
+// decodeObject unmarshals a resource fetched with kubectl into a ConfigMap.
+func decodeObject(kind string, data []byte) (*ConfigMap, error) {
+	if kind == ArgoCDResource {
+		var cr ArgoCD
+		if err := json.Unmarshal(data, &cr); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s JSON response: %w", kind, err)
+		}
+		return argoCDObject(&cr), nil
 	}
 
-	if cm.Data == nil {
-		return nil, fmt.Errorf("ConfigMap '%s' has no data section", RBACConfigMapName)
+	var obj ConfigMap
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s JSON response: %w", kind, err)
+	}
+
+	return &obj, nil
+}
+
+// patchResource applies JSON patch operations to a resource in the Argo CD namespace.
+// In dry-run mode the patch is only validated by the API server and the resulting diff is logged.
+func (c *Client) patchResource(ctx context.Context, kind string, name string, ops []jsonPatchOp) error {
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch for %s %s: %w", kind, name, err)
 	}
 
-	return &cm, nil
+	if c.dryRun {
+		return c.dryRunPatch(ctx, kind, name, string(patch))
+	}
+
+	return c.runKubectlCommand(
+		ctx,
+		"patch",
+		kind,
+		name,
+		NamespaceFlag,
+		c.namespace,
+		"--type=json",
+		fmt.Sprintf("-p=%s", patch),
+	)
+}
+
+// dryRunPatch submits a patch with server-side dry-run and logs what it would change.
+// Secret values are never logged.
+func (c *Client) dryRunPatch(ctx context.Context, kind string, name string, patch string) error {
+	before, err := c.getObject(ctx, kind, name)
+	if err != nil {
+		return err
+	}
+
+	output, err := c.runKubectlCommandWithOutput(ctx,
+		"patch",
+		kind,
+		name,
+		NamespaceFlag,
+		c.namespace,
+		"--type=json",
+		fmt.Sprintf("-p=%s", patch),
+		DryRunServerFlag,
+		OutputFlag,
+		JSONOutput,
+	)
+	if err != nil {
+		return fmt.Errorf("dry run rejected by the API server: %w", err)
+	}
+
+	after, err := decodeObject(kind, output)
+	if err != nil {
+		return fmt.Errorf("failed to decode dry run result for %s %s: %w", kind, name, err)
+	}
+
+	ctxzap.Extract(ctx).Info("dry run: skipped write",
+		zap.String("resource", kind+"/"+name),
+		zap.String("namespace", c.namespace),
+		zap.Strings("diff", diffObjects(before, after, kind == SecretResource)),
+	)
+
+	return nil
+}
+
+// editRBACPolicy applies edit to the `policy.csv` of the argocd-rbac-cm ConfigMap and writes the result
+// if edit reports a change. edit is also given the ConfigMap's data, to check other keys such as
+// policy.default. It reports whether anything was written.
+func (c *Client) editRBACPolicy(
+	ctx context.Context,
+	lastChange *LastChange,
+	edit func(doc *PolicyDocument, data map[string]string) (bool, error),
+) (bool, error) {
+	return c.editConfigMap(ctx, RBACConfigMapName, lastChange, func(data map[string]string) (map[string]string, []string, error) {
+		policyCsv, ok := data[PolicyCSVKey]
+		if !ok && c.gitops != nil && c.gitops.Manages(RBACConfigMapName) {
+			// A manifest, and in particular a kustomize patch, that doesn't define policy.csv
+			// doesn't tell us the current policy, so writing one would drop every existing line.
+			return nil, nil, fmt.Errorf("the manifest for %s does not define %s", RBACConfigMapName, PolicyCSVKey)
+		}
+
+		doc := ParsePolicyDocument(policyCsv)
+		changed, err := edit(doc, data)
+		if err != nil || !changed {
+			return nil, nil, err
+		}
+		return map[string]string{PolicyCSVKey: doc.String()}, nil, nil
+	})
+}
+
+// editConfigMap applies edit to the data of the named ConfigMap and writes the keys it sets and removes.
+// ConfigMaps managed by the configuration repository are changed there; all others are patched in the
+// cluster, recording the change in their last-change annotation, in a single patch that only applies to the
+// resourceVersion the edit was computed from. It reports whether anything was written.
+func (c *Client) editConfigMap(
+	ctx context.Context,
+	name string,
+	lastChange *LastChange,
+	edit func(data map[string]string) (map[string]string, []string, error),
+) (bool, error) {
+	if c.gitops != nil && c.gitops.Manages(name) {
+		return c.editConfigMapInGit(ctx, name, lastChange, edit)
+	}
+
+	install, err := c.DetectInstallation(ctx)
+	if err != nil {
+		return false, err
+	}
+	if install.Type == InstallOperator {
+		return c.editArgoCD(ctx, install.ArgoCD, name, lastChange, edit)
+	}
+
+	cm, err := c.getConfigMap(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s configmap: %w", name, err)
+	}
+
+	set, remove, err := edit(cm.Data)
+	if err != nil {
+		return false, err
+	}
+
+	ops := dataPatchOps(cm, set, remove)
+	if len(ops) == 0 {
+		return false, nil
+	}
+
+	if err := c.checkControllerOwner(ctx, ConfigMapResource, cm.Metadata); err != nil {
+		return false, err
+	}
+
+	annotationOps, err := lastChangePatchOps(cm, lastChange)
+	if err != nil {
+		return false, err
+	}
+	ops = append(resourceVersionTestOps(cm.Metadata), append(ops, annotationOps...)...)
+
+	if err := c.patchResource(ctx, ConfigMapResource, name, ops); err != nil {
+		return false, patchError(fmt.Sprintf("%s configmap", name), err)
+	}
+
+	return true, nil
+}
+
+// resourceVersionTestOps returns the JSON patch operation making a patch apply only to the version of the object
+// it was computed from, so a write never overwrites a change made since the object was read.
+func resourceVersionTestOps(meta ObjectMeta) []jsonPatchOp {
+	if meta.ResourceVersion == "" {
+		return nil
+	}
+	return []jsonPatchOp{{Op: "test", Path: "/metadata/resourceVersion", Value: meta.ResourceVersion}}
+}
+
+// patchError describes a failed patch of an object, telling a patch refused by its resourceVersion test apart.
+func patchError(object string, err error) error {
+	if strings.Contains(err.Error(), "testing value /metadata/resourceVersion") {
+		return fmt.Errorf("%s was changed since it was read, nothing was written: %w", object, err)
+	}
+	return fmt.Errorf("failed to patch %s: %w", object, err)
+}
+
+// dataPatchOps returns the JSON patch operations setting and removing data keys of an object.
+// Keys that already hold the requested value, or that are already absent, are skipped.
+func dataPatchOps(obj *ConfigMap, set map[string]string, remove []string) []jsonPatchOp {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ops []jsonPatchOp
+	for _, key := range keys {
+		current, ok := obj.Data[key]
+		switch {
+		case !ok:
+			ops = append(ops, jsonPatchOp{Op: "add", Path: "/data/" + escapeJSONPointer(key), Value: set[key]})
+		case current != set[key]:
+			ops = append(ops, jsonPatchOp{Op: "replace", Path: "/data/" + escapeJSONPointer(key), Value: set[key]})
+		}
+	}
+	for _, key := range remove {
+		if _, ok := obj.Data[key]; ok {
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: "/data/" + escapeJSONPointer(key)})
+		}
+	}
+	return ops
 }
 
 // cleanURLForCLI removes the protocol from the URL as the ArgoCD CLI doesn't accept it.
@@ -225,7 +468,7 @@ func (c *Client) ensureLoggedIn(ctx context.Context) error {
 
 // runArgoCDCommandDirect executes an ArgoCD CLI command without ensuring login first.
 func (c *Client) runArgoCDCommandDirect(ctx context.Context, args ...string) error {
-	return executeCommand(ctx, ArgoCDCommand, args...)
+	return executeCommand(ctx, ArgoCDCommand, c.argoCDArgs(args)...)
 }
 
 // runArgoCDCommandWithOutput executes an ArgoCD CLI command and returns the output.
@@ -234,12 +477,34 @@ func (c *Client) runArgoCDCommandWithOutput(ctx context.Context, args ...string)
 		return nil, fmt.Errorf("failed to ensure login: %w", err)
 	}
 
-	return executeCommandWithOutput(ctx, ArgoCDCommand, args...)
+	return executeCommandWithOutput(ctx, ArgoCDCommand, c.argoCDArgs(args)...)
+}
+
+// argoCDArgs points an ArgoCD CLI command at the client's own CLI configuration, if it has one,
+// so that sessions of different instances don't replace each other.
+func (c *Client) argoCDArgs(args []string) []string {
+	if c.argoCDConfig == "" {
+		return args
+	}
+	return append([]string{ArgoCDConfigFlag, c.argoCDConfig}, args...)
 }
 
 // runKubectlCommand executes a kubectl command and returns an error if it fails.
 func (c *Client) runKubectlCommand(ctx context.Context, args ...string) error {
-	return executeCommand(ctx, Kubectl, args...)
+	return executeCommand(ctx, Kubectl, c.kubectlArgs(args)...)
+}
+
+// runKubectlCommandWithOutput executes a kubectl command and returns its output.
+func (c *Client) runKubectlCommandWithOutput(ctx context.Context, args ...string) ([]byte, error) {
+	return executeCommandWithOutput(ctx, Kubectl, c.kubectlArgs(args)...)
+}
+
+// kubectlArgs points a kubectl command at the client's kubeconfig context, if it has one.
+func (c *Client) kubectlArgs(args []string) []string {
+	if c.kubeContext == "" {
+		return args
+	}
+	return append([]string{KubeContextFlag, c.kubeContext}, args...)
 }
 
 // getRoleNamesFromCSV extracts all unique role names from the policy CSV data.
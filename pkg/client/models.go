@@ -1,16 +1,28 @@
 package client
 
+import (
+	"strings"
+	"time"
+)
+
 // Account represents an account from the ArgoCD CLI.
 type Account struct {
 	Name         string         `json:"name"`
 	Enabled      bool           `json:"enabled"`
 	Capabilities []string       `json:"capabilities"`
 	Tokens       []AccountToken `json:"tokens,omitempty"`
+	// PasswordMtime is when the account's password was last set, read from argocd-secret. It is zero
+	// for accounts without a password or when argocd-secret can't be read.
+	PasswordMtime time.Time `json:"-"`
+	// Email is the email of the account's owner, from argocd-cm or the configured account emails.
+	Email string `json:"-"`
 }
 
+// AccountToken is an API token issued for an account. Times are Unix seconds; a zero ExpiresAt never expires.
 type AccountToken struct {
-	ID       string `json:"id"`
-	IssuedAt int64  `json:"issuedAt"`
+	ID        string `json:"id"`
+	IssuedAt  int64  `json:"issuedAt"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"`
 }
 
 // Role represents a role from the ArgoCD RBAC config map.
@@ -20,7 +32,25 @@ type Role struct {
 
 // ConfigMap is used to unmarshal the data from kubectl.
 type ConfigMap struct {
-	Data map[string]string `json:"data"`
+	Metadata ObjectMeta        `json:"metadata"`
+	Data     map[string]string `json:"data"`
+}
+
+// ObjectMeta holds the Kubernetes object metadata the client needs.
+type ObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	OwnerReferences []OwnerReference  `json:"ownerReferences,omitempty"`
+}
+
+// OwnerReference identifies the object owning a Kubernetes object.
+type OwnerReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
 }
 
 // PolicyGrant represents a 'g' policy from the ArgoCD RBAC config map.
@@ -30,9 +60,13 @@ type PolicyGrant struct {
 }
 
 // PolicyBinding represents a 'g' line in the policy, binding a subject to a role.
+// Managed is set when the line carries the connector's provenance marker, in which case
+// Change describes the write that added it.
 type PolicyBinding struct {
 	Subject string
 	Role    string
+	Managed bool
+	Change  *ChangeRecord
 }
 
 // PolicyDefinition represents a 'p' line, defining a permission for a role.
@@ -40,4 +74,66 @@ type PolicyDefinition struct {
 	Role     string
 	Resource string
 	Action   string
+	Object   string
+	Effect   string
+}
+
+// String renders the permission as the `p` line of policy.csv granting it to its role.
+func (p *PolicyDefinition) String() string {
+	return formatPolicyRecord([]string{
+		PolicyTypeDefinition,
+		RolePrefix + strings.TrimPrefix(p.Role, RolePrefix),
+		p.Resource,
+		p.Action,
+		p.Object,
+		p.Effect,
+	}, ", ")
+}
+
+// Project represents an Argo CD AppProject.
+type Project struct {
+	Name        string
+	Description string
+	Roles       []*ProjectRole
+}
+
+// ProjectRole represents a role defined in an AppProject, used by CI pipelines through its JWT tokens.
+type ProjectRole struct {
+	Project     string
+	Name        string
+	Description string
+	Policies    []string
+	Groups      []string
+	Tokens      []*ProjectToken
+}
+
+// ProjectToken is a JWT token issued for a project role. Argo CD only stores its metadata; a token is
+// identified within its role by the time it was issued.
+type ProjectToken struct {
+	ID        string `json:"id,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// Application represents an Argo CD Application.
+type Application struct {
+	Name      string
+	Namespace string
+	Project   string
+	// RBACObject is the object RBAC policies are matched against: `<project>/<application>`, or
+	// `<project>/<namespace>/<application>` for applications outside Argo CD's namespace.
+	RBACObject string
+	// ApplicationSet is the name of the ApplicationSet, in the same namespace, that generated the application.
+	ApplicationSet string
+}
+
+// ApplicationSet represents an Argo CD ApplicationSet.
+type ApplicationSet struct {
+	Name      string
+	Namespace string
+	// Project is the project of the Applications the ApplicationSet generates, taken from its template.
+	// It may itself be a template expression.
+	Project string
+	// RBACObject is the object RBAC policies are matched against, formed like an application's.
+	RBACObject string
 }
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// NotAppliedDryRun is the reason of a write that was only computed and logged in dry-run mode.
+	NotAppliedDryRun = "dry_run"
+	// NotAppliedPendingReview is the reason of a change pushed to a review branch of the configuration
+	// repository, which takes effect once the branch is merged.
+	NotAppliedPendingReview = "pending_review"
+
+	// notAppliedKey is the key of the struct annotation marking a write that didn't take effect.
+	notAppliedKey = "baton_argo_cd_not_applied"
+)
+
+// NotApplied describes a write that was accepted without taking effect. It is returned among the annotations
+// of the write, so that a successful result isn't mistaken for a change that was made.
+type NotApplied struct {
+	Reason string
+	// Branch is the review branch holding a change pending review.
+	Branch string
+}
+
+// Annotation encodes n as a struct annotation.
+func (n *NotApplied) Annotation() *structpb.Struct {
+	fields := map[string]*structpb.Value{
+		"reason": structpb.NewStringValue(n.Reason),
+	}
+	if n.Branch != "" {
+		fields["branch"] = structpb.NewStringValue(n.Branch)
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		notAppliedKey: structpb.NewStructValue(&structpb.Struct{Fields: fields}),
+	}}
+}
+
+// GetNotApplied returns the NotApplied annotation among annos, or nil if the write they belong to took effect.
+func GetNotApplied(annos annotations.Annotations) *NotApplied {
+	for _, a := range annos {
+		s := &structpb.Struct{}
+		if !a.MessageIs(s) || a.UnmarshalTo(s) != nil {
+			continue
+		}
+		value, ok := s.GetFields()[notAppliedKey]
+		if !ok {
+			continue
+		}
+		fields := value.GetStructValue().GetFields()
+		return &NotApplied{
+			Reason: fields["reason"].GetStringValue(),
+			Branch: fields["branch"].GetStringValue(),
+		}
+	}
+	return nil
+}
+
+// notApplied returns the annotations of a write made by the client: a NotApplied annotation in dry-run mode or
+// when the change was pushed to a review branch, and none when the write took effect. lastChange may be nil
+// for writes that never go through the configuration repository.
+func (c *Client) notApplied(lastChange *LastChange) annotations.Annotations {
+	switch {
+	case c.dryRun:
+		return annotations.New((&NotApplied{Reason: NotAppliedDryRun}).Annotation())
+	case lastChange != nil && lastChange.PendingBranch != "":
+		return annotations.New((&NotApplied{Reason: NotAppliedPendingReview, Branch: lastChange.PendingBranch}).Annotation())
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// InstallType is how Argo CD's configuration is managed in the cluster.
+type InstallType string
+
+const (
+	// InstallAuto detects the install type from the ArgoCD resources in the namespace.
+	InstallAuto InstallType = "auto"
+	// InstallConfigMap is a manifest or Helm install, configured through argocd-cm and argocd-rbac-cm directly.
+	InstallConfigMap InstallType = "configmap"
+	// InstallOperator is an Argo CD Operator or OpenShift GitOps install, configured through an ArgoCD resource
+	// from which the operator generates, and keeps overwriting, argocd-cm and argocd-rbac-cm.
+	InstallOperator InstallType = "operator"
+)
+
+// PolicyScopesKey is the key of the OIDC scopes used for RBAC in argocd-rbac-cm.
+const PolicyScopesKey = "scopes"
+
+// argoCDRBACFields maps argocd-rbac-cm keys to the fields of the ArgoCD resource's spec.rbac they are generated from.
+var argoCDRBACFields = map[string]string{
+	PolicyCSVKey:       "policy",
+	PolicyDefaultKey:   "defaultPolicy",
+	PolicyScopesKey:    "scopes",
+	"policy.matchMode": "policyMatcherMode",
+}
+
+// Installation describes the detected Argo CD installation.
+type Installation struct {
+	Type      InstallType
+	Namespace string
+	// ArgoCD is the name of the ArgoCD resource of operator installations.
+	ArgoCD string
+}
+
+// ArgoCD is the subset of the operator's ArgoCD resource the client reads and writes.
+type ArgoCD struct {
+	Metadata ObjectMeta  `json:"metadata"`
+	Spec     *ArgoCDSpec `json:"spec,omitempty"`
+}
+
+// ArgoCDSpec holds the RBAC settings, which the operator copies to argocd-rbac-cm, extraConfig,
+// which it merges into argocd-cm, and the namespaces outside its own the instance reconciles.
+type ArgoCDSpec struct {
+	RBAC             map[string]string `json:"rbac,omitempty"`
+	ExtraConfig      map[string]string `json:"extraConfig,omitempty"`
+	SourceNamespaces []string          `json:"sourceNamespaces,omitempty"`
+	ApplicationSet   *struct {
+		SourceNamespaces []string `json:"sourceNamespaces,omitempty"`
+	} `json:"applicationSet,omitempty"`
+}
+
+// WithNamespace sets the namespace Argo CD is installed in. The default is "argocd".
+func WithNamespace(namespace string) Option {
+	return func(c *Client) {
+		if namespace != "" {
+			c.namespace = namespace
+		}
+	}
+}
+
+// WithInstallType sets how Argo CD's configuration is written. For InstallOperator and InstallAuto, argoCDName
+// names the ArgoCD resource; when empty, the only one in the namespace is used.
+func WithInstallType(installType InstallType, argoCDName string) Option {
+	return func(c *Client) {
+		c.installType = installType
+		c.argoCDName = argoCDName
+	}
+}
+
+// DetectInstallation returns the Argo CD installation the client writes to, looking up the ArgoCD resource
+// the first time it is called for operator and auto-detected installs.
+func (c *Client) DetectInstallation(ctx context.Context) (*Installation, error) {
+	c.installMu.Lock()
+	defer c.installMu.Unlock()
+
+	if c.install != nil {
+		return c.install, nil
+	}
+
+	install := &Installation{Type: c.installType, Namespace: c.namespace}
+	if c.installType != InstallConfigMap {
+		name, err := c.findArgoCD(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case name != "":
+			install.Type = InstallOperator
+			install.ArgoCD = name
+		case c.installType == InstallOperator:
+			return nil, fmt.Errorf("no ArgoCD resource found in namespace '%s'", c.namespace)
+		default:
+			install.Type = InstallConfigMap
+		}
+	}
+
+	c.install = install
+	return install, nil
+}
+
+// findArgoCD returns the name of the ArgoCD resource in the namespace, or "" if there is none
+// or the operator's CRD isn't installed.
+func (c *Client) findArgoCD(ctx context.Context) (string, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx, GetCommand, ArgoCDResource, NamespaceFlag, c.namespace, OutputFlag, JSONOutput)
+	if err != nil {
+		if strings.Contains(err.Error(), "doesn't have a resource type") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to list ArgoCD resources in namespace '%s': %w", c.namespace, err)
+	}
+
+	var list struct {
+		Items []ArgoCD `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return "", fmt.Errorf("failed to unmarshal ArgoCD list: %w", err)
+	}
+
+	var names []string
+	for _, item := range list.Items {
+		if c.argoCDName == "" || item.Metadata.Name == c.argoCDName {
+			names = append(names, item.Metadata.Name)
+		}
+	}
+
+	switch len(names) {
+	case 0:
+		if c.argoCDName != "" && c.installType == InstallOperator {
+			return "", fmt.Errorf("ArgoCD resource '%s' not found in namespace '%s'", c.argoCDName, c.namespace)
+		}
+		return "", nil
+	case 1:
+		return names[0], nil
+	default:
+		return "", fmt.Errorf("found %d ArgoCD resources in namespace '%s' (%s), set the ArgoCD resource name",
+			len(names), c.namespace, strings.Join(names, ", "))
+	}
+}
+
+// getArgoCD fetches the named ArgoCD resource.
+func (c *Client) getArgoCD(ctx context.Context, name string) (*ArgoCD, error) {
+	output, err := c.getJSON(ctx, ArgoCDResource, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var cr ArgoCD
+	if err := json.Unmarshal(output, &cr); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s JSON response: %w", ArgoCDResource, err)
+	}
+	return &cr, nil
+}
+
+// editArgoCD is editConfigMap for operator installs: argocd-rbac-cm keys are read from and written to
+// spec.rbac, and argocd-cm keys to spec.extraConfig, of the ArgoCD resource.
+func (c *Client) editArgoCD(
+	ctx context.Context,
+	argoCDName string,
+	name string,
+	lastChange *LastChange,
+	edit func(data map[string]string) (map[string]string, []string, error),
+) (bool, error) {
+	cr, err := c.getArgoCD(ctx, argoCDName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get ArgoCD resource: %w", err)
+	}
+
+	set, remove, err := edit(argoCDData(cr, name))
+	if err != nil {
+		return false, err
+	}
+
+	ops, err := argoCDPatchOps(cr, name, set, remove)
+	if err != nil {
+		return false, err
+	}
+	if len(ops) == 0 {
+		return false, nil
+	}
+
+	if err := c.checkControllerOwner(ctx, ArgoCDResource, cr.Metadata); err != nil {
+		return false, err
+	}
+
+	annotationOps, err := lastChangePatchOps(&ConfigMap{Metadata: cr.Metadata}, lastChange)
+	if err != nil {
+		return false, err
+	}
+	ops = append(resourceVersionTestOps(cr.Metadata), append(ops, annotationOps...)...)
+
+	if err := c.patchResource(ctx, ArgoCDResource, argoCDName, ops); err != nil {
+		return false, patchError("ArgoCD resource "+argoCDName, err)
+	}
+
+	return true, nil
+}
+
+// argoCDData returns the data of the named ConfigMap as defined in the ArgoCD resource.
+func argoCDData(cr *ArgoCD, name string) map[string]string {
+	data := map[string]string{}
+	if cr.Spec == nil {
+		return data
+	}
+
+	switch name {
+	case RBACConfigMapName:
+		for key, field := range argoCDRBACFields {
+			if value, ok := cr.Spec.RBAC[field]; ok {
+				data[key] = value
+			}
+		}
+	case ArgoCDConfigMapName:
+		for key, value := range cr.Spec.ExtraConfig {
+			data[key] = value
+		}
+	}
+	return data
+}
+
+// argoCDPatchOps returns the JSON patch operations writing ConfigMap keys to the ArgoCD resource.
+func argoCDPatchOps(cr *ArgoCD, name string, set map[string]string, remove []string) ([]jsonPatchOp, error) {
+	var parent string
+	var current map[string]string
+	fields := map[string]string{}
+
+	switch name {
+	case RBACConfigMapName:
+		parent = "/spec/rbac"
+		for key, field := range argoCDRBACFields {
+			fields[key] = field
+		}
+	case ArgoCDConfigMapName:
+		parent = "/spec/extraConfig"
+		for key := range set {
+			fields[key] = key
+		}
+		for _, key := range remove {
+			fields[key] = key
+		}
+	default:
+		return nil, fmt.Errorf("ConfigMap '%s' is not managed through the ArgoCD resource", name)
+	}
+	if cr.Spec != nil {
+		current = cr.Spec.RBAC
+		if name == ArgoCDConfigMapName {
+			current = cr.Spec.ExtraConfig
+		}
+	}
+
+	// Map the edit to spec fields first, so that an unsupported key fails the whole edit.
+	setFields := make(map[string]string, len(set))
+	for key, value := range set {
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("'%s' of %s cannot be set through the ArgoCD resource", key, name)
+		}
+		setFields[field] = value
+	}
+	var removeFields []string
+	for _, key := range remove {
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("'%s' of %s cannot be removed through the ArgoCD resource", key, name)
+		}
+		removeFields = append(removeFields, field)
+	}
+
+	ops := dataPatchOps(&ConfigMap{Data: current}, setFields, removeFields)
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	for i := range ops {
+		ops[i].Path = parent + strings.TrimPrefix(ops[i].Path, "/data")
+	}
+
+	// Create the parent objects the operations write into.
+	var parents []jsonPatchOp
+	if cr.Spec == nil {
+		parents = append(parents, jsonPatchOp{Op: "add", Path: "/spec", Value: map[string]string{}})
+	}
+	if current == nil {
+		parents = append(parents, jsonPatchOp{Op: "add", Path: parent, Value: map[string]string{}})
+	}
+
+	return append(parents, ops...), nil
+}
+
+// argoCDObject flattens an ArgoCD resource into a ConfigMap, for dry-run diffs, with keys such as
+// `spec.rbac.policy` and `spec.extraConfig.accounts.alice`.
+func argoCDObject(cr *ArgoCD) *ConfigMap {
+	obj := &ConfigMap{Metadata: cr.Metadata, Data: map[string]string{}}
+	if cr.Spec == nil {
+		return obj
+	}
+
+	for _, section := range []struct {
+		prefix string
+		values map[string]string
+	}{
+		{"spec.rbac.", cr.Spec.RBAC},
+		{"spec.extraConfig.", cr.Spec.ExtraConfig},
+	} {
+		for key, value := range section.values {
+			obj.Data[section.prefix+key] = value
+		}
+	}
+	return obj
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArgoCDData tests reading ConfigMap data from the ArgoCD resource.
+func TestArgoCDData(t *testing.T) {
+	cr := &ArgoCD{Spec: &ArgoCDSpec{
+		RBAC: map[string]string{
+			"policy":        "g, alice, role:admin\n",
+			"defaultPolicy": "role:readonly",
+			"scopes":        "[groups]",
+		},
+		ExtraConfig: map[string]string{"accounts.alice": "apiKey, login"},
+	}}
+
+	assert.Equal(t, map[string]string{
+		PolicyCSVKey:     "g, alice, role:admin\n",
+		PolicyDefaultKey: "role:readonly",
+		PolicyScopesKey:  "[groups]",
+	}, argoCDData(cr, RBACConfigMapName))
+	assert.Equal(t, map[string]string{"accounts.alice": "apiKey, login"}, argoCDData(cr, ArgoCDConfigMapName))
+	assert.Empty(t, argoCDData(&ArgoCD{}, RBACConfigMapName))
+}
+
+// TestArgoCDPatchOps tests writing ConfigMap keys to the ArgoCD resource.
+func TestArgoCDPatchOps(t *testing.T) {
+	t.Run("rbac policy", func(t *testing.T) {
+		cr := &ArgoCD{Spec: &ArgoCDSpec{RBAC: map[string]string{"policy": "g, alice, role:admin\n", "scopes": "[groups]"}}}
+
+		ops, err := argoCDPatchOps(cr, RBACConfigMapName,
+			map[string]string{PolicyCSVKey: "g, alice, role:admin\ng, bob, role:admin\n", PolicyDefaultKey: "role:readonly"},
+			[]string{PolicyScopesKey},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []jsonPatchOp{
+			{Op: "add", Path: "/spec/rbac/defaultPolicy", Value: "role:readonly"},
+			{Op: "replace", Path: "/spec/rbac/policy", Value: "g, alice, role:admin\ng, bob, role:admin\n"},
+			{Op: "remove", Path: "/spec/rbac/scopes"},
+		}, ops)
+	})
+
+	t.Run("extra config creates missing parents", func(t *testing.T) {
+		ops, err := argoCDPatchOps(&ArgoCD{}, ArgoCDConfigMapName, map[string]string{"accounts.alice": "apiKey, login"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []jsonPatchOp{
+			{Op: "add", Path: "/spec", Value: map[string]string{}},
+			{Op: "add", Path: "/spec/extraConfig", Value: map[string]string{}},
+			{Op: "add", Path: "/spec/extraConfig/accounts.alice", Value: "apiKey, login"},
+		}, ops)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		cr := &ArgoCD{Spec: &ArgoCDSpec{RBAC: map[string]string{"policy": "g, alice, role:admin\n"}}}
+		ops, err := argoCDPatchOps(cr, RBACConfigMapName, map[string]string{PolicyCSVKey: "g, alice, role:admin\n"}, nil)
+		require.NoError(t, err)
+		assert.Empty(t, ops)
+	})
+
+	t.Run("unsupported rbac key", func(t *testing.T) {
+		_, err := argoCDPatchOps(&ArgoCD{}, RBACConfigMapName, map[string]string{"policy.team.csv": "g, a, b"}, nil)
+		assert.ErrorContains(t, err, "cannot be set through the ArgoCD resource")
+	})
+}
+
+// TestDetectInstallation tests that configured ConfigMap installs are not probed for ArgoCD resources.
+func TestDetectInstallation(t *testing.T) {
+	c := NewClient(context.Background(), "https://test.com", "admin", "password", WithNamespace("openshift-gitops"))
+
+	install, err := c.DetectInstallation(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &Installation{Type: InstallConfigMap, Namespace: "openshift-gitops"}, install)
+}
@@ -0,0 +1,464 @@
+package client
+
+import (
+	"encoding/csv"
+	"slices"
+	"strings"
+)
+
+// PolicyDocument is a lossless, line-level model of an Argo CD `policy.csv`.
+// Unlike a round trip through encoding/csv, it keeps comments, blank lines,
+// spacing and quoting exactly as found, and only touches the lines that an
+// edit actually adds or removes.
+type PolicyDocument struct {
+	lines []*policyLine
+}
+
+// policyLine is a single line of the policy. Fields is nil for comments,
+// blank lines and lines that cannot be parsed as a policy record.
+type policyLine struct {
+	Raw    string
+	Fields []string
+}
+
+// ParsePolicyDocument parses the raw `policy.csv` contents into a PolicyDocument.
+func ParsePolicyDocument(data string) *PolicyDocument {
+	rawLines := strings.Split(data, "\n")
+	doc := &PolicyDocument{lines: make([]*policyLine, 0, len(rawLines))}
+	for _, raw := range rawLines {
+		doc.lines = append(doc.lines, newPolicyLine(raw))
+	}
+	return doc
+}
+
+// String renders the document back to its `policy.csv` form.
+func (d *PolicyDocument) String() string {
+	raws := make([]string, 0, len(d.lines))
+	for _, line := range d.lines {
+		raws = append(raws, line.Raw)
+	}
+	return strings.Join(raws, "\n")
+}
+
+// HasBinding reports whether a `g` line binds the subject to the role.
+func (d *PolicyDocument) HasBinding(subject string, role string) bool {
+	for _, line := range d.lines {
+		if line.isBinding(subject, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddBinding adds a `g, subject, role:<role>` line unless the binding already exists.
+// The new line is placed after the last existing binding to the same role, or after the
+// last binding in the document, and otherwise appended at the end. When change is set,
+// a provenance marker line describing it is written directly above the binding.
+// It reports whether the document was changed.
+func (d *PolicyDocument) AddBinding(subject string, role string, change *ChangeRecord) bool {
+	if d.HasBinding(subject, role) {
+		return false
+	}
+
+	record := []string{PolicyTypeGrant, subject, RolePrefix + strings.TrimPrefix(role, RolePrefix)}
+	i := d.bindingInsertIndex(role)
+	d.insert(i, formatPolicyRecord(record, d.separator()))
+	if change != nil {
+		d.insert(i, change.marker())
+	}
+	return true
+}
+
+// RemoveBinding removes every `g` line binding the subject to the role, including duplicates
+// and the provenance markers that belong to them. It returns the number of bindings removed.
+func (d *PolicyDocument) RemoveBinding(subject string, role string) int {
+	return d.remove(func(line *policyLine) bool {
+		return line.isBinding(subject, role)
+	})
+}
+
+// HasRole reports whether any line defines a permission of the role or binds a subject to it.
+func (d *PolicyDocument) HasRole(role string) bool {
+	for _, line := range d.lines {
+		if line.isPolicyOf(role) || line.referencesRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPolicy reports whether a `p` line grants the permission to its role.
+func (d *PolicyDocument) HasPolicy(policy *PolicyDefinition) bool {
+	for _, line := range d.lines {
+		if line.isPolicy(policy) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddPolicy adds a `p, role:<role>, resource, action, object, effect` line unless it already exists.
+// The new line is placed after the last existing permission of the same role, or after the last
+// permission in the document, and otherwise before the first binding or at the end. When change is set,
+// a provenance marker line describing it is written directly above the permission.
+// It reports whether the document was changed.
+func (d *PolicyDocument) AddPolicy(policy *PolicyDefinition, change *ChangeRecord) bool {
+	if d.HasPolicy(policy) {
+		return false
+	}
+
+	record := []string{
+		PolicyTypeDefinition,
+		RolePrefix + strings.TrimPrefix(policy.Role, RolePrefix),
+		policy.Resource,
+		policy.Action,
+		policy.Object,
+		policy.Effect,
+	}
+	i := d.policyInsertIndex(policy.Role)
+	d.insert(i, formatPolicyRecord(record, d.separator()))
+	if change != nil {
+		d.insert(i, change.marker())
+	}
+	return true
+}
+
+// RemovePolicy removes every `p` line granting the permission to its role, including duplicates and
+// their provenance markers. It returns the number of permissions removed.
+func (d *PolicyDocument) RemovePolicy(policy *PolicyDefinition) int {
+	return d.remove(func(line *policyLine) bool {
+		return line.isPolicy(policy)
+	})
+}
+
+// RemoveRole removes the `p` lines of the role and every `g` line referencing it, whether binding a
+// subject to the role or the role to another one, with their provenance markers.
+// It returns the number of permissions and bindings removed.
+func (d *PolicyDocument) RemoveRole(role string) (int, int) {
+	policies := d.remove(func(line *policyLine) bool {
+		return line.isPolicyOf(role)
+	})
+	bindings := d.remove(func(line *policyLine) bool {
+		return line.referencesRole(role)
+	})
+	return policies, bindings
+}
+
+// RenameRole rewrites every line referencing the role to reference newRole instead: its `p` lines, the `g`
+// lines binding subjects to it and those binding it to other roles. Rewritten lines use the document's
+// separator. It returns the number of lines rewritten.
+func (d *PolicyDocument) RenameRole(role string, newRole string) int {
+	oldSubject := RolePrefix + strings.TrimPrefix(role, RolePrefix)
+	newSubject := RolePrefix + strings.TrimPrefix(newRole, RolePrefix)
+	separator := d.separator()
+
+	renamed := 0
+	for i, line := range d.lines {
+		var fields []string
+		switch {
+		case line.isPolicyOf(role):
+			fields = slices.Clone(line.Fields)
+			fields[1] = newSubject
+		case line.referencesRole(role):
+			fields = slices.Clone(line.Fields)
+			if fields[1] == oldSubject {
+				fields[1] = newSubject
+			}
+			if sameRole(fields[2], role) {
+				fields[2] = newSubject
+			}
+		default:
+			continue
+		}
+		d.lines[i] = newPolicyLine(formatPolicyRecord(fields, separator))
+		renamed++
+	}
+	return renamed
+}
+
+// MoveBindings rebinds every subject bound to the role to newRole, removing its bindings to the role and
+// adding a binding to newRole, with a provenance marker, unless it already has one. newRole itself, when it
+// inherits the role, is left bound to it. It returns the subjects moved, in order of appearance.
+func (d *PolicyDocument) MoveBindings(role string, newRole string, change *ChangeRecord) []string {
+	newSubject := RolePrefix + strings.TrimPrefix(newRole, RolePrefix)
+	var subjects []string
+	for _, binding := range d.Bindings() {
+		if sameRole(binding.Role, role) && binding.Subject != newSubject && !slices.Contains(subjects, binding.Subject) {
+			subjects = append(subjects, binding.Subject)
+		}
+	}
+	for _, subject := range subjects {
+		d.RemoveBinding(subject, role)
+		d.AddBinding(subject, newRole, change)
+	}
+	return subjects
+}
+
+// InheritedRoles returns the roles, named without the `role:` prefix, that `g` lines bind the role to, in order
+// of appearance.
+func (d *PolicyDocument) InheritedRoles(role string) []string {
+	var inherited []string
+	for _, binding := range d.Bindings() {
+		if binding.Subject == RolePrefix+strings.TrimPrefix(role, RolePrefix) {
+			inherited = append(inherited, binding.Role)
+		}
+	}
+	return inherited
+}
+
+// RoleLines returns the records, without comments, that define permissions of the roles or bind subjects to
+// them or them to other roles, trimmed and in order of appearance.
+func (d *PolicyDocument) RoleLines(roles ...string) []string {
+	var lines []string
+	for _, line := range d.lines {
+		for _, role := range roles {
+			if line.isPolicyOf(role) || line.referencesRole(role) {
+				lines = append(lines, strings.TrimSpace(line.Raw))
+				break
+			}
+		}
+	}
+	return lines
+}
+
+// Policies returns the `p` lines of the document in order of appearance, with roles named without
+// the `role:` prefix.
+func (d *PolicyDocument) Policies() []*PolicyDefinition {
+	var policies []*PolicyDefinition
+	for _, line := range d.lines {
+		if policy := line.policy(); policy != nil {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// remove drops the lines matching match, along with the provenance markers directly above them.
+// It returns the number of lines removed, not counting markers.
+func (d *PolicyDocument) remove(match func(line *policyLine) bool) int {
+	kept := d.lines[:0]
+	removed := 0
+	for _, line := range d.lines {
+		if match(line) {
+			if n := len(kept); n > 0 && kept[n-1].isMarker() {
+				kept = kept[:n-1]
+			}
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	d.lines = kept
+	return removed
+}
+
+// Bindings returns the `g` lines of the document in order of appearance,
+// noting which of them were written by the connector.
+func (d *PolicyDocument) Bindings() []*PolicyBinding {
+	var bindings []*PolicyBinding
+	for i, line := range d.lines {
+		if line.recordType() != PolicyTypeGrant || len(line.Fields) < 3 {
+			continue
+		}
+		binding := &PolicyBinding{
+			Subject: line.Fields[1],
+			Role:    strings.TrimPrefix(line.Fields[2], RolePrefix),
+		}
+		if i > 0 {
+			binding.Change, binding.Managed = parseMarker(d.lines[i-1].Raw)
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings
+}
+
+// insert places a new line at index i, keeping the trailing newline of the document intact.
+func (d *PolicyDocument) insert(i int, raw string) {
+	d.lines = append(d.lines, nil)
+	copy(d.lines[i+1:], d.lines[i:])
+	d.lines[i] = newPolicyLine(raw)
+}
+
+// bindingInsertIndex returns where a new binding to the role should be inserted.
+func (d *PolicyDocument) bindingInsertIndex(role string) int {
+	lastSameRole, lastBinding := -1, -1
+	for i, line := range d.lines {
+		if line.recordType() != PolicyTypeGrant || len(line.Fields) < 3 {
+			continue
+		}
+		lastBinding = i
+		if sameRole(line.Fields[2], role) {
+			lastSameRole = i
+		}
+	}
+
+	switch {
+	case lastSameRole >= 0:
+		return lastSameRole + 1
+	case lastBinding >= 0:
+		return lastBinding + 1
+	}
+
+	// Append after the last non-blank line so a trailing newline stays trailing.
+	end := len(d.lines)
+	for end > 0 && strings.TrimSpace(d.lines[end-1].Raw) == "" {
+		end--
+	}
+	return end
+}
+
+// policyInsertIndex returns where a new permission of the role should be inserted.
+func (d *PolicyDocument) policyInsertIndex(role string) int {
+	lastSameRole, lastPolicy, firstBinding := -1, -1, -1
+	for i, line := range d.lines {
+		switch {
+		case line.policy() != nil:
+			lastPolicy = i
+			if line.isPolicyOf(role) {
+				lastSameRole = i
+			}
+		case firstBinding < 0 && line.recordType() == PolicyTypeGrant:
+			firstBinding = i
+		}
+	}
+
+	switch {
+	case lastSameRole >= 0:
+		return lastSameRole + 1
+	case lastPolicy >= 0:
+		return lastPolicy + 1
+	case firstBinding >= 0:
+		// Keep a marker above the first binding with it.
+		if firstBinding > 0 && d.lines[firstBinding-1].isMarker() {
+			return firstBinding - 1
+		}
+		return firstBinding
+	}
+
+	end := len(d.lines)
+	for end > 0 && strings.TrimSpace(d.lines[end-1].Raw) == "" {
+		end--
+	}
+	return end
+}
+
+// separator returns the field separator used by the existing records, defaulting to ", ".
+func (d *PolicyDocument) separator() string {
+	for _, line := range d.lines {
+		if line.Fields == nil {
+			continue
+		}
+		if strings.Contains(line.Raw, ", ") {
+			return ", "
+		}
+		return ","
+	}
+	return ", "
+}
+
+// newPolicyLine parses a raw line, leaving Fields nil for anything that isn't a record.
+func newPolicyLine(raw string) *policyLine {
+	line := &policyLine{Raw: raw}
+
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return line
+	}
+
+	reader := csv.NewReader(strings.NewReader(trimmed))
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	fields, err := reader.Read()
+	if err != nil || len(fields) == 0 {
+		return line
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	line.Fields = fields
+	return line
+}
+
+// recordType returns the policy type ('p' or 'g') of the line, or "" for non-records.
+func (l *policyLine) recordType() string {
+	if len(l.Fields) == 0 {
+		return ""
+	}
+	return l.Fields[0]
+}
+
+// isMarker reports whether the line is a provenance marker comment.
+func (l *policyLine) isMarker() bool {
+	_, ok := parseMarker(l.Raw)
+	return ok
+}
+
+// isBinding reports whether the line is a `g` line binding the subject to the role.
+func (l *policyLine) isBinding(subject string, role string) bool {
+	return l.recordType() == PolicyTypeGrant &&
+		len(l.Fields) >= 3 &&
+		l.Fields[1] == subject &&
+		sameRole(l.Fields[2], role)
+}
+
+// policy returns the permission of a `p` line with a role subject, or nil for any other line.
+func (l *policyLine) policy() *PolicyDefinition {
+	if l.recordType() != PolicyTypeDefinition || len(l.Fields) < 5 || !strings.HasPrefix(l.Fields[1], RolePrefix) {
+		return nil
+	}
+	policy := &PolicyDefinition{
+		Role:     strings.TrimPrefix(l.Fields[1], RolePrefix),
+		Resource: l.Fields[2],
+		Action:   l.Fields[3],
+		Object:   l.Fields[4],
+		Effect:   PolicyEffectAllow,
+	}
+	if len(l.Fields) >= 6 {
+		policy.Effect = l.Fields[5]
+	}
+	return policy
+}
+
+// isPolicyOf reports whether the line is a `p` line defining a permission of the role.
+func (l *policyLine) isPolicyOf(role string) bool {
+	policy := l.policy()
+	return policy != nil && sameRole(policy.Role, role)
+}
+
+// isPolicy reports whether the line is a `p` line granting the permission to its role.
+func (l *policyLine) isPolicy(want *PolicyDefinition) bool {
+	policy := l.policy()
+	return policy != nil &&
+		sameRole(policy.Role, want.Role) &&
+		policy.Resource == want.Resource &&
+		policy.Action == want.Action &&
+		policy.Object == want.Object &&
+		policy.Effect == want.Effect
+}
+
+// referencesRole reports whether the line is a `g` line binding a subject to the role or the role to another one.
+func (l *policyLine) referencesRole(role string) bool {
+	if l.recordType() != PolicyTypeGrant || len(l.Fields) < 3 {
+		return false
+	}
+	return sameRole(l.Fields[2], role) || l.Fields[1] == RolePrefix+strings.TrimPrefix(role, RolePrefix)
+}
+
+// sameRole compares two role names, ignoring the optional "role:" prefix.
+func sameRole(a string, b string) bool {
+	return strings.TrimPrefix(a, RolePrefix) == strings.TrimPrefix(b, RolePrefix)
+}
+
+// formatPolicyRecord renders fields as a policy line, quoting only where CSV requires it.
+func formatPolicyRecord(fields []string, separator string) string {
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if strings.ContainsAny(field, ",\"\r\n") || strings.TrimSpace(field) != field {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		quoted = append(quoted, field)
+	}
+	return strings.Join(quoted, separator)
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/policy")
+
+func readPolicyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "policy", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "policy", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o600))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+// TestPolicyDocument_RoundTrip tests that parsing and rendering a policy is lossless.
+func TestPolicyDocument_RoundTrip(t *testing.T) {
+	for _, name := range []string{"upstream_example.csv", "duplicates.csv", "quoted.csv", "compact.csv", "empty.csv"} {
+		t.Run(name, func(t *testing.T) {
+			input := readPolicyFixture(t, name)
+			assert.Equal(t, input, ParsePolicyDocument(input).String())
+		})
+	}
+}
+
+// TestPolicyDocument_AddBinding tests inserting bindings against golden files.
+func TestPolicyDocument_AddBinding(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		golden  string
+		subject string
+		role    string
+	}{
+		{"after same role", "upstream_example.csv", "upstream_example.add.golden", "grace", "org-admin"},
+		{"prefixed role", "upstream_example.csv", "upstream_example.add_prefixed.golden", "grace", "role:readonly-extra"},
+		{"new role after last binding", "upstream_example.csv", "upstream_example.add_new_role.golden", "grace", "auditor"},
+		{"quoted subject", "quoted.csv", "quoted.add.golden", "Platform, Americas", "viewer"},
+		{"compact style without trailing newline", "compact.csv", "compact.add.golden", "gina", "ops"},
+		{"empty policy", "empty.csv", "empty.add.golden", "henry", "admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := ParsePolicyDocument(readPolicyFixture(t, tt.input))
+			require.True(t, doc.AddBinding(tt.subject, tt.role, nil))
+			assert.True(t, doc.HasBinding(tt.subject, tt.role))
+			assertGolden(t, tt.golden, doc.String())
+		})
+	}
+
+	t.Run("existing binding is left untouched", func(t *testing.T) {
+		input := readPolicyFixture(t, "upstream_example.csv")
+		doc := ParsePolicyDocument(input)
+		assert.False(t, doc.AddBinding("alice", "org-admin", nil))
+		assert.False(t, doc.AddBinding("alice", "role:org-admin", nil))
+		assert.Equal(t, input, doc.String())
+	})
+}
+
+// TestPolicyDocument_RemoveBinding tests removing bindings against golden files.
+func TestPolicyDocument_RemoveBinding(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		golden  string
+		subject string
+		role    string
+		removed int
+	}{
+		{"single binding", "upstream_example.csv", "upstream_example.remove.golden", "alice", "org-admin", 1},
+		{"all duplicates and later lines kept", "duplicates.csv", "duplicates.remove.golden", "carol", "deployer", 3},
+		{"quoted subject", "quoted.csv", "quoted.remove.golden", "Platform, Europe", "role:viewer", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := ParsePolicyDocument(readPolicyFixture(t, tt.input))
+			assert.Equal(t, tt.removed, doc.RemoveBinding(tt.subject, tt.role))
+			assert.False(t, doc.HasBinding(tt.subject, tt.role))
+			assertGolden(t, tt.golden, doc.String())
+		})
+	}
+
+	t.Run("missing binding is left untouched", func(t *testing.T) {
+		input := readPolicyFixture(t, "duplicates.csv")
+		doc := ParsePolicyDocument(input)
+		assert.Equal(t, 0, doc.RemoveBinding("zoe", "deployer"))
+		assert.Equal(t, input, doc.String())
+	})
+}
+
+// TestPolicyDocument_AddPolicy tests inserting permissions against golden files.
+func TestPolicyDocument_AddPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		golden string
+		policy *PolicyDefinition
+	}{
+		{"after same role", "upstream_example.csv", "upstream_example.add_policy.golden",
+			&PolicyDefinition{Role: "org-admin", Resource: "applications", Action: "sync", Object: "staging/*", Effect: "allow"}},
+		{"new role after last permission", "upstream_example.csv", "upstream_example.add_policy_new_role.golden",
+			&PolicyDefinition{Role: "auditor", Resource: "logs", Action: "get", Object: "*/*", Effect: "allow"}},
+		{"compact style without trailing newline", "compact.csv", "compact.add_policy.golden",
+			&PolicyDefinition{Role: "ops", Resource: "applications", Action: "delete", Object: "ops/*", Effect: "deny"}},
+		{"empty policy", "empty.csv", "empty.add_policy.golden",
+			&PolicyDefinition{Role: "viewer", Resource: "applications", Action: "get", Object: "*/*", Effect: "allow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := ParsePolicyDocument(readPolicyFixture(t, tt.input))
+			require.True(t, doc.AddPolicy(tt.policy, nil))
+			assert.True(t, doc.HasPolicy(tt.policy))
+			assert.True(t, doc.HasRole(tt.policy.Role))
+			assertGolden(t, tt.golden, doc.String())
+		})
+	}
+
+	t.Run("existing permission is left untouched", func(t *testing.T) {
+		input := readPolicyFixture(t, "upstream_example.csv")
+		doc := ParsePolicyDocument(input)
+		policy := &PolicyDefinition{Role: "role:org-admin", Resource: "clusters", Action: "get", Object: "*", Effect: "allow"}
+		assert.False(t, doc.AddPolicy(policy, nil))
+		assert.Equal(t, input, doc.String())
+
+		assert.Equal(t, 1, doc.RemovePolicy(policy))
+		assert.False(t, doc.HasPolicy(policy))
+	})
+}
+
+// TestPolicyDocument_RemoveRole tests removing a role's permissions and the bindings referencing it.
+func TestPolicyDocument_RemoveRole(t *testing.T) {
+	doc := ParsePolicyDocument(readPolicyFixture(t, "upstream_example.csv") + "g, role:org-admin, role:readonly-extra\n")
+	policies, bindings := doc.RemoveRole("org-admin")
+	assert.Equal(t, 6, policies)
+	assert.Equal(t, 3, bindings)
+	assert.False(t, doc.HasRole("org-admin"))
+	assert.True(t, doc.HasRole("readonly-extra"))
+	assertGolden(t, "upstream_example.remove_role.golden", doc.String())
+
+	policies, bindings = doc.RemoveRole("auditor")
+	assert.Zero(t, policies+bindings)
+}
+
+// TestPolicyDocument_RenameRole tests rewriting every line referencing a role.
+func TestPolicyDocument_RenameRole(t *testing.T) {
+	doc := ParsePolicyDocument(readPolicyFixture(t, "upstream_example.csv") + "g, role:org-admin, role:readonly-extra\n")
+	before := doc.RoleLines("org-admin")
+	assert.Equal(t, 9, doc.RenameRole("org-admin", "platform-admin"))
+	assert.False(t, doc.HasRole("org-admin"))
+	assert.Len(t, doc.RoleLines("platform-admin"), len(before))
+	assertGolden(t, "upstream_example.rename_role.golden", doc.String())
+}
+
+// TestPolicyDocument_MoveBindings tests moving the subjects bound to a role to another.
+func TestPolicyDocument_MoveBindings(t *testing.T) {
+	doc := ParsePolicyDocument(readPolicyFixture(t, "upstream_example.csv") + "g, bob, role:org-admin\ng, role:readonly-extra, role:org-admin\n")
+	moved := doc.MoveBindings("org-admin", "readonly-extra", nil)
+	assert.Equal(t, []string{"my-org:team-alpha", "alice", "bob"}, moved)
+	assert.Equal(t, []string{"g, role:readonly-extra, role:org-admin"}, doc.RoleLines("org-admin")[6:])
+	assertGolden(t, "upstream_example.move_bindings.golden", doc.String())
+}
+
+// TestPolicyDocument_InheritedRoles tests finding the roles a role inherits, including those of the built-in admin.
+func TestPolicyDocument_InheritedRoles(t *testing.T) {
+	doc := ParsePolicyDocument(readPolicyFixture(t, "upstream_example.csv") + "g, role:org-admin, role:readonly-extra\ng, org-admin, role:auditor\n")
+	assert.Equal(t, []string{"readonly-extra"}, doc.InheritedRoles("org-admin"))
+	assert.Equal(t, []string{"readonly-extra"}, doc.InheritedRoles("role:org-admin"))
+	assert.Empty(t, doc.InheritedRoles("readonly-extra"))
+
+	assert.Equal(t, []string{"readonly"}, ParsePolicyDocument(builtinPolicy).InheritedRoles("admin"))
+}
+
+// TestPolicyDocument_Provenance tests that connector-written bindings carry a marker that survives a round trip.
+func TestPolicyDocument_Provenance(t *testing.T) {
+	change := &ChangeRecord{
+		GrantID:   "role:org-admin:assigned:user:grace",
+		RequestID: "REQ 42",
+		Time:      time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC),
+	}
+
+	doc := ParsePolicyDocument(readPolicyFixture(t, "upstream_example.csv"))
+	require.True(t, doc.AddBinding("grace", "org-admin", change))
+	assertGolden(t, "upstream_example.add_managed.golden", doc.String())
+
+	doc = ParsePolicyDocument(doc.String())
+	managed := map[string]*PolicyBinding{}
+	for _, binding := range doc.Bindings() {
+		managed[binding.Subject] = binding
+	}
+	require.Contains(t, managed, "grace")
+	assert.True(t, managed["grace"].Managed)
+	assert.Equal(t, change, managed["grace"].Change)
+	assert.False(t, managed["alice"].Managed)
+	assert.Nil(t, managed["alice"].Change)
+
+	assert.Equal(t, 1, doc.RemoveBinding("grace", "role:org-admin"))
+	assert.Equal(t, readPolicyFixture(t, "upstream_example.csv"), doc.String())
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	// CmdParamsConfigMapName is the ConfigMap holding the command line parameters of the Argo CD components.
+	CmdParamsConfigMapName = "argocd-cmd-params-cm"
+	DeploymentResource     = "deployment"
+
+	AnonymousEnabledKey = "users.anonymous.enabled"
+	AdminEnabledKey     = "admin.enabled"
+	SessionDurationKey  = "users.session.duration"
+	ExecEnabledKey      = "exec.enabled"
+	OIDCConfigKey       = "oidc.config"
+	DisableAuthKey      = "server.disable.auth"
+
+	defaultSessionDuration = "24h"
+	serverComponentLabel   = "app.kubernetes.io/component=server"
+)
+
+// Risk is an access-relevant setting of an Argo CD installation that weakens its security.
+type Risk struct {
+	ID          string
+	Description string
+}
+
+// SecurityPosture holds the access-relevant settings of an Argo CD installation, from argocd-cm,
+// argocd-rbac-cm and argocd-cmd-params-cm, with Argo CD's defaults for unset keys.
+type SecurityPosture struct {
+	Namespace string
+	// Version is the image tag of the API server, empty if it couldn't be read.
+	Version          string
+	AnonymousEnabled bool
+	AdminEnabled     bool
+	SessionDuration  string
+	ExecEnabled      bool
+	AuthDisabled     bool
+	DefaultPolicy    string
+	OIDCConfigured   bool
+	DexConfigured    bool
+	// APIKeyAccounts and LoginAccounts are the enabled local accounts with the apiKey and login capabilities.
+	APIKeyAccounts []string
+	LoginAccounts  []string
+}
+
+// GetSecurityPosture reads the security posture of the Argo CD installation. A missing argocd-cmd-params-cm
+// is read as empty, and failing to read the version is only logged.
+// Command: kubectl get cm argocd-cm -n argocd -o json.
+// Command: kubectl get cm argocd-cmd-params-cm -n argocd -o json.
+// Command: kubectl get deployment -n argocd -l app.kubernetes.io/component=server,app.kubernetes.io/part-of=argocd -o json.
+func (c *Client) GetSecurityPosture(ctx context.Context) (*SecurityPosture, error) {
+	cm, err := c.getConfigMap(ctx, ArgoCDConfigMapName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s configmap: %w", ArgoCDConfigMapName, err)
+	}
+	rbac, err := c.getRBACConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
+	}
+	params, err := c.getObject(ctx, ConfigMapResource, CmdParamsConfigMapName)
+	if err != nil {
+		if !strings.Contains(err.Error(), "NotFound") {
+			return nil, fmt.Errorf("failed to get %s configmap: %w", CmdParamsConfigMapName, err)
+		}
+		params = &ConfigMap{}
+	}
+
+	posture := parseSecurityPosture(cm.Data, rbac.Data, params.Data)
+	posture.Namespace = c.namespace
+
+	posture.Version, err = c.getServerVersion(ctx)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to read the Argo CD version", zap.Error(err))
+	}
+	return posture, nil
+}
+
+// parseSecurityPosture reads the security posture from the data of argocd-cm, argocd-rbac-cm and argocd-cmd-params-cm.
+func parseSecurityPosture(cm map[string]string, rbac map[string]string, params map[string]string) *SecurityPosture {
+	posture := &SecurityPosture{
+		AnonymousEnabled: cm[AnonymousEnabledKey] == "true",
+		AdminEnabled:     cm[AdminEnabledKey] != "false",
+		SessionDuration:  cm[SessionDurationKey],
+		ExecEnabled:      cm[ExecEnabledKey] == "true",
+		AuthDisabled:     params[DisableAuthKey] == "true",
+		DefaultPolicy:    rbac[PolicyDefaultKey],
+		OIDCConfigured:   strings.TrimSpace(cm[OIDCConfigKey]) != "",
+		DexConfigured:    strings.TrimSpace(cm[DexConfigKey]) != "",
+	}
+	if posture.SessionDuration == "" {
+		posture.SessionDuration = defaultSessionDuration
+	}
+
+	for _, account := range parseConfigMapAccounts(cm) {
+		if !account.Enabled {
+			continue
+		}
+		for _, capability := range account.Capabilities {
+			switch capability {
+			case "apiKey":
+				posture.APIKeyAccounts = append(posture.APIKeyAccounts, account.Name)
+			case "login":
+				posture.LoginAccounts = append(posture.LoginAccounts, account.Name)
+			}
+		}
+	}
+	sort.Strings(posture.APIKeyAccounts)
+	sort.Strings(posture.LoginAccounts)
+	return posture
+}
+
+// Risks returns the dangerous settings of the installation.
+func (p *SecurityPosture) Risks() []Risk {
+	var risks []Risk
+	if p.AuthDisabled {
+		risks = append(risks, Risk{"auth-disabled", "authentication is disabled on the API server, everyone has admin access"})
+	}
+	if p.AnonymousEnabled && p.DefaultPolicy != "" {
+		risks = append(risks, Risk{"anonymous-access", "anonymous users are enabled and get the default policy " + p.DefaultPolicy})
+	}
+	if strings.TrimPrefix(p.DefaultPolicy, RolePrefix) == "admin" {
+		risks = append(risks, Risk{"default-admin", "every authenticated user gets role:admin through policy.default"})
+	}
+	if p.AdminEnabled {
+		risks = append(risks, Risk{"admin-enabled", "the built-in admin account is enabled"})
+	}
+	if p.ExecEnabled {
+		risks = append(risks, Risk{"exec-enabled", "the web terminal is enabled, users with exec permission can open shells in application pods"})
+	}
+	return risks
+}
+
+// getServerVersion returns the image tag of the Argo CD API server's deployment.
+func (c *Client) getServerVersion(ctx context.Context) (string, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx,
+		GetCommand,
+		DeploymentResource,
+		NamespaceFlag,
+		c.namespace,
+		"-l",
+		serverComponentLabel+","+PartOfLabel+"="+partOfArgoCD,
+		OutputFlag,
+		JSONOutput,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to list Argo CD server deployments: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Image string `json:"image"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return "", fmt.Errorf("failed to unmarshal deployment list: %w", err)
+	}
+	for _, deployment := range list.Items {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if version := imageTag(container.Image); version != "" {
+				return version, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// imageTag returns the tag of a container image reference, without any digest, or "" if it has none.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, _ := strings.Cut(name, ":")
+	return tag
+}
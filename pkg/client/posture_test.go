@@ -0,0 +1,64 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseSecurityPosture tests reading the security posture and flagging its risks.
+func TestParseSecurityPosture(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		posture := parseSecurityPosture(map[string]string{}, map[string]string{}, nil)
+		assert.Equal(t, &SecurityPosture{
+			AdminEnabled:    true,
+			SessionDuration: "24h",
+			LoginAccounts:   []string{"admin"},
+		}, posture)
+		assert.Equal(t, []string{"admin-enabled"}, riskIDs(posture.Risks()))
+	})
+
+	t.Run("dangerous settings", func(t *testing.T) {
+		posture := parseSecurityPosture(map[string]string{
+			"users.anonymous.enabled": "true",
+			"admin.enabled":           "false",
+			"users.session.duration":  "720h",
+			"exec.enabled":            "true",
+			"oidc.config":             "name: Okta\nissuer: https://example.okta.com\n",
+			"accounts.ci":             "apiKey",
+			"accounts.alice":          "apiKey, login",
+		}, map[string]string{"policy.default": "role:admin"}, map[string]string{"server.disable.auth": "true"})
+
+		assert.Equal(t, &SecurityPosture{
+			AnonymousEnabled: true,
+			SessionDuration:  "720h",
+			ExecEnabled:      true,
+			AuthDisabled:     true,
+			DefaultPolicy:    "role:admin",
+			OIDCConfigured:   true,
+			APIKeyAccounts:   []string{"alice", "ci"},
+			LoginAccounts:    []string{"alice"},
+		}, posture)
+		assert.Equal(t, []string{"auth-disabled", "anonymous-access", "default-admin", "exec-enabled"}, riskIDs(posture.Risks()))
+	})
+
+	t.Run("anonymous access without a default policy", func(t *testing.T) {
+		posture := parseSecurityPosture(map[string]string{"users.anonymous.enabled": "true", "admin.enabled": "false"}, nil, nil)
+		assert.Empty(t, posture.Risks())
+	})
+}
+
+// TestImageTag tests reading the version from container image references.
+func TestImageTag(t *testing.T) {
+	assert.Equal(t, "v2.13.1", imageTag("quay.io/argoproj/argocd:v2.13.1"))
+	assert.Equal(t, "v2.13.1", imageTag("registry.local:5000/argoproj/argocd:v2.13.1@sha256:abc"))
+	assert.Equal(t, "", imageTag("registry.local:5000/argoproj/argocd"))
+}
+
+func riskIDs(risks []Risk) []string {
+	var ids []string
+	for _, risk := range risks {
+		ids = append(ids, risk.ID)
+	}
+	return ids
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// ArgoCD CLI project role command constants.
+const (
+	ProjectCommand     = "proj"
+	RoleCommand        = "role"
+	CreateTokenCommand = "create-token"
+	DeleteTokenCommand = "delete-token"
+	ExpiresInFlag      = "--expires-in"
+	TokenIDFlag        = "--id"
+	TokenOnlyFlag      = "--token-only"
+)
+
+// GetProjects returns the AppProjects in the Argo CD namespace, with their roles.
+// Command: kubectl get appprojects.argoproj.io -n argocd -o json.
+func (c *Client) GetProjects(ctx context.Context) ([]*Project, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx, GetCommand, AppProjectResource, NamespaceFlag, c.namespace, OutputFlag, JSONOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	return parseProjects(output)
+}
+
+// GetProject returns the named AppProject with its roles, or nil if there is no such project.
+// Command: kubectl get appprojects.argoproj.io <name> -n argocd -o json.
+func (c *Client) GetProject(ctx context.Context, name string) (*Project, error) {
+	output, err := c.runKubectlCommandWithOutput(ctx, GetCommand, AppProjectResource, name, NamespaceFlag, c.namespace, OutputFlag, JSONOutput)
+	if err != nil {
+		if strings.Contains(err.Error(), "NotFound") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get project %s: %w", name, err)
+	}
+
+	var item appProject
+	if err := json.Unmarshal(output, &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project %s: %w", name, err)
+	}
+	return item.project(), nil
+}
+
+// appProject is the part of an AppProject resource describing the project and its roles.
+type appProject struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Description string `json:"description"`
+		Roles       []struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			Policies    []string        `json:"policies"`
+			Groups      []string        `json:"groups"`
+			JWTTokens   []*ProjectToken `json:"jwtTokens"`
+		} `json:"roles"`
+	} `json:"spec"`
+	Status struct {
+		JWTTokensByRole map[string]struct {
+			Items []*ProjectToken `json:"items"`
+		} `json:"jwtTokensByRole"`
+	} `json:"status"`
+}
+
+// parseProjects unmarshals a list of AppProject resources with their roles.
+func parseProjects(output []byte) ([]*Project, error) {
+	var list struct {
+		Items []*appProject `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project list: %w", err)
+	}
+
+	projects := make([]*Project, 0, len(list.Items))
+	for _, item := range list.Items {
+		projects = append(projects, item.project())
+	}
+	return projects, nil
+}
+
+// project returns the project with its roles. Token metadata is read from both the role spec and
+// `status.jwtTokensByRole`, where newer Argo CD versions keep it.
+func (item *appProject) project() *Project {
+	project := &Project{Name: item.Metadata.Name, Description: item.Spec.Description}
+	for _, r := range item.Spec.Roles {
+		role := &ProjectRole{
+			Project:     project.Name,
+			Name:        r.Name,
+			Description: r.Description,
+			Policies:    r.Policies,
+			Groups:      r.Groups,
+		}
+		seen := map[int64]struct{}{}
+		for _, token := range append(r.JWTTokens, item.Status.JWTTokensByRole[r.Name].Items...) {
+			if _, ok := seen[token.IssuedAt]; ok {
+				continue
+			}
+			seen[token.IssuedAt] = struct{}{}
+			role.Tokens = append(role.Tokens, token)
+		}
+		project.Roles = append(project.Roles, role)
+	}
+	return project
+}
+
+// CreateProjectToken issues a JWT token for a project role and returns it. The token is only ever returned
+// here; Argo CD keeps its metadata alone. A zero expiresIn issues a token that doesn't expire.
+// In dry-run mode no token is issued, and an empty token is returned with a NotApplied annotation.
+// Command: argocd proj role create-token <project> <role> --expires-in <duration> --id <id> --token-only.
+func (c *Client) CreateProjectToken(ctx context.Context, project string, role string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+	args := []string{ProjectCommand, RoleCommand, CreateTokenCommand, project, role, TokenOnlyFlag}
+	if expiresIn > 0 {
+		args = append(args, ExpiresInFlag, expiresIn.String())
+	}
+	if id != "" {
+		args = append(args, TokenIDFlag, id)
+	}
+
+	if c.dryRun {
+		ctxzap.Extract(ctx).Info("dry run: skipped issuing project role token",
+			zap.String("project", project),
+			zap.String("role", role),
+			zap.Duration("expires_in", expiresIn),
+		)
+		return "", c.notApplied(nil), nil
+	}
+
+	output, err := c.runArgoCDCommandWithOutput(ctx, args...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create token for role %s of project %s: %w", role, project, err)
+	}
+	return strings.TrimSpace(string(output)), nil, nil
+}
+
+// DeleteProjectToken revokes the token of a project role issued at the given Unix time.
+// In dry-run mode nothing is revoked and a NotApplied annotation is returned.
+// Command: argocd proj role delete-token <project> <role> <issued-at>.
+func (c *Client) DeleteProjectToken(ctx context.Context, project string, role string, issuedAt int64) (annotations.Annotations, error) {
+	args := []string{ProjectCommand, RoleCommand, DeleteTokenCommand, project, role, strconv.FormatInt(issuedAt, 10)}
+
+	if c.dryRun {
+		ctxzap.Extract(ctx).Info("dry run: skipped revoking project role token",
+			zap.String("project", project),
+			zap.String("role", role),
+			zap.Int64("issued_at", issuedAt),
+		)
+		return c.notApplied(nil), nil
+	}
+
+	if _, err := c.runArgoCDCommandWithOutput(ctx, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete token of role %s of project %s: %w", role, project, err)
+	}
+	return nil, nil
+}
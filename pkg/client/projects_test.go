@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseProjects tests reading AppProjects with their roles and the metadata of role tokens.
+func TestParseProjects(t *testing.T) {
+	projects, err := parseProjects([]byte(`{"items": [
+		{"metadata": {"name": "prod"}, "spec": {
+			"description": "Production",
+			"roles": [
+				{"name": "ci", "description": "CI pipeline", "groups": ["my-org:ci"],
+					"policies": ["p, proj:prod:ci, applications, sync, prod/*, allow"],
+					"jwtTokens": [{"iat": 1700000000, "exp": 1710000000, "id": "deploy"}]},
+				{"name": "viewer"}
+			]},
+			"status": {"jwtTokensByRole": {"ci": {"items": [
+				{"iat": 1700000000, "exp": 1710000000, "id": "deploy"},
+				{"iat": 1720000000}
+			]}}}},
+		{"metadata": {"name": "default"}, "spec": {}}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+
+	prod := projects[0]
+	assert.Equal(t, "Production", prod.Description)
+	require.Len(t, prod.Roles, 2)
+	assert.Equal(t, &ProjectRole{
+		Project:     "prod",
+		Name:        "ci",
+		Description: "CI pipeline",
+		Policies:    []string{"p, proj:prod:ci, applications, sync, prod/*, allow"},
+		Groups:      []string{"my-org:ci"},
+		Tokens: []*ProjectToken{
+			{ID: "deploy", IssuedAt: 1700000000, ExpiresAt: 1710000000},
+			{IssuedAt: 1720000000},
+		},
+	}, prod.Roles[0])
+	assert.Empty(t, prod.Roles[1].Tokens)
+	assert.Empty(t, projects[1].Roles)
+}
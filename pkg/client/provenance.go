@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ProvenanceMarker starts the comment line written directly above every policy line the connector adds.
+	// Argo CD's policy loader does not support trailing comments on a record, so the marker
+	// can't share the line with the binding it describes.
+	ProvenanceMarker = "# managed-by: conductorone"
+
+	// LastChangeAnnotation is set on every ConfigMap the connector writes, describing the most recent change.
+	LastChangeAnnotation = "conductorone.io/last-change"
+)
+
+// ChangeRecord describes who or what caused a write, so it can be traced back from the cluster.
+type ChangeRecord struct {
+	GrantID   string    `json:"grantId,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// LastChange is the value stored in the LastChangeAnnotation.
+type LastChange struct {
+	Operation string `json:"operation"`
+	Subject   string `json:"subject,omitempty"`
+	Role      string `json:"role,omitempty"`
+	*ChangeRecord
+
+	// PendingBranch is set once the change is pushed to a review branch of the configuration repository
+	// instead of its configured branch. It is never stored.
+	PendingBranch string `json:"-"`
+}
+
+type changeRecordKey struct{}
+
+// WithChangeRecord returns a context carrying the change record for the writes made with it.
+func WithChangeRecord(ctx context.Context, record *ChangeRecord) context.Context {
+	return context.WithValue(ctx, changeRecordKey{}, record)
+}
+
+// changeRecordFromContext returns the change record of the context,
+// or a record holding only the current time if none was set.
+func changeRecordFromContext(ctx context.Context) *ChangeRecord {
+	if record, ok := ctx.Value(changeRecordKey{}).(*ChangeRecord); ok && record != nil {
+		if record.Time.IsZero() {
+			record.Time = time.Now().UTC()
+		}
+		return record
+	}
+	return &ChangeRecord{Time: time.Now().UTC()}
+}
+
+// marker renders the record as a provenance comment line.
+func (r *ChangeRecord) marker() string {
+	parts := []string{ProvenanceMarker}
+	if r.GrantID != "" {
+		parts = append(parts, "grant="+url.PathEscape(r.GrantID))
+	}
+	if r.RequestID != "" {
+		parts = append(parts, "request="+url.PathEscape(r.RequestID))
+	}
+	parts = append(parts, "at="+r.Time.UTC().Format(time.RFC3339))
+	return strings.Join(parts, " ")
+}
+
+// parseMarker parses a provenance comment line, reporting false for any other line.
+func parseMarker(line string) (*ChangeRecord, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), ProvenanceMarker)
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return nil, false
+	}
+
+	record := &ChangeRecord{}
+	for _, token := range strings.Fields(rest) {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		switch key {
+		case "grant":
+			record.GrantID = value
+		case "request":
+			record.RequestID = value
+		case "at":
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				record.Time = t
+			}
+		}
+	}
+	return record, true
+}
+
+// lastChangePatchOps returns the JSON patch operations setting the LastChangeAnnotation on a ConfigMap.
+func lastChangePatchOps(cm *ConfigMap, change *LastChange) ([]jsonPatchOp, error) {
+	value, err := json.Marshal(change)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal last change annotation: %w", err)
+	}
+
+	if cm.Metadata.Annotations == nil {
+		return []jsonPatchOp{{
+			Op:    "add",
+			Path:  "/metadata/annotations",
+			Value: map[string]string{LastChangeAnnotation: string(value)},
+		}}, nil
+	}
+
+	return []jsonPatchOp{{
+		Op:    "add",
+		Path:  "/metadata/annotations/" + escapeJSONPointer(LastChangeAnnotation),
+		Value: string(value),
+	}}, nil
+}
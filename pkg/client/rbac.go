@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PolicyMatchModeKey is the argocd-rbac-cm key selecting how policy resources, actions and objects are matched.
+const PolicyMatchModeKey = "policy.matchMode"
+
+// Policy effects and match modes.
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+
+	PolicyMatchModeGlob  = "glob"
+	PolicyMatchModeRegex = "regex"
+)
+
+// builtinPolicy is Argo CD's built-in policy, which defines role:readonly and role:admin
+// in addition to the policy configured in argocd-rbac-cm.
+const builtinPolicy = `p, role:readonly, applications, get, */*, allow
+p, role:readonly, certificates, get, *, allow
+p, role:readonly, clusters, get, *, allow
+p, role:readonly, repositories, get, *, allow
+p, role:readonly, write-repositories, get, *, allow
+p, role:readonly, projects, get, *, allow
+p, role:readonly, accounts, get, *, allow
+p, role:readonly, gpgkeys, get, *, allow
+p, role:readonly, logs, get, */*, allow
+
+p, role:admin, applications, create, */*, allow
+p, role:admin, applications, update, */*, allow
+p, role:admin, applications, update/*, */*, allow
+p, role:admin, applications, delete, */*, allow
+p, role:admin, applications, delete/*, */*, allow
+p, role:admin, applications, sync, */*, allow
+p, role:admin, applications, override, */*, allow
+p, role:admin, applications, action/*, */*, allow
+p, role:admin, applicationsets, get, */*, allow
+p, role:admin, applicationsets, create, */*, allow
+p, role:admin, applicationsets, update, */*, allow
+p, role:admin, applicationsets, delete, */*, allow
+p, role:admin, certificates, create, *, allow
+p, role:admin, certificates, update, *, allow
+p, role:admin, certificates, delete, *, allow
+p, role:admin, clusters, create, *, allow
+p, role:admin, clusters, update, *, allow
+p, role:admin, clusters, delete, *, allow
+p, role:admin, repositories, create, *, allow
+p, role:admin, repositories, update, *, allow
+p, role:admin, repositories, delete, *, allow
+p, role:admin, write-repositories, create, *, allow
+p, role:admin, write-repositories, update, *, allow
+p, role:admin, write-repositories, delete, *, allow
+p, role:admin, projects, create, *, allow
+p, role:admin, projects, update, *, allow
+p, role:admin, projects, delete, *, allow
+p, role:admin, accounts, update, *, allow
+p, role:admin, gpgkeys, create, *, allow
+p, role:admin, gpgkeys, delete, *, allow
+p, role:admin, exec, create, */*, allow
+
+g, role:admin, role:readonly
+`
+
+// PolicyEvaluator decides whether a role may perform an action on an object the way Argo CD does:
+// a role has the permissions of its `p` lines and of the roles it is bound to with `g` lines,
+// resources, actions and objects are matched as globs (or regular expressions in regex match mode),
+// and a matching deny overrides any allow. Roles are named without the `role:` prefix.
+type PolicyEvaluator struct {
+	regex       bool
+	definitions map[string][]*PolicyDefinition
+	inherits    map[string][]string
+	patterns    map[string]*regexp.Regexp
+}
+
+// NewPolicyEvaluator creates a PolicyEvaluator for a `policy.csv`, combined with the built-in policy.
+func NewPolicyEvaluator(policyCSV string, matchMode string) (*PolicyEvaluator, error) {
+	bindings, definitions, err := ParseArgoCDPolicyCSV(builtinPolicy + "\n" + policyCSV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy csv: %w", err)
+	}
+
+	e := &PolicyEvaluator{
+		regex:       matchMode == PolicyMatchModeRegex,
+		definitions: make(map[string][]*PolicyDefinition),
+		inherits:    make(map[string][]string),
+		patterns:    make(map[string]*regexp.Regexp),
+	}
+	for _, definition := range definitions {
+		e.definitions[definition.Role] = append(e.definitions[definition.Role], definition)
+	}
+	for _, binding := range bindings {
+		subject := strings.TrimPrefix(binding.Subject, RolePrefix)
+		e.inherits[subject] = append(e.inherits[subject], binding.Role)
+	}
+	return e, nil
+}
+
+// GetPolicyEvaluator returns a PolicyEvaluator for the RBAC policy in argocd-rbac-cm.
+// Command: kubectl get cm argocd-rbac-cm -n argocd -o json.
+func (c *Client) GetPolicyEvaluator(ctx context.Context) (*PolicyEvaluator, error) {
+	cm, err := c.getRBACConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rbac configmap: %w", err)
+	}
+	return NewPolicyEvaluator(cm.Data[PolicyCSVKey], cm.Data[PolicyMatchModeKey])
+}
+
+// RoleAllowed reports whether the role may perform the action on the object of the given resource type.
+func (e *PolicyEvaluator) RoleAllowed(role string, resource string, action string, object string) bool {
+	allowed := false
+	seen := map[string]bool{}
+	queue := []string{strings.TrimPrefix(role, RolePrefix)}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		queue = append(queue, e.inherits[current]...)
+
+		for _, definition := range e.definitions[current] {
+			if !e.match(definition.Resource, resource) || !e.match(definition.Action, action) || !e.match(definition.Object, object) {
+				continue
+			}
+			if strings.EqualFold(definition.Effect, PolicyEffectDeny) {
+				return false
+			}
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// match reports whether value matches a policy pattern. Regex patterns are unanchored, as in Argo CD.
+func (e *PolicyEvaluator) match(pattern string, value string) bool {
+	re, ok := e.patterns[pattern]
+	if !ok {
+		expr := pattern
+		if !e.regex {
+			expr = globToRegexp(pattern)
+		}
+		var err error
+		if re, err = regexp.Compile(expr); err != nil {
+			re = nil
+		}
+		e.patterns[pattern] = re
+	}
+	if re == nil {
+		return pattern == value
+	}
+	return re.MatchString(value)
+}
+
+// globToRegexp translates a glob as matched by Argo CD, where `*` also matches `/`, into an anchored
+// regular expression. It supports `*`, `?`, character classes and `{a,b}` alternatives.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case ch == '*':
+			b.WriteString(".*")
+		case ch == '?':
+			b.WriteString(".")
+		case ch == '{':
+			braces++
+			b.WriteString("(?:")
+		case ch == '}' && braces > 0:
+			braces--
+			b.WriteString(")")
+		case ch == ',' && braces > 0:
+			b.WriteString("|")
+		case ch == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case ch == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const evaluatedPolicy = `p, role:deployer, applications, get, prod/*, allow
+p, role:deployer, applications, sync, prod/*, allow
+p, role:deployer, applications, sync, prod/payments, deny
+p, role:lead, applications, delete, "{prod,staging}/*", allow
+p, role:lead, applications, action/*, prod/*, allow
+g, role:lead, role:deployer
+g, alice, role:lead
+p, role:operators, exec, create, */*, allow
+`
+
+// TestPolicyEvaluator tests RBAC evaluation against application objects.
+func TestPolicyEvaluator(t *testing.T) {
+	e, err := NewPolicyEvaluator(evaluatedPolicy, "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		role     string
+		resource string
+		action   string
+		object   string
+		want     bool
+	}{
+		{"glob object", "deployer", "applications", "sync", "prod/checkout", true},
+		{"role prefix is optional", "role:deployer", "applications", "sync", "prod/checkout", true},
+		{"deny overrides allow", "deployer", "applications", "sync", "prod/payments", false},
+		{"other project", "deployer", "applications", "sync", "staging/checkout", false},
+		{"other action", "deployer", "applications", "delete", "prod/checkout", false},
+		{"inherited permission", "lead", "applications", "get", "prod/checkout", true},
+		{"inherited deny", "lead", "applications", "sync", "prod/payments", false},
+		{"brace alternatives", "lead", "applications", "delete", "staging/checkout", true},
+		{"wildcard matches slashes", "operators", "exec", "create", "prod/team-ns/checkout", true},
+		{"action glob", "lead", "applications", "action/*", "prod/checkout", true},
+		{"built-in admin", "admin", "applications", "override", "prod/payments", true},
+		{"built-in readonly via admin", "admin", "logs", "get", "prod/payments", true},
+		{"built-in readonly", "readonly", "applications", "sync", "prod/payments", false},
+		{"unknown role", "nobody", "applications", "get", "prod/checkout", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, e.RoleAllowed(tt.role, tt.resource, tt.action, tt.object))
+		})
+	}
+
+	t.Run("regex match mode", func(t *testing.T) {
+		e, err := NewPolicyEvaluator("p, role:ci, applications, sync, ^prod/(api|web)$, allow\n", PolicyMatchModeRegex)
+		require.NoError(t, err)
+		assert.True(t, e.RoleAllowed("ci", "applications", "sync", "prod/api"))
+		assert.False(t, e.RoleAllowed("ci", "applications", "sync", "prod/worker"))
+	})
+}
+
+// TestParseApplications tests reading Applications and their RBAC objects.
+func TestParseApplications(t *testing.T) {
+	c := NewClient(context.Background(), "https://test.com", "admin", "password")
+	apps, err := c.parseApplications([]byte(`{"items": [
+		{"metadata": {"name": "payments", "namespace": "argocd",
+			"ownerReferences": [{"apiVersion": "argoproj.io/v1alpha1", "kind": "ApplicationSet", "name": "services"}]},
+			"spec": {"project": "prod"}},
+		{"metadata": {"name": "checkout", "namespace": "team-a"}, "spec": {"project": "prod"}},
+		{"metadata": {"name": "guestbook", "namespace": "argocd"}, "spec": {}}
+	]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []*Application{
+		{Name: "payments", Namespace: "argocd", Project: "prod", RBACObject: "prod/payments", ApplicationSet: "services"},
+		{Name: "checkout", Namespace: "team-a", Project: "prod", RBACObject: "prod/team-a/checkout"},
+		{Name: "guestbook", Namespace: "argocd", Project: "default", RBACObject: "default/guestbook"},
+	}, apps)
+}
+
+// TestParseApplicationSets tests reading ApplicationSets and their RBAC objects.
+func TestParseApplicationSets(t *testing.T) {
+	c := NewClient(context.Background(), "https://test.com", "admin", "password")
+	appSets, err := c.parseApplicationSets([]byte(`{"items": [
+		{"metadata": {"name": "services", "namespace": "argocd"}, "spec": {"template": {"spec": {"project": "prod"}}}},
+		{"metadata": {"name": "clusters", "namespace": "team-a"}, "spec": {"template": {"spec": {}}}}
+	]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []*ApplicationSet{
+		{Name: "services", Namespace: "argocd", Project: "prod", RBACObject: "prod/services"},
+		{Name: "clusters", Namespace: "team-a", Project: "default", RBACObject: "default/team-a/clusters"},
+	}, appSets)
+}
+
+// TestManagedNamespace tests matching namespaces against the source namespaces of an instance.
+func TestManagedNamespace(t *testing.T) {
+	patterns := []string{"team-a", "apps-*", "/^ci-[0-9]+$/"}
+	assert.True(t, managedNamespace("argocd", "argocd", nil))
+	assert.True(t, managedNamespace("team-a", "argocd", patterns))
+	assert.True(t, managedNamespace("apps-payments", "argocd", patterns))
+	assert.True(t, managedNamespace("ci-42", "argocd", patterns))
+	assert.False(t, managedNamespace("ci-main", "argocd", patterns))
+	assert.False(t, managedNamespace("team-b", "argocd", patterns))
+	assert.False(t, managedNamespace("team-a", "argocd", nil))
+}
+
+// TestManagedApplications tests that instances sharing a cluster each keep only the Applications they manage.
+func TestManagedApplications(t *testing.T) {
+	output := []byte(`{"items": [
+		{"metadata": {"name": "payments", "namespace": "argocd"}, "spec": {"project": "default"}},
+		{"metadata": {"name": "checkout", "namespace": "team-a"}, "spec": {"project": "default"}},
+		{"metadata": {"name": "billing", "namespace": "argocd-platform"}, "spec": {"project": "default"}},
+		{"metadata": {"name": "ingress", "namespace": "platform-infra"}, "spec": {"project": "default"}}
+	]}`)
+	instances := []struct {
+		namespace string
+		sources   []string
+		want      []string
+	}{
+		{"argocd", []string{"team-*"}, []string{"payments", "checkout"}},
+		{"argocd-platform", []string{"platform-infra"}, []string{"billing", "ingress"}},
+	}
+
+	for _, inst := range instances {
+		c := NewClient(context.Background(), "https://test.com", "admin", "password", WithNamespace(inst.namespace))
+		apps, err := c.parseApplications(output)
+		require.NoError(t, err)
+
+		var names []string
+		for _, app := range c.managedApplications(apps, inst.sources) {
+			names = append(names, app.Name)
+		}
+		assert.Equal(t, inst.want, names, inst.namespace)
+	}
+}
@@ -0,0 +1,246 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Argo CD secret types of repositories and repository credential templates.
+const (
+	SecretTypeRepository      = "repository"
+	SecretTypeRepoCredentials = "repo-creds"
+
+	defaultRepositoryType = "git"
+)
+
+// repositoryCredentialKeys are the secret keys holding repository credentials. Their presence is checked,
+// their values are never decoded.
+var repositoryCredentialKeys = []string{
+	"username",
+	"password",
+	"bearerToken",
+	"sshPrivateKey",
+	"tlsClientCertData",
+	"tlsClientCertKey",
+	"githubAppPrivateKey",
+	"gcpServiceAccountKey",
+}
+
+// Repository is a Git, Helm or OCI repository Argo CD pulls from, as defined by a repository secret.
+// Secret material is never read.
+type Repository struct {
+	URL     string
+	Name    string
+	Type    string
+	Project string
+	// SecretName is the secret defining the repository.
+	SecretName string
+	// HasCredentials reports whether the repository secret, or a credential template, holds credentials.
+	HasCredentials bool
+	// CredentialTemplate is the URL prefix of the credential template the credentials come from, if any.
+	CredentialTemplate string
+	// SharedWithProjects lists the project scopes, "" for global, of all repositories using the same credentials
+	// when there is more than one.
+	SharedWithProjects []string
+}
+
+// RepositoryCredential is a repository credential template, applied to repositories whose URL starts with URL
+// and that don't hold credentials themselves. Secret material is never read.
+type RepositoryCredential struct {
+	// URL is the prefix of the repository URLs the template applies to.
+	URL     string
+	Type    string
+	Project string
+	// SecretName is the secret defining the template.
+	SecretName     string
+	HasCredentials bool
+	// Repositories lists the URLs of the repositories whose credentials come from the template.
+	Repositories []string
+	// Projects lists the project scopes, "" for global, of those repositories.
+	Projects []string
+}
+
+// GetRepositories returns the repositories defined by repository secrets, noting where their credentials
+// come from and whether the same credentials serve repositories of several projects.
+// Command: kubectl get secret -n argocd -l argocd.argoproj.io/secret-type=repository -o json.
+// Command: kubectl get secret -n argocd -l argocd.argoproj.io/secret-type=repo-creds -o json.
+func (c *Client) GetRepositories(ctx context.Context) ([]*Repository, error) {
+	repoSecrets, err := c.listSecrets(ctx, SecretTypeRepository)
+	if err != nil {
+		return nil, err
+	}
+	credSecrets, err := c.listSecrets(ctx, SecretTypeRepoCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRepositories(repoSecrets, credSecrets)
+}
+
+// GetRepositoryCredentials returns the repository credential templates defined by repo-creds secrets, with
+// the repositories and project scopes they serve.
+// Command: kubectl get secret -n argocd -l argocd.argoproj.io/secret-type=repo-creds -o json.
+// Command: kubectl get secret -n argocd -l argocd.argoproj.io/secret-type=repository -o json.
+func (c *Client) GetRepositoryCredentials(ctx context.Context) ([]*RepositoryCredential, error) {
+	credSecrets, err := c.listSecrets(ctx, SecretTypeRepoCredentials)
+	if err != nil {
+		return nil, err
+	}
+	repoSecrets, err := c.listSecrets(ctx, SecretTypeRepository)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRepositoryCredentials(credSecrets, repoSecrets)
+}
+
+// RepositoryRBACObject returns the object RBAC policies for a repository are matched against: its URL,
+// prefixed with `<project>/` for project-scoped repositories.
+func RepositoryRBACObject(project string, url string) string {
+	if project == "" {
+		return url
+	}
+	return project + "/" + url
+}
+
+// parseRepositories builds the repositories from repository secrets, applying credential templates
+// to those without credentials of their own.
+func parseRepositories(repoSecrets []*ConfigMap, credSecrets []*ConfigMap) ([]*Repository, error) {
+	templates, err := parseCredentialTemplates(credSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]*Repository, 0, len(repoSecrets))
+	for _, secret := range repoSecrets {
+		data, err := decodeSecretData(secret, "url", "name", "type", "project")
+		if err != nil {
+			return nil, err
+		}
+		repo := &Repository{
+			URL:            data["url"],
+			Name:           data["name"],
+			Type:           data["type"],
+			Project:        data["project"],
+			SecretName:     secret.Metadata.Name,
+			HasCredentials: hasRepositoryCredentials(secret),
+		}
+		if repo.Type == "" {
+			repo.Type = defaultRepositoryType
+		}
+		if !repo.HasCredentials {
+			if template := matchRepoCredential(templates, repo.URL); template != nil {
+				repo.HasCredentials = template.HasCredentials
+				repo.CredentialTemplate = template.URL
+			}
+		}
+		repositories = append(repositories, repo)
+	}
+
+	markSharedCredentials(repositories)
+	return repositories, nil
+}
+
+// parseCredentialTemplates builds the credential templates from repo-creds secrets.
+func parseCredentialTemplates(credSecrets []*ConfigMap) ([]*RepositoryCredential, error) {
+	templates := make([]*RepositoryCredential, 0, len(credSecrets))
+	for _, secret := range credSecrets {
+		data, err := decodeSecretData(secret, "url", "type", "project")
+		if err != nil {
+			return nil, err
+		}
+		template := &RepositoryCredential{
+			URL:            data["url"],
+			Type:           data["type"],
+			Project:        data["project"],
+			SecretName:     secret.Metadata.Name,
+			HasCredentials: hasRepositoryCredentials(secret),
+		}
+		if template.Type == "" {
+			template.Type = defaultRepositoryType
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// parseRepositoryCredentials builds the credential templates from repo-creds secrets, listing the repositories
+// each one serves.
+func parseRepositoryCredentials(credSecrets []*ConfigMap, repoSecrets []*ConfigMap) ([]*RepositoryCredential, error) {
+	templates, err := parseCredentialTemplates(credSecrets)
+	if err != nil {
+		return nil, err
+	}
+	repositories, err := parseRepositories(repoSecrets, credSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, template := range templates {
+		projects := map[string]struct{}{}
+		for _, repo := range repositories {
+			if repo.CredentialTemplate != template.URL {
+				continue
+			}
+			template.Repositories = append(template.Repositories, repo.URL)
+			projects[repo.Project] = struct{}{}
+		}
+		for project := range projects {
+			template.Projects = append(template.Projects, project)
+		}
+		sort.Strings(template.Repositories)
+		sort.Strings(template.Projects)
+	}
+	return templates, nil
+}
+
+// hasRepositoryCredentials reports whether a repository or credential template secret holds credentials.
+func hasRepositoryCredentials(secret *ConfigMap) bool {
+	for _, key := range repositoryCredentialKeys {
+		if secret.Data[key] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRepoCredential returns the credential template with the longest URL prefix of url, as Argo CD does.
+func matchRepoCredential(templates []*RepositoryCredential, url string) *RepositoryCredential {
+	var match *RepositoryCredential
+	for _, template := range templates {
+		if template.URL == "" || !strings.HasPrefix(url, template.URL) {
+			continue
+		}
+		if match == nil || len(template.URL) > len(match.URL) {
+			match = template
+		}
+	}
+	return match
+}
+
+// markSharedCredentials sets SharedWithProjects on repositories whose credentials, from a credential
+// template, serve repositories with different project scopes.
+func markSharedCredentials(repositories []*Repository) {
+	projects := map[string]map[string]struct{}{}
+	for _, repo := range repositories {
+		if repo.CredentialTemplate == "" || !repo.HasCredentials {
+			continue
+		}
+		if projects[repo.CredentialTemplate] == nil {
+			projects[repo.CredentialTemplate] = map[string]struct{}{}
+		}
+		projects[repo.CredentialTemplate][repo.Project] = struct{}{}
+	}
+
+	for _, repo := range repositories {
+		scopes := projects[repo.CredentialTemplate]
+		if repo.CredentialTemplate == "" || len(scopes) < 2 {
+			continue
+		}
+		for project := range scopes {
+			repo.SharedWithProjects = append(repo.SharedWithProjects, project)
+		}
+		sort.Strings(repo.SharedWithProjects)
+	}
+}
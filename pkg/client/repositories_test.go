@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRepositories tests resolving repository credentials and flagging credentials shared across projects.
+func TestParseRepositories(t *testing.T) {
+	secret := func(name string, data map[string]string) *ConfigMap {
+		encoded := map[string]string{}
+		for key, value := range data {
+			encoded[key] = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+		return &ConfigMap{Metadata: ObjectMeta{Name: name}, Data: encoded}
+	}
+	repoSecrets := []*ConfigMap{
+		secret("repo-infra", map[string]string{"url": "https://github.com/org/infra", "project": "platform"}),
+		secret("repo-team-a", map[string]string{"url": "https://github.com/org/team-a", "project": "team-a"}),
+		secret("repo-own", map[string]string{"url": "https://github.com/org/own", "project": "team-a", "sshPrivateKey": "key"}),
+		secret("repo-charts", map[string]string{"url": "https://charts.example.com", "type": "helm", "name": "charts"}),
+		secret("repo-public", map[string]string{"url": "https://gitlab.com/public/app"}),
+	}
+	credSecrets := []*ConfigMap{
+		secret("creds-github", map[string]string{"url": "https://github.com", "password": "token"}),
+		secret("creds-org", map[string]string{"url": "https://github.com/org", "githubAppPrivateKey": "key"}),
+		secret("creds-charts", map[string]string{"url": "https://charts.example.com", "username": "u", "password": "p"}),
+	}
+
+	repositories, err := parseRepositories(repoSecrets, credSecrets)
+	require.NoError(t, err)
+	require.Len(t, repositories, 5)
+
+	infra := repositories[0]
+	assert.Equal(t, "git", infra.Type)
+	assert.True(t, infra.HasCredentials)
+	assert.Equal(t, "https://github.com/org", infra.CredentialTemplate)
+	assert.Equal(t, []string{"platform", "team-a"}, infra.SharedWithProjects)
+	assert.Equal(t, infra.SharedWithProjects, repositories[1].SharedWithProjects)
+
+	own := repositories[2]
+	assert.True(t, own.HasCredentials)
+	assert.Empty(t, own.CredentialTemplate)
+	assert.Empty(t, own.SharedWithProjects)
+
+	charts := repositories[3]
+	assert.Equal(t, "helm", charts.Type)
+	assert.Equal(t, "charts", charts.Name)
+	assert.Equal(t, "https://charts.example.com", charts.CredentialTemplate)
+	assert.Empty(t, charts.SharedWithProjects)
+
+	public := repositories[4]
+	assert.False(t, public.HasCredentials)
+	assert.Empty(t, public.CredentialTemplate)
+}
+
+// TestParseRepositoryCredentials tests building credential templates with the repositories and projects they serve.
+func TestParseRepositoryCredentials(t *testing.T) {
+	secret := func(name string, data map[string]string) *ConfigMap {
+		encoded := map[string]string{}
+		for key, value := range data {
+			encoded[key] = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+		return &ConfigMap{Metadata: ObjectMeta{Name: name}, Data: encoded}
+	}
+	credSecrets := []*ConfigMap{
+		secret("creds-org", map[string]string{"url": "https://github.com/org", "githubAppPrivateKey": "key"}),
+		secret("creds-charts", map[string]string{"url": "https://charts.example.com", "type": "helm", "project": "team-a"}),
+	}
+	repoSecrets := []*ConfigMap{
+		secret("repo-infra", map[string]string{"url": "https://github.com/org/infra", "project": "platform"}),
+		secret("repo-team-a", map[string]string{"url": "https://github.com/org/team-a", "project": "team-a"}),
+		secret("repo-own", map[string]string{"url": "https://github.com/org/own", "sshPrivateKey": "key"}),
+	}
+
+	templates, err := parseRepositoryCredentials(credSecrets, repoSecrets)
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+
+	assert.Equal(t, &RepositoryCredential{
+		URL:            "https://github.com/org",
+		Type:           "git",
+		SecretName:     "creds-org",
+		HasCredentials: true,
+		Repositories:   []string{"https://github.com/org/infra", "https://github.com/org/team-a"},
+		Projects:       []string{"platform", "team-a"},
+	}, templates[0])
+	assert.Equal(t, &RepositoryCredential{
+		URL:        "https://charts.example.com",
+		Type:       "helm",
+		Project:    "team-a",
+		SecretName: "creds-charts",
+	}, templates[1])
+}
+
+// TestRepositoryRBACObject tests the RBAC object of global and project-scoped repositories.
+func TestRepositoryRBACObject(t *testing.T) {
+	assert.Equal(t, "https://github.com/org/infra", RepositoryRBACObject("", "https://github.com/org/infra"))
+	assert.Equal(t, "team-a/https://github.com/org/infra", RepositoryRBACObject("team-a", "https://github.com/org/infra"))
+}
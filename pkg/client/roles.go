@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// builtinRoles are the roles Argo CD defines in its built-in policy, which can't be changed through policy.csv.
+var builtinRoles = []string{"admin", "readonly"}
+
+// IsBuiltinRole reports whether the role is one of Argo CD's built-in roles.
+func IsBuiltinRole(role string) bool {
+	return slices.Contains(builtinRoles, strings.TrimPrefix(role, RolePrefix))
+}
+
+// ValidateRoleName returns an error for role names that can't be written as a policy.csv field
+// without quoting, or that are taken by a built-in role.
+func ValidateRoleName(role string) error {
+	name := strings.TrimPrefix(role, RolePrefix)
+	switch {
+	case name == "":
+		return fmt.Errorf("role name is required")
+	case strings.ContainsAny(name, ",\"\r\n\t "):
+		return fmt.Errorf("invalid role name %q: it can't contain commas, quotes or whitespace", name)
+	case IsBuiltinRole(name):
+		return fmt.Errorf("role %s is a built-in Argo CD role", name)
+	}
+	return nil
+}
+
+// CreateRole adds a role to the `policy.csv` of argocd-rbac-cm with the given permissions, each written as a
+// `p, role:<role>, resource, action, object, effect` line preceded by a provenance marker. A role exists in
+// policy.csv only through its lines, so at least one permission is required, and a role that already has
+// lines is refused.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "replace", "path": "/data/policy.csv", "value": "..."}]'.
+func (c *Client) CreateRole(ctx context.Context, role string, policies []*PolicyDefinition) (annotations.Annotations, error) {
+	if err := ValidateRoleName(role); err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("role %s needs at least one permission", role)
+	}
+
+	change := changeRecordFromContext(ctx)
+	lastChange := &LastChange{Operation: "create-role", Role: role, ChangeRecord: change}
+
+	_, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		if doc.HasRole(role) {
+			return false, fmt.Errorf("role %s already exists", role)
+		}
+		for _, policy := range policies {
+			doc.AddPolicy(&PolicyDefinition{
+				Role:     role,
+				Resource: policy.Resource,
+				Action:   policy.Action,
+				Object:   policy.Object,
+				Effect:   policy.Effect,
+			}, change)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.notApplied(lastChange), nil
+}
+
+// DeleteRole removes a role from the `policy.csv` of argocd-rbac-cm: its `p` lines and every `g` line
+// referencing it, so no subject keeps a binding to a role that no longer grants anything. Built-in roles
+// and the role of policy.default are refused. Deleting a role without lines changes nothing.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "replace", "path": "/data/policy.csv", "value": "..."}]'.
+func (c *Client) DeleteRole(ctx context.Context, role string) (annotations.Annotations, error) {
+	if IsBuiltinRole(role) {
+		return nil, fmt.Errorf("role %s is a built-in Argo CD role and can't be deleted", role)
+	}
+
+	lastChange := &LastChange{Operation: "delete-role", Role: role, ChangeRecord: changeRecordFromContext(ctx)}
+
+	var policies, bindings int
+	_, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, data map[string]string) (bool, error) {
+		if defaultRole := data[PolicyDefaultKey]; defaultRole != "" && sameRole(defaultRole, role) {
+			return false, fmt.Errorf("role %s is the default role set by %s and can't be deleted", role, PolicyDefaultKey)
+		}
+		policies, bindings = doc.RemoveRole(role)
+		return policies+bindings > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctxzap.Extract(ctx).Info("deleted role",
+		zap.String("role", role),
+		zap.Int("policies", policies),
+		zap.Int("bindings", bindings),
+	)
+	return c.notApplied(lastChange), nil
+}
+
+// AddRolePolicy adds a permission to an existing role as a `p, role:<role>, resource, action, object, effect` line
+// preceded by a provenance marker. Built-in roles and roles without lines in policy.csv are refused.
+// It reports whether the line was added, false if the role already had the permission.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "replace", "path": "/data/policy.csv", "value": "..."}]'.
+func (c *Client) AddRolePolicy(ctx context.Context, policy *PolicyDefinition) (bool, error) {
+	if IsBuiltinRole(policy.Role) {
+		return false, fmt.Errorf("role %s is a built-in Argo CD role and can't be changed", policy.Role)
+	}
+
+	change := changeRecordFromContext(ctx)
+	lastChange := &LastChange{Operation: "add-permission", Subject: policy.String(), ChangeRecord: change}
+
+	return c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		if !doc.HasRole(policy.Role) {
+			return false, fmt.Errorf("role %s not found", policy.Role)
+		}
+		return doc.AddPolicy(policy, change), nil
+	})
+}
+
+// RemoveRolePolicy removes every `p` line granting a permission to a role, with their provenance markers.
+// Built-in roles are refused. It reports whether anything was removed.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "replace", "path": "/data/policy.csv", "value": "..."}]'.
+func (c *Client) RemoveRolePolicy(ctx context.Context, policy *PolicyDefinition) (bool, error) {
+	if IsBuiltinRole(policy.Role) {
+		return false, fmt.Errorf("role %s is a built-in Argo CD role and can't be changed", policy.Role)
+	}
+
+	lastChange := &LastChange{Operation: "remove-permission", Subject: policy.String(), ChangeRecord: changeRecordFromContext(ctx)}
+
+	return c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		return doc.RemovePolicy(policy) > 0, nil
+	})
+}
+
+// RoleChange summarizes an edit of roles: the policy.csv records of the roles it touched, before and after it.
+type RoleChange struct {
+	Before  []string
+	After   []string
+	Changed bool
+}
+
+// CloneRole creates a role with the permissions of another: a copy of each of its `p` lines, or of the built-in
+// policy for built-in roles, and of each `g` line binding it to a role it inherits, preceded by a provenance
+// marker. Bindings of subjects to the role aren't copied.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "test", "path": "/metadata/resourceVersion", ...}, ...]'.
+func (c *Client) CloneRole(ctx context.Context, role string, newRole string) (*RoleChange, error) {
+	if err := ValidateRoleName(newRole); err != nil {
+		return nil, err
+	}
+
+	change := changeRecordFromContext(ctx)
+	lastChange := &LastChange{Operation: "clone-role", Subject: RolePrefix + strings.TrimPrefix(role, RolePrefix), Role: newRole, ChangeRecord: change}
+
+	summary := &RoleChange{}
+	_, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		if doc.HasRole(newRole) {
+			return false, fmt.Errorf("role %s already exists", newRole)
+		}
+		source := doc
+		if IsBuiltinRole(role) {
+			source = ParsePolicyDocument(builtinPolicy)
+		}
+		var policies []*PolicyDefinition
+		for _, policy := range source.Policies() {
+			if sameRole(policy.Role, role) {
+				policies = append(policies, policy)
+			}
+		}
+		inherited := source.InheritedRoles(role)
+		if len(policies) == 0 && len(inherited) == 0 {
+			return false, fmt.Errorf("role %s has no permissions to clone", role)
+		}
+
+		summary.Before = doc.RoleLines(newRole)
+		for _, policy := range policies {
+			policy.Role = newRole
+			doc.AddPolicy(policy, change)
+		}
+		for _, inheritedRole := range inherited {
+			doc.AddBinding(RolePrefix+newRole, inheritedRole, change)
+		}
+		summary.After = doc.RoleLines(newRole)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Changed = true
+	return summary, nil
+}
+
+// RenameRole renames a role, rewriting every `p` and `g` line referencing it in a single write. Built-in roles,
+// the role of policy.default and names already in use are refused.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "test", "path": "/metadata/resourceVersion", ...}, ...]'.
+func (c *Client) RenameRole(ctx context.Context, role string, newRole string) (*RoleChange, error) {
+	if IsBuiltinRole(role) {
+		return nil, fmt.Errorf("role %s is a built-in Argo CD role and can't be renamed", role)
+	}
+	if err := ValidateRoleName(newRole); err != nil {
+		return nil, err
+	}
+
+	lastChange := &LastChange{Operation: "rename-role", Subject: RolePrefix + strings.TrimPrefix(role, RolePrefix), Role: newRole, ChangeRecord: changeRecordFromContext(ctx)}
+
+	summary := &RoleChange{}
+	_, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, data map[string]string) (bool, error) {
+		if defaultRole := data[PolicyDefaultKey]; defaultRole != "" && sameRole(defaultRole, role) {
+			return false, fmt.Errorf("role %s is the default role set by %s and can't be renamed", role, PolicyDefaultKey)
+		}
+		if !doc.HasRole(role) {
+			return false, fmt.Errorf("role %s not found", role)
+		}
+		if doc.HasRole(newRole) {
+			return false, fmt.Errorf("role %s already exists", newRole)
+		}
+
+		summary.Before = doc.RoleLines(role, newRole)
+		doc.RenameRole(role, newRole)
+		summary.After = doc.RoleLines(role, newRole)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Changed = true
+	return summary, nil
+}
+
+// MigrateRoleMembers moves every subject bound to a role to another existing or built-in role, in a single
+// write. The role keeps its permissions. Migrating a role without members changes nothing.
+// Command: kubectl patch configmap argocd-rbac-cm -n argocd --type=json -p '[{"op": "test", "path": "/metadata/resourceVersion", ...}, ...]'.
+func (c *Client) MigrateRoleMembers(ctx context.Context, role string, newRole string) (*RoleChange, error) {
+	if sameRole(role, newRole) {
+		return nil, fmt.Errorf("can't migrate the members of role %s to itself", role)
+	}
+
+	change := changeRecordFromContext(ctx)
+	lastChange := &LastChange{Operation: "migrate-members", Subject: RolePrefix + strings.TrimPrefix(role, RolePrefix), Role: newRole, ChangeRecord: change}
+
+	summary := &RoleChange{}
+	changed, err := c.editRBACPolicy(ctx, lastChange, func(doc *PolicyDocument, _ map[string]string) (bool, error) {
+		if !doc.HasRole(role) {
+			return false, fmt.Errorf("role %s not found", role)
+		}
+		if !doc.HasRole(newRole) && !IsBuiltinRole(newRole) {
+			return false, fmt.Errorf("role %s not found", newRole)
+		}
+
+		summary.Before = doc.RoleLines(role, newRole)
+		moved := doc.MoveBindings(role, newRole, change)
+		summary.After = doc.RoleLines(role, newRole)
+		return len(moved) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Changed = changed
+	return summary, nil
+}
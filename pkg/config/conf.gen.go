@@ -7,6 +7,21 @@ type ArgoCd struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	ApiUrl string `mapstructure:"api-url"`
+	InstancesFile string `mapstructure:"instances-file"`
+	DiscoverInstances bool `mapstructure:"discover-instances"`
+	ArgocdNamespace string `mapstructure:"argocd-namespace"`
+	InstallType string `mapstructure:"install-type"`
+	ArgocdName string `mapstructure:"argocd-name"`
+	AccountEmailsFile string `mapstructure:"account-emails-file"`
+	AccountTokenLifetime string `mapstructure:"account-token-lifetime"`
+	GroupMappingFile string `mapstructure:"group-mapping-file"`
+	DryRun bool `mapstructure:"dry-run"`
+	GitopsRepoUrl string `mapstructure:"gitops-repo-url"`
+	GitopsBranch string `mapstructure:"gitops-branch"`
+	GitopsRbacCmPath string `mapstructure:"gitops-rbac-cm-path"`
+	GitopsCmPath string `mapstructure:"gitops-cm-path"`
+	GitopsReviewBranchPrefix string `mapstructure:"gitops-review-branch-prefix"`
+	ControllerManagedWrites string `mapstructure:"controller-managed-writes"`
 }
 
 func (c* ArgoCd) findFieldByTag(tagValue string) (any, bool) {
@@ -8,26 +8,143 @@ var (
 	UsernameField = field.StringField(
 		"username",
 		field.WithDescription("Username for authenticating with Argo CD CLI."),
-		field.WithRequired(true),
 		field.WithDisplayName("Username"),
 	)
 	PasswordField = field.StringField(
 		"password",
 		field.WithDescription("Password for authenticating with Argo CD CLI."),
 		field.WithIsSecret(true),
-		field.WithRequired(true),
 		field.WithDisplayName("Password"),
 	)
 	ApiUrlField = field.StringField(
 		"api-url",
 		field.WithDescription("API URL for Argo CD."),
-		field.WithRequired(true),
 		field.WithDisplayName("API URL"),
 	)
-	ConfigurationFields = []field.SchemaField{UsernameField, PasswordField, ApiUrlField}
+	DryRunField = field.BoolField(
+		"dry-run",
+		field.WithDescription("Log the ConfigMap and Secret changes provisioning operations would make, validated with a server-side dry run, without applying them."),
+		field.WithDisplayName("Dry run"),
+	)
+	GitOpsRepoUrlField = field.StringField(
+		"gitops-repo-url",
+		field.WithDescription("URL of the Git repository holding the argocd-rbac-cm and argocd-cm manifests. When set, changes to those ConfigMaps are committed there "+
+			"instead of patched in the cluster. With an instances file, set gitops per instance there instead."),
+		field.WithDisplayName("GitOps repository URL"),
+	)
+	GitOpsBranchField = field.StringField(
+		"gitops-branch",
+		field.WithDescription("Branch of the GitOps repository the manifests are read from and committed to."),
+		field.WithDisplayName("GitOps branch"),
+		field.WithDefaultValue("main"),
+	)
+	GitOpsRBACConfigMapPathField = field.StringField(
+		"gitops-rbac-cm-path",
+		field.WithDescription("Path, relative to the repository root, of the manifest or kustomize patch defining argocd-rbac-cm."),
+		field.WithDisplayName("GitOps argocd-rbac-cm path"),
+	)
+	GitOpsConfigMapPathField = field.StringField(
+		"gitops-cm-path",
+		field.WithDescription("Path, relative to the repository root, of the manifest or kustomize patch defining argocd-cm."),
+		field.WithDisplayName("GitOps argocd-cm path"),
+	)
+	GitOpsReviewBranchPrefixField = field.StringField(
+		"gitops-review-branch-prefix",
+		field.WithDescription("When set, each change is pushed to a new branch with this prefix for review instead of to the GitOps branch."),
+		field.WithDisplayName("GitOps review branch prefix"),
+	)
+	ControllerManagedWritesField = field.StringField(
+		"controller-managed-writes",
+		field.WithDescription("What to do when argocd-rbac-cm, argocd-cm or argocd-secret carries Argo CD or Flux tracking labels "+
+			"and would be patched in the cluster: \"warn\" writes and logs a warning, \"refuse\" fails the operation."),
+		field.WithDisplayName("Writes to GitOps-managed objects"),
+		field.WithDefaultValue("warn"),
+		field.WithString(func(r *field.StringRuler) {
+			r.In([]string{"warn", "refuse"})
+		}),
+	)
+	NamespaceField = field.StringField(
+		"argocd-namespace",
+		field.WithDescription("Namespace Argo CD is installed in, e.g. openshift-gitops for OpenShift GitOps."),
+		field.WithDisplayName("Argo CD namespace"),
+		field.WithDefaultValue("argocd"),
+	)
+	InstallTypeField = field.StringField(
+		"install-type",
+		field.WithDescription("How Argo CD is configured: \"configmap\" writes argocd-cm and argocd-rbac-cm, \"operator\" writes the "+
+			"ArgoCD resource of the Argo CD Operator or OpenShift GitOps, \"auto\" detects it."),
+		field.WithDisplayName("Install type"),
+		field.WithDefaultValue("auto"),
+		field.WithString(func(r *field.StringRuler) {
+			r.In([]string{"auto", "configmap", "operator"})
+		}),
+	)
+	ArgoCDNameField = field.StringField(
+		"argocd-name",
+		field.WithDescription("Name of the ArgoCD resource for operator installs. Only needed when the namespace has more than one."),
+		field.WithDisplayName("ArgoCD resource name"),
+	)
+	InstancesFileField = field.StringField(
+		"instances-file",
+		field.WithDescription("Path to a YAML file listing several Argo CD instances to sync, each with its name, apiUrl, username, "+
+			"password or passwordEnv, and optionally kubeContext, namespace, installType, argocdName and gitops. Replaces api-url, username, password "+
+			"and the gitops-* flags."),
+		field.WithDisplayName("Instances file"),
+	)
+	DiscoverInstancesField = field.BoolField(
+		"discover-instances",
+		field.WithDescription("Scan the cluster of each instance for other Argo CD installations, by their argocd-rbac-cm and argocd-cm "+
+			"ConfigMaps labelled app.kubernetes.io/part-of: argocd, and sync each one found as its own instance, read-only."),
+		field.WithDisplayName("Discover instances"),
+	)
+	AccountEmailsFileField = field.StringField(
+		"account-emails-file",
+		field.WithDescription("Path to a YAML file mapping local account names to the email of their owner, for accounts without an "+
+			"accounts.<name>.email key in argocd-cm."),
+		field.WithDisplayName("Account emails file"),
+	)
+	AccountTokenLifetimeField = field.StringField(
+		"account-token-lifetime",
+		field.WithDescription("Default lifetime of the API tokens created for local accounts without an expires_in, such as 720h or 30d. "+
+			"Empty for tokens that never expire. Rotating an existing token keeps its lifetime."),
+		field.WithDisplayName("Account token lifetime"),
+	)
+	GroupMappingFileField = field.StringField(
+		"group-mapping-file",
+		field.WithDescription("Path to a YAML file mapping the SSO group subjects of policy.csv to identity provider groups, by a static "+
+			"table, Dex connector prefixes and regex rewrites, so that role grants to groups expand through the identity provider's connector."),
+		field.WithDisplayName("Group mapping file"),
+	)
+	ConfigurationFields = []field.SchemaField{
+		UsernameField,
+		PasswordField,
+		ApiUrlField,
+		InstancesFileField,
+		DiscoverInstancesField,
+		NamespaceField,
+		InstallTypeField,
+		ArgoCDNameField,
+		AccountEmailsFileField,
+		AccountTokenLifetimeField,
+		GroupMappingFileField,
+		DryRunField,
+		GitOpsRepoUrlField,
+		GitOpsBranchField,
+		GitOpsRBACConfigMapPathField,
+		GitOpsConfigMapPathField,
+		GitOpsReviewBranchPrefixField,
+		ControllerManagedWritesField,
+	}
 
 	FieldRelationships = []field.SchemaFieldRelationship{
-		field.FieldsRequiredTogether(UsernameField, PasswordField),
+		field.FieldsRequiredTogether(UsernameField, PasswordField, ApiUrlField),
+		field.FieldsAtLeastOneUsed(ApiUrlField, InstancesFileField),
+		field.FieldsMutuallyExclusive(ApiUrlField, InstancesFileField),
+		field.FieldsMutuallyExclusive(InstancesFileField, GitOpsRepoUrlField),
+		field.FieldsDependentOn(
+			[]field.SchemaField{GitOpsRBACConfigMapPathField, GitOpsConfigMapPathField, GitOpsReviewBranchPrefixField},
+			[]field.SchemaField{GitOpsRepoUrlField},
+		),
 	}
 )
 
@@ -36,6 +36,72 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid config - refuse writes to GitOps-managed objects",
+			config: &ArgoCd{
+				Username:                "admin",
+				Password:                "test-password",
+				ApiUrl:                  "https://test.com",
+				ControllerManagedWrites: "refuse",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid config - unknown controller-managed-writes value",
+			config: &ArgoCd{
+				Username:                "admin",
+				Password:                "test-password",
+				ApiUrl:                  "https://test.com",
+				ControllerManagedWrites: "ignore",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid config - instances file",
+			config: &ArgoCd{
+				InstancesFile: "/etc/baton/instances.yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid config - instances file with api url",
+			config: &ArgoCd{
+				Username:      "admin",
+				Password:      "test-password",
+				ApiUrl:        "https://test.com",
+				InstancesFile: "/etc/baton/instances.yaml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid config - instances file with GitOps write-back",
+			config: &ArgoCd{
+				InstancesFile: "/etc/baton/instances.yaml",
+				GitopsRepoUrl: "https://github.com/org/argocd-config",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid config - discovery",
+			config: &ArgoCd{
+				Username:          "admin",
+				Password:          "test-password",
+				ApiUrl:            "https://test.com",
+				DiscoverInstances: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid config - discovery with GitOps write-back for the configured instance",
+			config: &ArgoCd{
+				Username:          "admin",
+				Password:          "test-password",
+				ApiUrl:            "https://test.com",
+				DiscoverInstances: true,
+				GitopsRepoUrl:     "https://github.com/org/argocd-config",
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid config - missing username",
 			config: &ArgoCd{
@@ -0,0 +1,336 @@
+package connector
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// profile fields of a new account token's secret trait.
+const (
+	tokenExpiresInProfileField   = "expires_in"
+	tokenDescriptionProfileField = "description"
+)
+
+// maxTokenIDPrefix bounds the part of a token ID taken from its description.
+const maxTokenIDPrefix = 40
+
+// accountTokenBuilder implements the ResourceSyncer, ResourceManager and CredentialManager interfaces for the
+// API tokens of local accounts. Tokens are only ever returned by Rotate, as plaintext data the SDK encrypts.
+type accountTokenBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for account tokens.
+func (a *accountTokenBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return accountTokenResourceType
+}
+
+// List returns the API tokens of a local account, identified by `<account>/<token id>`.
+func (a *accountTokenBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != userResourceType.Id {
+		return nil, "", nil, nil
+	}
+	inst, name, err := a.instances.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	accounts, err := inst.client.GetAccounts(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch accounts: %w", err)
+	}
+	i := slices.IndexFunc(accounts, func(account *client.Account) bool { return account.Name == name })
+	if i < 0 {
+		return nil, "", nil, nil
+	}
+
+	var resources []*v2.Resource
+	for _, token := range accounts[i].Tokens {
+		tokenResource, err := newAccountTokenResource(inst, parentResourceID, name, token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, tokenResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns an empty slice, tokens carry the access of their account.
+func (a *accountTokenBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty slice, tokens carry the access of their account.
+func (a *accountTokenBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Create issues an API token for the local account of the token's parent user resource, with the `expires_in`
+// and `description` fields of its secret profile. expires_in, such as 720h or 30d, defaults to the configured
+// account token lifetime; the description, reduced to lowercase letters, digits and dashes, prefixes the token's
+// ID. The created resource is identified by the ID Argo CD lists the token under. The SDK only encrypts
+// credentials returned by rotation, so the token is delivered by rotating the created resource, which issues one
+// of the same lifetime and description and revokes the token issued here, never returned.
+func (a *accountTokenBuilder) Create(ctx context.Context, tokenResource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	parentResourceID := tokenResource.GetParentResourceId()
+	if parentResourceID.GetResourceType() != userResourceType.Id {
+		return nil, nil, fmt.Errorf("account token needs a user resource as parent")
+	}
+	inst, name, err := a.instances.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := findAPIKeyAccount(ctx, inst, name); err != nil {
+		return nil, nil, err
+	}
+
+	trait := &v2.SecretTrait{}
+	resourceAnnos := annotations.Annotations(tokenResource.GetAnnotations())
+	if _, err := resourceAnnos.Pick(trait); err != nil {
+		return nil, nil, fmt.Errorf("invalid account token: %w", err)
+	}
+	fields := trait.GetProfile().GetFields()
+	var expiresIn time.Duration
+	if lifetime := strings.TrimSpace(fields[tokenExpiresInProfileField].GetStringValue()); lifetime != "" {
+		expiresIn, err = client.ParseTokenLifetime(lifetime)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	description := fields[tokenDescriptionProfileField].GetStringValue()
+
+	id, err := newTokenID(description)
+	if err != nil {
+		return nil, nil, err
+	}
+	issuedAt := time.Now()
+	_, annos, err := inst.client.CreateAccountToken(ctx, name, expiresIn, id)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to issue account token: %w", err)
+	}
+	if client.GetNotApplied(annos) != nil {
+		created, err := newAccountTokenResource(inst, parentResourceID, name, issuedToken(id, issuedAt, expiresIn))
+		return created, annos, err
+	}
+	ctxzap.Extract(ctx).Info("issued account token",
+		zap.String("account", name),
+		zap.String("token_id", id),
+		zap.String("description", description),
+	)
+
+	// The lifetime may be the configured default, so the issued token is read back for its expiry.
+	token := issuedToken(id, issuedAt, expiresIn)
+	if account, err := findAPIKeyAccount(ctx, inst, name); err == nil {
+		if i := slices.IndexFunc(account.Tokens, func(t client.AccountToken) bool { return t.ID == id }); i >= 0 {
+			token = account.Tokens[i]
+		}
+	}
+	created, err := newAccountTokenResource(inst, parentResourceID, name, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, nil, nil
+}
+
+// Delete revokes an account token.
+func (a *accountTokenBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	inst, account, id, err := a.resolveToken(resourceId)
+	if err != nil {
+		return nil, err
+	}
+	annos, err := inst.client.DeleteAccountToken(ctx, account, id)
+	if err != nil {
+		return annos, fmt.Errorf("failed to revoke account token: %w", err)
+	}
+	return annos, nil
+}
+
+// RotateCapabilityDetails declares support for issuing tokens, which Argo CD generates itself.
+func (a *accountTokenBuilder) RotateCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	return tokenRotationDetails(), nil, nil
+}
+
+// Rotate issues a new token for the account of an existing one, with the same lifetime, returns it with the ID
+// it was issued under, and then revokes the existing token. As for project role tokens, the new token's ID is the
+// existing one with the issue time as suffix, since Argo CD refuses an ID the account already uses. A failure to
+// revoke the existing token is only logged, since the new token can't be returned again.
+func (a *accountTokenBuilder) Rotate(ctx context.Context, resourceId *v2.ResourceId, _ *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	inst, name, id, err := a.resolveToken(resourceId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	account, err := findAPIKeyAccount(ctx, inst, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := slices.IndexFunc(account.Tokens, func(token client.AccountToken) bool { return token.ID == id })
+	if i < 0 {
+		return nil, nil, fmt.Errorf("token %s not found", resourceId.Resource)
+	}
+	var expiresIn time.Duration
+	if token := account.Tokens[i]; token.ExpiresAt > 0 {
+		expiresIn = time.Duration(token.ExpiresAt-token.IssuedAt) * time.Second
+	}
+
+	newID := rotatedTokenID(id, time.Now())
+	token, annos, err := inst.client.CreateAccountToken(ctx, name, expiresIn, newID)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to issue account token: %w", err)
+	}
+	if client.GetNotApplied(annos) != nil {
+		return nil, annos, nil
+	}
+	ctxzap.Extract(ctx).Info("issued account token",
+		zap.String("account", name),
+		zap.String("token_id", newID),
+		zap.String("replaces", id),
+	)
+
+	if _, err := inst.client.DeleteAccountToken(ctx, name, id); err != nil {
+		ctxzap.Extract(ctx).Warn("issued a new account token but failed to revoke the one it replaces",
+			zap.String("token", resourceId.Resource),
+			zap.Error(err),
+		)
+	}
+	return []*v2.PlaintextData{
+		{Name: "token", Bytes: []byte(token)},
+		{Name: "token_id", Description: "The ID Argo CD lists the token under.", Bytes: []byte(newID)},
+	}, nil, nil
+}
+
+// resolveToken splits an account token resource ID into its instance, account and token ID.
+// Tokens of discovered instances can't be revoked or rotated.
+func (a *accountTokenBuilder) resolveToken(resourceId *v2.ResourceId) (*instance, string, string, error) {
+	inst, id, err := a.instances.resolve(resourceId.Resource)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, "", "", err
+	}
+	account, tokenID, ok := strings.Cut(id, "/")
+	if !ok || account == "" || tokenID == "" {
+		return nil, "", "", fmt.Errorf("invalid account token id %q", resourceId.Resource)
+	}
+	return inst, account, tokenID, nil
+}
+
+// newAccountTokenResource creates a secret resource for an API token of a local account, under its user resource.
+func newAccountTokenResource(inst *instance, parentResourceID *v2.ResourceId, account string, token client.AccountToken) (*v2.Resource, error) {
+	issuedAt := time.Unix(token.IssuedAt, 0).UTC()
+	options := []resource.SecretTraitOption{
+		resource.WithSecretCreatedAt(issuedAt),
+		resource.WithSecretIdentityID(parentResourceID),
+	}
+	description := "Issued " + issuedAt.Format(time.RFC3339)
+	if token.ExpiresAt > 0 {
+		expiresAt := time.Unix(token.ExpiresAt, 0).UTC()
+		options = append(options, resource.WithSecretExpiresAt(expiresAt))
+		description += ", expires " + expiresAt.Format(time.RFC3339)
+	} else {
+		description += ", never expires"
+	}
+
+	tokenResource, err := resource.NewSecretResource(
+		token.ID,
+		accountTokenResourceType,
+		inst.id(account+"/"+token.ID),
+		options,
+		resource.WithParentResourceID(parentResourceID),
+		resource.WithDescription(description),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account token resource %s: %w", token.ID, err)
+	}
+	return tokenResource, nil
+}
+
+// issuedToken returns the token issued under id at issuedAt with the given lifetime, zero for one that never expires.
+func issuedToken(id string, issuedAt time.Time, expiresIn time.Duration) client.AccountToken {
+	token := client.AccountToken{ID: id, IssuedAt: issuedAt.Unix()}
+	if expiresIn > 0 {
+		token.ExpiresAt = issuedAt.Add(expiresIn).Unix()
+	}
+	return token
+}
+
+// findAPIKeyAccount returns the local account with the given name, which must have the apiKey capability.
+func findAPIKeyAccount(ctx context.Context, inst *instance, name string) (*client.Account, error) {
+	accounts, err := inst.client.GetAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch accounts: %w", err)
+	}
+	i := slices.IndexFunc(accounts, func(a *client.Account) bool { return a.Name == name })
+	if i < 0 {
+		return nil, fmt.Errorf("local account %s not found", name)
+	}
+	if !slices.Contains(accounts[i].Capabilities, apiKeyCapability) {
+		return nil, fmt.Errorf("account %s doesn't have the %s capability", name, apiKeyCapability)
+	}
+	return accounts[i], nil
+}
+
+// tokenRotationDetails declares support for issuing tokens, which Argo CD generates itself.
+func tokenRotationDetails() *v2.CredentialDetailsCredentialRotation {
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}
+}
+
+// newTokenID returns a unique token ID, prefixed with the description reduced to lowercase letters, digits and
+// dashes, or with `baton` without one, so it can be recognized in the account's token list.
+func newTokenID(description string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	var prefix strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(description) {
+		if prefix.Len() >= maxTokenIDPrefix {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			prefix.WriteRune(r)
+			dash = false
+		} else if prefix.Len() > 0 && !dash {
+			prefix.WriteByte('-')
+			dash = true
+		}
+	}
+	if prefix.Len() == 0 {
+		return "baton-" + hex.EncodeToString(b), nil
+	}
+	return strings.TrimSuffix(prefix.String(), "-") + "-" + hex.EncodeToString(b), nil
+}
+
+// newAccountTokenBuilder creates a new accountTokenBuilder.
+func newAccountTokenBuilder(instances instances) *accountTokenBuilder {
+	return &accountTokenBuilder{
+		resourceType: accountTokenResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,147 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestAccountTokens tests listing account API tokens as secrets, and creating, rotating and revoking them.
+func TestAccountTokens(t *testing.T) {
+	type issued struct {
+		account   string
+		expiresIn time.Duration
+		id        string
+	}
+	var calls []issued
+	var deleted []string
+	mockCli := &test.MockClient{
+		GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+			return []*client.Account{
+				{Name: "ci", Enabled: true, Capabilities: []string{"apiKey"}, Tokens: []client.AccountToken{
+					{ID: "deploy", IssuedAt: 1700000000, ExpiresAt: 1702592000},
+					{ID: "nightly", IssuedAt: 1720000000},
+				}},
+				{Name: "alice", Enabled: true, Capabilities: []string{"login"}},
+			}, nil
+		},
+		CreateAccountTokenFunc: func(ctx context.Context, account string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+			calls = append(calls, issued{account, expiresIn, id})
+			return "eyJhbGciOi.payload.signature", nil, nil
+		},
+		DeleteAccountTokenFunc: func(ctx context.Context, account string, id string) (annotations.Annotations, error) {
+			deleted = append(deleted, account+"/"+id)
+			return nil, nil
+		},
+	}
+	tokens := newAccountTokenBuilder(singleInstance(mockCli))
+	ciID := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "ci"}
+
+	t.Run("list", func(t *testing.T) {
+		resources, _, _, err := tokens.List(context.Background(), ciID, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+		assert.Equal(t, "ci/deploy", resources[0].Id.Resource)
+		assert.Equal(t, "deploy", resources[0].DisplayName)
+		assert.Equal(t, ciID, resources[0].ParentResourceId)
+		assert.Equal(t, "Issued 2023-11-14T22:13:20Z, expires 2023-12-14T22:13:20Z", resources[0].Description)
+		assert.Contains(t, resources[1].Description, "never expires")
+
+		resources, _, _, err = tokens.List(context.Background(), nil, &pagination.Token{})
+		require.NoError(t, err)
+		assert.Empty(t, resources)
+	})
+
+	newToken := func(parent *v2.ResourceId, profile map[string]interface{}) *v2.Resource {
+		p, err := structpb.NewStruct(profile)
+		require.NoError(t, err)
+		return &v2.Resource{
+			Id:               &v2.ResourceId{ResourceType: accountTokenResourceType.Id},
+			ParentResourceId: parent,
+			Annotations:      annotations.New(&v2.SecretTrait{Profile: p}),
+		}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		calls = nil
+		created, _, err := tokens.Create(context.Background(), newToken(ciID, map[string]interface{}{
+			"expires_in":  "30d",
+			"description": "CI deploy",
+		}))
+		require.NoError(t, err)
+		require.Len(t, calls, 1)
+		assert.Equal(t, "ci", calls[0].account)
+		assert.Equal(t, 30*24*time.Hour, calls[0].expiresIn)
+		assert.Regexp(t, `^ci-deploy-[0-9a-f]{8}$`, calls[0].id)
+		assert.Equal(t, "ci/"+calls[0].id, created.Id.Resource)
+		assert.Equal(t, ciID, created.ParentResourceId)
+		assert.Contains(t, created.Description, ", expires ")
+
+		calls = nil
+		created, _, err = tokens.Create(context.Background(), newToken(ciID, nil))
+		require.NoError(t, err)
+		require.Len(t, calls, 1)
+		// Zero lets the client apply the configured account token lifetime.
+		assert.Zero(t, calls[0].expiresIn)
+		assert.Regexp(t, `^baton-[0-9a-f]{8}$`, calls[0].id)
+		assert.Equal(t, "ci/"+calls[0].id, created.Id.Resource)
+		assert.Empty(t, deleted)
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		calls, deleted = nil, nil
+		plaintexts, _, err := tokens.Rotate(context.Background(), &v2.ResourceId{ResourceType: accountTokenResourceType.Id, Resource: "ci/deploy"}, nil)
+		require.NoError(t, err)
+		require.Len(t, plaintexts, 2)
+		assert.Equal(t, "eyJhbGciOi.payload.signature", string(plaintexts[0].Bytes))
+		assert.Equal(t, "token_id", plaintexts[1].Name)
+
+		require.Len(t, calls, 1)
+		assert.Equal(t, 30*24*time.Hour, calls[0].expiresIn)
+		assert.Regexp(t, `^deploy-[0-9]{10}$`, calls[0].id)
+		assert.Equal(t, calls[0].id, string(plaintexts[1].Bytes))
+		assert.Equal(t, []string{"ci/deploy"}, deleted)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		deleted = nil
+		_, err := tokens.Delete(context.Background(), &v2.ResourceId{ResourceType: accountTokenResourceType.Id, Resource: "ci/nightly"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ci/nightly"}, deleted)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := tokens.Create(context.Background(), newToken(&v2.ResourceId{ResourceType: userResourceType.Id, Resource: "alice"}, nil))
+		assert.ErrorContains(t, err, "account alice doesn't have the apiKey capability")
+		_, _, err = tokens.Create(context.Background(), newToken(&v2.ResourceId{ResourceType: userResourceType.Id, Resource: "bob"}, nil))
+		assert.ErrorContains(t, err, "local account bob not found")
+		_, _, err = tokens.Create(context.Background(), newToken(nil, nil))
+		assert.ErrorContains(t, err, "account token needs a user resource as parent")
+		_, _, err = tokens.Create(context.Background(), newToken(ciID, map[string]interface{}{"expires_in": "soon"}))
+		assert.Error(t, err)
+		_, _, err = tokens.Rotate(context.Background(), &v2.ResourceId{ResourceType: accountTokenResourceType.Id, Resource: "ci/missing"}, nil)
+		assert.ErrorContains(t, err, "token ci/missing not found")
+		_, err = tokens.Delete(context.Background(), &v2.ResourceId{ResourceType: accountTokenResourceType.Id, Resource: "ci"})
+		assert.ErrorContains(t, err, "invalid account token id")
+	})
+
+	t.Run("discovered instance", func(t *testing.T) {
+		is := instances{
+			{name: "prod", client: mockCli},
+			{name: "staging", client: mockCli, namespace: "argocd-staging", discovered: true},
+		}
+		_, _, err := newAccountTokenBuilder(is).Create(context.Background(), newToken(&v2.ResourceId{ResourceType: userResourceType.Id, Resource: "staging:ci"}, nil))
+		assert.ErrorContains(t, err, "synced read-only")
+		_, err = newAccountTokenBuilder(is).Delete(context.Background(), &v2.ResourceId{ResourceType: accountTokenResourceType.Id, Resource: "staging:ci/deploy"})
+		assert.ErrorContains(t, err, "synced read-only")
+	})
+}
@@ -0,0 +1,175 @@
+package connector
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	configv1 "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// action is a custom action the connector can be asked to run, with the handler invoked with its arguments.
+type action struct {
+	schema *v2.BatonActionSchema
+	invoke func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, error)
+}
+
+// actionManager implements the CustomActionManager interface. Actions run synchronously: an invocation
+// completes or fails before InvokeAction returns, and only its outcome is kept for GetActionStatus,
+// never its response.
+type actionManager struct {
+	actions []*action
+
+	mu          sync.Mutex
+	invocations map[string]actionInvocation
+}
+
+type actionInvocation struct {
+	name   string
+	status v2.BatonActionStatus
+}
+
+// RegisterActionManager returns the manager of the connector's custom actions.
+func (d *Connector) RegisterActionManager(ctx context.Context) (connectorbuilder.CustomActionManager, error) {
+	return newActionManager(d.instances), nil
+}
+
+// ListActionSchemas returns the schemas of every custom action.
+func (m *actionManager) ListActionSchemas(ctx context.Context) ([]*v2.BatonActionSchema, annotations.Annotations, error) {
+	schemas := make([]*v2.BatonActionSchema, 0, len(m.actions))
+	for _, a := range m.actions {
+		schemas = append(schemas, a.schema)
+	}
+	return schemas, nil, nil
+}
+
+// GetActionSchema returns the schema of the named custom action.
+func (m *actionManager) GetActionSchema(ctx context.Context, name string) (*v2.BatonActionSchema, annotations.Annotations, error) {
+	a, err := m.get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.schema, nil, nil
+}
+
+// InvokeAction runs the named custom action with its arguments and returns its response.
+func (m *actionManager) InvokeAction(
+	ctx context.Context,
+	name string,
+	args *structpb.Struct,
+) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+	a, err := m.get(name)
+	if err != nil {
+		return "", v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, nil, err
+	}
+	id, err := newInvocationID()
+	if err != nil {
+		return "", v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, nil, err
+	}
+
+	response, err := a.invoke(ctx, args)
+	if err != nil {
+		m.record(id, name, v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED)
+		return id, v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, nil, fmt.Errorf("action %s failed: %w", name, err)
+	}
+	m.record(id, name, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE)
+	return id, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, response, nil, nil
+}
+
+// GetActionStatus returns the outcome of an invocation. Responses aren't kept.
+func (m *actionManager) GetActionStatus(ctx context.Context, id string) (v2.BatonActionStatus, string, *structpb.Struct, annotations.Annotations, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	invocation, ok := m.invocations[id]
+	if !ok {
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_UNKNOWN, "", nil, nil, fmt.Errorf("unknown action invocation %q", id)
+	}
+	return invocation.status, invocation.name, nil, nil, nil
+}
+
+func (m *actionManager) get(name string) (*action, error) {
+	for _, a := range m.actions {
+		if a.schema.Name == name {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown action %q", name)
+}
+
+func (m *actionManager) record(id string, name string, status v2.BatonActionStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.invocations[id] = actionInvocation{name: name, status: status}
+}
+
+// newInvocationID returns a random ID for an action invocation.
+func newInvocationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate action invocation id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// stringField returns a string argument or return value of an action schema.
+func stringField(name string, displayName string, description string, required bool) *configv1.Field {
+	return &configv1.Field{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		IsRequired:  required,
+		Field:       &configv1.Field_StringField{StringField: &configv1.StringField{}},
+	}
+}
+
+// boolField returns a boolean return value of an action schema.
+func boolField(name string, displayName string, description string) *configv1.Field {
+	return &configv1.Field{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		Field:       &configv1.Field_BoolField{BoolField: &configv1.BoolField{}},
+	}
+}
+
+// stringListField returns a string list return value of an action schema.
+func stringListField(name string, displayName string, description string) *configv1.Field {
+	return &configv1.Field{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		Field:       &configv1.Field_StringSliceField{StringSliceField: &configv1.StringSliceField{}},
+	}
+}
+
+// stringArg returns a string argument of an action invocation, or "" if it isn't set.
+func stringArg(args *structpb.Struct, name string) (string, error) {
+	v, ok := args.GetFields()[name]
+	if !ok {
+		return "", nil
+	}
+	if _, ok := v.GetKind().(*structpb.Value_StringValue); !ok {
+		return "", fmt.Errorf("argument %s must be a string", name)
+	}
+	return v.GetStringValue(), nil
+}
+
+// newActionManager creates the manager of the connector's custom actions.
+func newActionManager(instances instances) *actionManager {
+	return &actionManager{
+		actions: []*action{
+			newRolePermissionAction(instances, addRolePermissionAction),
+			newRolePermissionAction(instances, removeRolePermissionAction),
+			newRoleChangeAction(instances, cloneRoleAction),
+			newRoleChangeAction(instances, renameRoleAction),
+			newRoleChangeAction(instances, migrateRoleMembersAction),
+		},
+		invocations: map[string]actionInvocation{},
+	}
+}
@@ -0,0 +1,142 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// applicationActions are the actions on an application that are exposed as entitlements.
+var applicationActions = []policyAction{
+	{"get", "applications", "get", "view"},
+	{"sync", "applications", "sync", "sync"},
+	{"update", "applications", "update", "update"},
+	{"delete", "applications", "delete", "delete"},
+	{"override", "applications", "override", "override the parameters of"},
+	{"action", "applications", "action/*", "run resource actions on"},
+	{"exec", "exec", "create", "exec into the pods of"},
+}
+
+// applicationBuilder implements the ResourceSyncer interface for Argo CD applications.
+type applicationBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for applications.
+func (a *applicationBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return applicationResourceType
+}
+
+// List returns the applications of a project.
+func (a *applicationBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	inst, project, err := a.instances.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	applications, err := inst.applications(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var resources []*v2.Resource
+	for _, app := range applications {
+		if app.Project != project {
+			continue
+		}
+		appResource, err := parseApplicationResource(inst, app, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, appResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns a permission entitlement for each Argo CD action on the application.
+func (a *applicationBuilder) Entitlements(_ context.Context, appResource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return policyEntitlements(appResource, applicationActions), "", nil, nil
+}
+
+// Grants returns a grant to each role whose policy allows an action on the application, evaluated against
+// its `<project>/<application>` object. Grants are expandable to the role's members.
+func (a *applicationBuilder) Grants(ctx context.Context, appResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	inst, id, err := a.instances.resolve(appResource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	_, project, err := a.instances.resolve(appResource.ParentResourceId.GetResource())
+	if err != nil {
+		return nil, "", nil, err
+	}
+	object, err := applicationRBACObject(ctx, inst, id, project)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	grants, err := policyGrants(ctx, inst, appResource, object, applicationActions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return grants, "", nil, nil
+}
+
+// applicationRBACObject returns the RBAC object of the application identified by `<namespace>/<name>`, from the
+// Applications listed for the sync, or computed from its project if it is no longer listed.
+func applicationRBACObject(ctx context.Context, inst *instance, id string, project string) (string, error) {
+	applications, err := inst.applications(ctx)
+	if err != nil {
+		return "", err
+	}
+	namespace, name, _ := strings.Cut(id, "/")
+	for _, app := range applications {
+		if app.Namespace == namespace && app.Name == name {
+			return app.RBACObject, nil
+		}
+	}
+
+	install, err := inst.client.DetectInstallation(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect Argo CD installation: %w", err)
+	}
+	return client.ApplicationRBACObject(project, namespace, name, install.Namespace), nil
+}
+
+// parseApplicationResource creates a resource for an application, identified by `<namespace>/<name>`.
+func parseApplicationResource(inst *instance, app *client.Application, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := fmt.Sprintf("Application %s in namespace %s, RBAC object %s", app.Name, app.Namespace, app.RBACObject)
+	if app.ApplicationSet != "" {
+		description += ", generated by ApplicationSet " + app.ApplicationSet
+	}
+	appResource, err := resource.NewResource(
+		app.Name,
+		applicationResourceType,
+		inst.id(app.Namespace+"/"+app.Name),
+		resource.WithParentResourceID(parentResourceID),
+		resource.WithDescription(description),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create application resource %s: %w", app.Name, err)
+	}
+	return appResource, nil
+}
+
+// newApplicationBuilder creates a new applicationBuilder.
+func newApplicationBuilder(instances instances) *applicationBuilder {
+	return &applicationBuilder{
+		resourceType: applicationResourceType,
+		instances:    instances,
+	}
+}
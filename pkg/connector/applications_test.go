@@ -0,0 +1,128 @@
+package connector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newApplicationMockClient() *test.MockClient {
+	return &test.MockClient{
+		GetApplicationsFunc: func(ctx context.Context) ([]*client.Application, error) {
+			return []*client.Application{
+				{Name: "payments", Namespace: "argocd", Project: "prod", RBACObject: "prod/payments"},
+				{Name: "checkout", Namespace: "team-a", Project: "prod", RBACObject: "prod/team-a/checkout"},
+				{Name: "guestbook", Namespace: "argocd", Project: "default", RBACObject: "default/guestbook"},
+			}, nil
+		},
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "deployer"}, {Name: "readonly"}}, nil, nil
+		},
+		GetPolicyEvaluatorFunc: func(ctx context.Context) (*client.PolicyEvaluator, error) {
+			return client.NewPolicyEvaluator(
+				"p, role:deployer, applications, sync, prod/*, allow\np, role:deployer, applications, sync, prod/payments, deny\n", "")
+		},
+	}
+}
+
+// TestApplicationBuilder_List tests listing the applications of a project.
+func TestApplicationBuilder_List(t *testing.T) {
+	builder := newApplicationBuilder(singleInstance(newApplicationMockClient()))
+	parent := &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: "prod"}
+
+	resources, _, _, err := builder.List(context.Background(), parent, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "argocd/payments", resources[0].Id.Resource)
+	assert.Equal(t, "team-a/checkout", resources[1].Id.Resource)
+	assert.Equal(t, parent, resources[1].ParentResourceId)
+
+	resources, _, _, err = builder.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	assert.Empty(t, resources)
+}
+
+// TestApplicationBuilder_Grants tests that grants follow the evaluated RBAC policy.
+func TestApplicationBuilder_Grants(t *testing.T) {
+	mockCli := newApplicationMockClient()
+	builder := newApplicationBuilder(singleInstance(mockCli))
+	parent := &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: "prod"}
+
+	resources, _, _, err := builder.List(context.Background(), parent, &pagination.Token{})
+	require.NoError(t, err)
+
+	ents, _, _, err := builder.Entitlements(context.Background(), resources[0], &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, ents, len(applicationActions))
+
+	grantsOf := func(res *v2.Resource) map[string][]string {
+		grants, _, _, err := builder.Grants(context.Background(), res, &pagination.Token{})
+		require.NoError(t, err)
+		byRole := map[string][]string{}
+		for _, g := range grants {
+			action := g.Entitlement.Id[strings.LastIndex(g.Entitlement.Id, ":")+1:]
+			byRole[g.Principal.Id.Resource] = append(byRole[g.Principal.Id.Resource], action)
+
+			expandable := &v2.GrantExpandable{}
+			annos := annotations.Annotations(g.Annotations)
+			ok, err := annos.Pick(expandable)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, []string{"role:" + g.Principal.Id.Resource + ":assigned"}, expandable.EntitlementIds)
+		}
+		return byRole
+	}
+
+	// Sync on prod/payments is denied; the built-in readonly role can view everything.
+	assert.Equal(t, map[string][]string{"readonly": {"get"}}, grantsOf(resources[0]))
+	assert.Equal(t, map[string][]string{"deployer": {"sync"}, "readonly": {"get"}}, grantsOf(resources[1]))
+}
+
+// TestApplicationBuilder_SyncCache tests that a sync lists the applications and loads the RBAC policy once per
+// instance, however many projects and applications it syncs, and that the next sync reads them again.
+func TestApplicationBuilder_SyncCache(t *testing.T) {
+	mockCli := newApplicationMockClient()
+	calls := map[string]int{}
+	getApplications, getRoles, getPolicyEvaluator := mockCli.GetApplicationsFunc, mockCli.GetRolesFunc, mockCli.GetPolicyEvaluatorFunc
+	mockCli.GetApplicationsFunc = func(ctx context.Context) ([]*client.Application, error) {
+		calls["applications"]++
+		return getApplications(ctx)
+	}
+	mockCli.GetRolesFunc = func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+		calls["roles"]++
+		return getRoles(ctx)
+	}
+	mockCli.GetPolicyEvaluatorFunc = func(ctx context.Context) (*client.PolicyEvaluator, error) {
+		calls["policy"]++
+		return getPolicyEvaluator(ctx)
+	}
+	connector := &Connector{instances: singleInstance(mockCli)}
+	builder := newApplicationBuilder(connector.instances)
+
+	sync := func() {
+		_, err := connector.Validate(context.Background())
+		require.NoError(t, err)
+		for _, project := range []string{"prod", "default", "staging"} {
+			parent := &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: project}
+			resources, _, _, err := builder.List(context.Background(), parent, &pagination.Token{})
+			require.NoError(t, err)
+			for _, res := range resources {
+				_, _, _, err := builder.Grants(context.Background(), res, &pagination.Token{})
+				require.NoError(t, err)
+			}
+		}
+	}
+
+	sync()
+	assert.Equal(t, map[string]int{"applications": 1, "roles": 1, "policy": 1}, calls)
+	sync()
+	assert.Equal(t, map[string]int{"applications": 2, "roles": 2, "policy": 2}, calls)
+}
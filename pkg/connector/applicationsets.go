@@ -0,0 +1,129 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// generatesEntitlement links an ApplicationSet to the Applications it generated.
+const generatesEntitlement = "generates"
+
+// applicationSetActions are the actions on an ApplicationSet that are exposed as entitlements.
+var applicationSetActions = []policyAction{
+	{"create", "applicationsets", "create", "create"},
+	{"update", "applicationsets", "update", "update"},
+	{"delete", "applicationsets", "delete", "delete"},
+}
+
+// applicationSetBuilder implements the ResourceSyncer interface for Argo CD ApplicationSets.
+type applicationSetBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for ApplicationSets.
+func (a *applicationSetBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return applicationSetResourceType
+}
+
+// List returns all ApplicationSets as resource objects. An ApplicationSet is identified by its RBAC object,
+// `<project>/<name>` or `<project>/<namespace>/<name>`.
+func (a *applicationSetBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	inst := a.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	appSets, err := inst.client.GetApplicationSets(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch applicationsets: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, appSet := range appSets {
+		appSetResource, err := resource.NewResource(
+			appSet.Name,
+			applicationSetResourceType,
+			appSet.RBACObject,
+			resource.WithDescription(fmt.Sprintf("ApplicationSet %s in namespace %s, generating applications in project %s",
+				appSet.Name, appSet.Namespace, appSet.Project)),
+		)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create applicationset resource %s: %w", appSet.Name, err)
+		}
+		resources = append(resources, inst.scope(appSetResource))
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns a permission entitlement for each Argo CD action on the ApplicationSet, and an entitlement
+// linking it to the Applications it generated.
+func (a *applicationSetBuilder) Entitlements(_ context.Context, appSetResource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := policyEntitlements(appSetResource, applicationSetActions)
+	entitlements = append(entitlements, entitlement.NewAssignmentEntitlement(
+		appSetResource,
+		generatesEntitlement,
+		entitlement.WithGrantableTo(applicationResourceType),
+		entitlement.WithDisplayName(fmt.Sprintf("%s applicationset generates", appSetResource.DisplayName)),
+		entitlement.WithDescription(fmt.Sprintf("Application generated by applicationset %s", appSetResource.DisplayName)),
+	))
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a grant to each role whose policy allows an action on the ApplicationSet, and a grant
+// to each Application owned by it.
+func (a *applicationSetBuilder) Grants(ctx context.Context, appSetResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	inst, object, err := a.instances.resolve(appSetResource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	grants, err := policyGrants(ctx, inst, appSetResource, object, applicationSetActions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	install, err := inst.client.DetectInstallation(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to detect Argo CD installation: %w", err)
+	}
+	namespace, name := applicationSetName(object, install.Namespace)
+
+	applications, err := inst.applications(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	for _, app := range applications {
+		if app.ApplicationSet != name || app.Namespace != namespace {
+			continue
+		}
+		appResourceID := &v2.ResourceId{ResourceType: applicationResourceType.Id, Resource: inst.id(app.Namespace + "/" + app.Name)}
+		grants = append(grants, grant.NewGrant(appSetResource, generatesEntitlement, appResourceID))
+	}
+	return grants, "", nil, nil
+}
+
+// applicationSetName returns the namespace and name of an ApplicationSet from its RBAC object.
+func applicationSetName(object string, argoCDNamespace string) (string, string) {
+	parts := strings.Split(object, "/")
+	if len(parts) < 3 {
+		return argoCDNamespace, parts[len(parts)-1]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+// newApplicationSetBuilder creates a new applicationSetBuilder.
+func newApplicationSetBuilder(instances instances) *applicationSetBuilder {
+	return &applicationSetBuilder{
+		resourceType: applicationSetResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,76 @@
+package connector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestApplicationSetBuilder tests listing ApplicationSets, deriving grants from the policy and linking
+// the Applications they own.
+func TestApplicationSetBuilder(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetApplicationSetsFunc: func(ctx context.Context) ([]*client.ApplicationSet, error) {
+			return []*client.ApplicationSet{
+				{Name: "services", Namespace: "argocd", Project: "prod", RBACObject: "prod/services"},
+				{Name: "services", Namespace: "team-a", Project: "dev", RBACObject: "dev/team-a/services"},
+			}, nil
+		},
+		GetApplicationsFunc: func(ctx context.Context) ([]*client.Application, error) {
+			return []*client.Application{
+				{Name: "payments", Namespace: "argocd", Project: "prod", ApplicationSet: "services"},
+				{Name: "checkout", Namespace: "argocd", Project: "prod", ApplicationSet: "services"},
+				{Name: "guestbook", Namespace: "argocd", Project: "prod"},
+				{Name: "payments", Namespace: "team-a", Project: "dev", ApplicationSet: "services"},
+			}, nil
+		},
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "platform"}, {Name: "team-a"}}, nil, nil
+		},
+		GetPolicyEvaluatorFunc: func(ctx context.Context) (*client.PolicyEvaluator, error) {
+			return client.NewPolicyEvaluator(
+				"p, role:platform, applicationsets, *, */*, allow\np, role:team-a, applicationsets, update, dev/*, allow\n", "")
+		},
+	}
+	builder := newApplicationSetBuilder(singleInstance(mockCli))
+
+	resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "prod/services", resources[0].Id.Resource)
+	assert.Equal(t, "dev/team-a/services", resources[1].Id.Resource)
+
+	ents, _, _, err := builder.Entitlements(context.Background(), resources[0], &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, ents, len(applicationSetActions)+1)
+
+	grants := func(i int) map[string][]string {
+		grants, _, _, err := builder.Grants(context.Background(), resources[i], &pagination.Token{})
+		require.NoError(t, err)
+		bySlug := map[string][]string{}
+		for _, g := range grants {
+			slug := g.Entitlement.Id[strings.LastIndex(g.Entitlement.Id, ":")+1:]
+			bySlug[slug] = append(bySlug[slug], g.Principal.Id.Resource)
+		}
+		return bySlug
+	}
+	assert.Equal(t, map[string][]string{
+		"create":    {"platform"},
+		"update":    {"platform"},
+		"delete":    {"platform"},
+		"generates": {"argocd/payments", "argocd/checkout"},
+	}, grants(0))
+	assert.Equal(t, map[string][]string{
+		"create":    {"platform"},
+		"update":    {"platform", "team-a"},
+		"delete":    {"platform"},
+		"generates": {"team-a/payments"},
+	}, grants(1))
+}
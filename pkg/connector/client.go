@@ -2,6 +2,7 @@ package connector
 
 import (
 	"context"
+	"time"
 
 	"github.com/conductorone/baton-argo-cd/pkg/client"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -11,11 +12,37 @@ import (
 // It's used to abstract the client implementation for testing.
 type ArgoCdClient interface {
 	GetAccounts(ctx context.Context) ([]*client.Account, error)
+	GetDexUsers(ctx context.Context) ([]*client.DexUser, error)
+	GetPasswordPattern(ctx context.Context) (string, error)
 	GetRoles(ctx context.Context) ([]*client.Role, annotations.Annotations, error)
+	GetGroups(ctx context.Context) ([]*client.Group, error)
+	CreateRole(ctx context.Context, role string, policies []*client.PolicyDefinition) (annotations.Annotations, error)
+	DeleteRole(ctx context.Context, role string) (annotations.Annotations, error)
+	AddRolePolicy(ctx context.Context, policy *client.PolicyDefinition) (bool, error)
+	RemoveRolePolicy(ctx context.Context, policy *client.PolicyDefinition) (bool, error)
+	CloneRole(ctx context.Context, role string, newRole string) (*client.RoleChange, error)
+	RenameRole(ctx context.Context, role string, newRole string) (*client.RoleChange, error)
+	MigrateRoleMembers(ctx context.Context, role string, newRole string) (*client.RoleChange, error)
 	GetDefaultRole(ctx context.Context) (string, error)
-	CreateAccount(ctx context.Context, username string, password string) (*client.Account, annotations.Annotations, error)
+	CreateAccount(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error)
+	CreateAccountToken(ctx context.Context, account string, expiresIn time.Duration, id string) (string, annotations.Annotations, error)
+	DeleteAccountToken(ctx context.Context, account string, id string) (annotations.Annotations, error)
 	UpdateUserRole(ctx context.Context, userID string, roleID string) (annotations.Annotations, error)
 	RemoveUserRole(ctx context.Context, userID string, roleID string) (annotations.Annotations, error)
 	GetUserRoles(ctx context.Context, userID string) ([]string, error)
 	GetRoleUsers(ctx context.Context, roleID string) ([]*client.Account, error)
+	GetRoleBindings(ctx context.Context, roleID string) ([]*client.PolicyBinding, error)
+	GetControllerOwner(ctx context.Context, kind string, name string) (*client.ControllerOwner, error)
+	DetectInstallation(ctx context.Context) (*client.Installation, error)
+	GetProjects(ctx context.Context) ([]*client.Project, error)
+	GetProject(ctx context.Context, name string) (*client.Project, error)
+	CreateProjectToken(ctx context.Context, project string, role string, expiresIn time.Duration, id string) (string, annotations.Annotations, error)
+	DeleteProjectToken(ctx context.Context, project string, role string, issuedAt int64) (annotations.Annotations, error)
+	GetApplications(ctx context.Context) ([]*client.Application, error)
+	GetPolicyEvaluator(ctx context.Context) (*client.PolicyEvaluator, error)
+	GetApplicationSets(ctx context.Context) ([]*client.ApplicationSet, error)
+	GetClusters(ctx context.Context) ([]*client.Cluster, error)
+	GetRepositories(ctx context.Context) ([]*client.Repository, error)
+	GetRepositoryCredentials(ctx context.Context) ([]*client.RepositoryCredential, error)
+	GetSecurityPosture(ctx context.Context) (*client.SecurityPosture, error)
 }
@@ -0,0 +1,96 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// clusterActions are the actions on a cluster that are exposed as entitlements.
+var clusterActions = []policyAction{
+	{"get", "clusters", "get", "view"},
+	{"create", "clusters", "create", "create"},
+	{"update", "clusters", "update", "update"},
+	{"delete", "clusters", "delete", "delete"},
+}
+
+// clusterBuilder implements the ResourceSyncer interface for the clusters Argo CD deploys into.
+type clusterBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for clusters.
+func (c *clusterBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return clusterResourceType
+}
+
+// List returns all clusters as resource objects. A cluster is identified by its RBAC object,
+// its server URL optionally prefixed by its project.
+func (c *clusterBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	inst := c.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	clusters, err := inst.client.GetClusters(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch clusters: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, cluster := range clusters {
+		description := "Server " + cluster.Server
+		if cluster.Project != "" {
+			description += ", project " + cluster.Project
+		}
+		if len(cluster.Namespaces) > 0 {
+			description += ", namespaces " + strings.Join(cluster.Namespaces, ", ")
+		}
+
+		clusterResource, err := resource.NewResource(
+			cluster.Name,
+			clusterResourceType,
+			client.ClusterRBACObject(cluster.Project, cluster.Server),
+			resource.WithDescription(description),
+		)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create cluster resource %s: %w", cluster.Name, err)
+		}
+		resources = append(resources, inst.scope(clusterResource))
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns a permission entitlement for each Argo CD action on the cluster.
+func (c *clusterBuilder) Entitlements(_ context.Context, clusterResource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return policyEntitlements(clusterResource, clusterActions), "", nil, nil
+}
+
+// Grants returns a grant to each role whose policy allows an action on the cluster.
+func (c *clusterBuilder) Grants(ctx context.Context, clusterResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	inst, object, err := c.instances.resolve(clusterResource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	grants, err := policyGrants(ctx, inst, clusterResource, object, clusterActions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return grants, "", nil, nil
+}
+
+// newClusterBuilder creates a new clusterBuilder.
+func newClusterBuilder(instances instances) *clusterBuilder {
+	return &clusterBuilder{
+		resourceType: clusterResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,56 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClusterBuilder tests listing clusters and deriving grants from the policy.
+func TestClusterBuilder(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetClustersFunc: func(ctx context.Context) ([]*client.Cluster, error) {
+			return []*client.Cluster{
+				{Name: "prod", Server: "https://prod.example.com", SecretName: "cluster-prod"},
+				{Name: "team-a-dev", Server: "https://dev.example.com", Project: "team-a", Namespaces: []string{"a", "b"}},
+			}, nil
+		},
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "platform"}, {Name: "team-a"}}, nil, nil
+		},
+		GetPolicyEvaluatorFunc: func(ctx context.Context) (*client.PolicyEvaluator, error) {
+			return client.NewPolicyEvaluator(
+				"p, role:platform, clusters, *, *, allow\np, role:team-a, clusters, get, team-a/*, allow\n", "")
+		},
+	}
+	builder := newClusterBuilder(singleInstance(mockCli))
+
+	resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "https://prod.example.com", resources[0].Id.Resource)
+	assert.Equal(t, "team-a/https://dev.example.com", resources[1].Id.Resource)
+	assert.Equal(t, "Server https://dev.example.com, project team-a, namespaces a, b", resources[1].Description)
+
+	ents, _, _, err := builder.Entitlements(context.Background(), resources[0], &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, ents, len(clusterActions))
+
+	principals := func(i int) map[string]int {
+		grants, _, _, err := builder.Grants(context.Background(), resources[i], &pagination.Token{})
+		require.NoError(t, err)
+		counts := map[string]int{}
+		for _, g := range grants {
+			counts[g.Principal.Id.Resource]++
+		}
+		return counts
+	}
+	assert.Equal(t, map[string]int{"platform": 4}, principals(0))
+	assert.Equal(t, map[string]int{"platform": 4, "team-a": 1}, principals(1))
+}
@@ -2,23 +2,49 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/conductorone/baton-argo-cd/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
+type writtenObject struct{ kind, name string }
+
+// writtenObjects are the objects in the Argo CD namespace provisioning writes to.
+var writtenObjects = []writtenObject{
+	{client.ConfigMapResource, client.RBACConfigMapName},
+	{client.ConfigMapResource, client.ArgoCDConfigMapName},
+	{client.SecretResource, client.ArgoCDSecretName},
+}
+
 type Connector struct {
-	client ArgoCdClient
+	instances instances
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (a *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(a.client),
-		newRoleBuilder(a.client),
+		newUserBuilder(a.instances),
+		newAccountTokenBuilder(a.instances),
+		newGroupBuilder(a.instances),
+		newRoleBuilder(a.instances),
+		newProjectBuilder(a.instances),
+		newProjectRoleBuilder(a.instances),
+		newProjectTokenBuilder(a.instances),
+		newApplicationBuilder(a.instances),
+		newApplicationSetBuilder(a.instances),
+		newClusterBuilder(a.instances),
+		newRepositoryBuilder(a.instances),
+		newRepositoryCredentialBuilder(a.instances),
+		newInstanceBuilder(a.instances),
 	}
 }
 
@@ -28,39 +54,174 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 	return "", nil, nil
 }
 
-// Metadata returns metadata about the connector.
+// Metadata returns metadata about the connector. With several instances, account creation also asks
+// for the instance to create the account in.
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
+	fields := map[string]*v2.ConnectorAccountCreationSchema_Field{
+		"username": {
+			DisplayName: "Username",
+			Required:    true,
+			Description: "The username for the new Argo CD account.",
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+			Placeholder: "alice",
+			Order:       1,
+		},
+	}
+	if d.instances.named() {
+		fields["instance"] = &v2.ConnectorAccountCreationSchema_Field{
+			DisplayName: "Instance",
+			Required:    true,
+			Description: "The Argo CD instance to create the account in.",
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+			Placeholder: d.instances[0].name,
+			Order:       2,
+		}
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Argo CD",
 		Description: "Connector syncs data about accounts, roles, create account and role resources in Argo CD.",
 		AccountCreationSchema: &v2.ConnectorAccountCreationSchema{
-			FieldMap: map[string]*v2.ConnectorAccountCreationSchema_Field{
-				"username": {
-					DisplayName: "Username",
-					Required:    true,
-					Description: "The username for the new Argo CD account.",
-					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
-						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
-					},
-					Placeholder: "alice",
-					Order:       1,
-				},
-			},
+			FieldMap: fields,
 		},
 	}, nil
 }
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
+// It detects whether Argo CD is managed by the Argo CD Operator and reports the GitOps controller managing
+// each object the connector writes, since changes patched into those objects are reverted on its next sync.
+// Each managing Application or Kustomization is returned as a gitopsManagedKey struct annotation.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
-	return nil, nil
+	var annos annotations.Annotations
+	for _, inst := range d.instances {
+		// Validate starts every sync, so nothing read by the previous one is reused.
+		inst.resetCache()
+		owners, err := validateInstance(ctx, inst)
+		if err != nil {
+			if inst.name != "" {
+				return nil, fmt.Errorf("instance %s: %w", inst.name, err)
+			}
+			return nil, err
+		}
+		for _, owner := range owners {
+			annos.Append(gitopsManagedAnnotation(inst, owner))
+		}
+	}
+	return annos, nil
+}
+
+// validateInstance detects the installation type of an instance and returns the GitOps controllers managing
+// the objects the connector writes to it, which it also logs.
+func validateInstance(ctx context.Context, inst *instance) ([]*client.ControllerOwner, error) {
+	l := ctxzap.Extract(ctx)
+	if inst.name != "" {
+		l = l.With(zap.String("instance", inst.name))
+	}
+
+	install, err := inst.client.DetectInstallation(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect Argo CD installation: %w", err)
+	}
+	l.Info("detected Argo CD installation",
+		zap.String("type", string(install.Type)),
+		zap.String("namespace", install.Namespace),
+		zap.String("argocd", install.ArgoCD),
+	)
+
+	if inst.discovered {
+		// Nothing is written to discovered instances.
+		return nil, nil
+	}
+
+	objects := writtenObjects
+	if install.Type == client.InstallOperator {
+		// The operator regenerates the ConfigMaps from the ArgoCD resource, which is written instead.
+		objects = []writtenObject{
+			{client.ArgoCDResource, install.ArgoCD},
+			{client.SecretResource, client.ArgoCDSecretName},
+		}
+	}
+
+	var owners []*client.ControllerOwner
+	for _, obj := range objects {
+		owner := getControllerOwner(ctx, inst.client, obj.kind, obj.name)
+		if owner == nil {
+			continue
+		}
+		l.Warn("object written by the connector is managed by a GitOps controller",
+			zap.String("resource", owner.Object),
+			zap.String("controller", owner.Controller),
+			zap.String("application", owner.Application),
+			zap.String("source", owner.Source),
+		)
+		owners = append(owners, owner)
+	}
+
+	return owners, nil
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, apiUrl string, username string, password string) (*Connector, error) {
-	cli := client.NewClient(ctx, apiUrl, username, password)
+func New(ctx context.Context, apiUrl string, username string, password string, opts ...client.Option) (*Connector, error) {
+	cli := client.NewClient(ctx, apiUrl, username, password, opts...)
+
+	return &Connector{
+		instances: instances{{apiURL: apiUrl, client: cli}},
+	}, nil
+}
+
+// NewWithInstances returns a connector syncing several Argo CD installations, each synced as an instance
+// resource. The options apply to every instance, before the instance's own settings. Each instance keeps
+// its ArgoCD CLI session in its own configuration file. Discovered instances have no CLI session: they are
+// read through kubectl only, with their installation type detected.
+func NewWithInstances(ctx context.Context, configs []*InstanceConfig, opts ...client.Option) (*Connector, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.TempDir()
+	}
+
+	var is instances
+	for _, config := range configs {
+		if config.Discovered {
+			is = append(is, &instance{
+				name: config.Name,
+				client: client.NewClient(ctx, "", "", "", append(slices.Clone(opts),
+					client.WithKubeContext(config.KubeContext),
+					client.WithNamespace(config.Namespace),
+					client.WithInstallType(client.InstallAuto, ""),
+				)...),
+				namespace:  config.Namespace,
+				discovered: true,
+			})
+			continue
+		}
+
+		instanceOpts := append(slices.Clone(opts),
+			client.WithKubeContext(config.KubeContext),
+			client.WithArgoCDConfig(filepath.Join(configDir, "argocd", "baton-argo-cd-"+config.Name)),
+		)
+		if config.Namespace != "" {
+			instanceOpts = append(instanceOpts, client.WithNamespace(config.Namespace))
+		}
+		if config.InstallType != "" {
+			instanceOpts = append(instanceOpts, client.WithInstallType(client.InstallType(config.InstallType), config.ArgoCDName))
+		}
+		if config.GitOps != nil {
+			instanceOpts = append(instanceOpts, client.WithGitOps(config.GitOps.Repository()))
+		}
+
+		is = append(is, &instance{
+			name:   config.Name,
+			apiURL: config.APIURL,
+			client: client.NewClient(ctx, config.APIURL, config.Username, config.Password, instanceOpts...),
+		})
+	}
 
 	return &Connector{
-		client: cli,
+		instances: is,
 	}, nil
 }
@@ -0,0 +1,54 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestConnector_Validate tests that the GitOps controllers managing the objects the connector writes are
+// returned from Validate.
+func TestConnector_Validate(t *testing.T) {
+	managed := &test.MockClient{
+		GetControllerOwnerFunc: func(ctx context.Context, kind string, name string) (*client.ControllerOwner, error) {
+			if name != client.RBACConfigMapName {
+				return nil, nil
+			}
+			return &client.ControllerOwner{
+				Object:      kind + "/" + name,
+				Controller:  client.ControllerArgoCD,
+				Application: "argocd-config",
+				Source:      client.ArgoCDTrackingIDAnnotation,
+			}, nil
+		},
+	}
+	connector := &Connector{instances: instances{
+		{name: "eu-prod", client: managed},
+		{name: "us-dev", client: &test.MockClient{}},
+	}}
+
+	annos, err := connector.Validate(context.Background())
+	require.NoError(t, err)
+	require.Len(t, annos, 1)
+
+	s := &structpb.Struct{}
+	require.NoError(t, annos[0].UnmarshalTo(s))
+	assert.Equal(t, map[string]interface{}{
+		"instance":              "eu-prod",
+		"gitops_controller":     client.ControllerArgoCD,
+		"gitops_application":    "argocd-config",
+		"gitops_managed_object": "cm/argocd-rbac-cm",
+		"gitops_source":         client.ArgoCDTrackingIDAnnotation,
+	}, s.AsMap()[gitopsManagedKey])
+
+	t.Run("unmanaged objects return nothing", func(t *testing.T) {
+		annos, err := (&Connector{instances: singleInstance(&test.MockClient{})}).Validate(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+	})
+}
@@ -0,0 +1,133 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/protobuf/proto"
+)
+
+// groupBuilder implements the ResourceSyncer and ResourceTargetedSyncer interfaces for the SSO groups bound to roles in policy.csv.
+// Their members are only known to the identity provider, so groups mapped to one carry an external match
+// that lets ConductorOne expand their role grants through the identity provider's connector.
+type groupBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for groups.
+func (g *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return groupResourceType
+}
+
+// List returns the SSO groups bound to roles, identified by their subject in policy.csv.
+func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	inst := g.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	groups, err := inst.client.GetGroups(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, group := range groups {
+		groupResource, err := parseGroupResource(group)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create group resource %s: %w", group.Name, err)
+		}
+		resources = append(resources, inst.scope(groupResource))
+	}
+
+	return resources, "", nil, nil
+}
+
+// Get returns a single SSO group, or nil if no role is bound to it anymore.
+func (g *groupBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, _ *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	inst, name, err := g.instances.resolve(resourceID.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups, err := inst.client.GetGroups(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	i := slices.IndexFunc(groups, func(group *client.Group) bool { return group.Name == name })
+	if i < 0 {
+		return nil, nil, nil
+	}
+
+	groupResource, err := parseGroupResource(groups[i])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create group resource %s: %w", name, err)
+	}
+	return inst.scope(groupResource), nil, nil
+}
+
+// Entitlements returns an empty slice, group membership is managed in the identity provider.
+func (g *groupBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty slice, group membership is managed in the identity provider.
+func (g *groupBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// parseGroupResource creates a resource for an SSO group, with its external match when it is mapped.
+func parseGroupResource(group *client.Group) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"name":  group.Name,
+		"roles": strings.Join(group.Roles, ","),
+	}
+	options := []resource.ResourceOption{
+		resource.WithDescription("Bound to " + strings.Join(group.Roles, ", ")),
+	}
+	if match := groupExternalMatch(group); match != nil {
+		profile["external_id"] = group.ExternalID
+		options = append(options, resource.WithAnnotation(match))
+	}
+
+	return resource.NewGroupResource(
+		group.Name,
+		groupResourceType,
+		group.Name,
+		[]resource.GroupTraitOption{resource.WithGroupProfile(profile)},
+		options...,
+	)
+}
+
+// groupExternalMatch returns the annotation matching a mapped group to the identity provider's group, by resource ID
+// or by the mapping's profile field, or nil for unmapped groups.
+func groupExternalMatch(group *client.Group) proto.Message {
+	switch {
+	case group.ExternalID == "":
+		return nil
+	case group.ExternalKey == "":
+		return &v2.ExternalResourceMatchID{Id: group.ExternalID}
+	default:
+		return &v2.ExternalResourceMatch{
+			ResourceType: v2.ResourceType_TRAIT_GROUP,
+			Key:          group.ExternalKey,
+			Value:        group.ExternalID,
+		}
+	}
+}
+
+// newGroupBuilder creates a new groupBuilder.
+func newGroupBuilder(instances instances) *groupBuilder {
+	return &groupBuilder{
+		resourceType: groupResourceType,
+		instances:    instances,
+	}
+}
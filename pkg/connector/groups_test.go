@@ -0,0 +1,79 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroupBuilder tests syncing SSO groups with their external matches and granting them roles.
+func TestGroupBuilder(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetGroupsFunc: func(ctx context.Context) ([]*client.Group, error) {
+			return []*client.Group{
+				{Name: "0f3c2a9e-6d1b-4c1e-9f1a-2b7d4e5c6a8b", Roles: []string{"admin"}, ExternalID: "0f3c2a9e-6d1b-4c1e-9f1a-2b7d4e5c6a8b"},
+				{Name: "my-org:platform", Roles: []string{"admin", "developer"}, ExternalID: "platform", ExternalKey: "name"},
+				{Name: "contractors", Roles: []string{"developer"}},
+			}, nil
+		},
+	}
+
+	resources, _, _, err := newGroupBuilder(singleInstance(mockCli)).List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 3)
+
+	matchID := &v2.ExternalResourceMatchID{}
+	annos := annotations.Annotations(resources[0].Annotations)
+	ok, err := annos.Pick(matchID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "0f3c2a9e-6d1b-4c1e-9f1a-2b7d4e5c6a8b", matchID.Id)
+
+	match := &v2.ExternalResourceMatch{}
+	annos = annotations.Annotations(resources[1].Annotations)
+	ok, err = annos.Pick(match)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, v2.ResourceType_TRAIT_GROUP, match.ResourceType)
+	assert.Equal(t, "name", match.Key)
+	assert.Equal(t, "platform", match.Value)
+	assert.Equal(t, "Bound to admin, developer", resources[1].Description)
+
+	assert.Equal(t, "contractors", resources[2].Id.Resource)
+	assert.False(t, hasAnnotation(resources[2].Annotations, &v2.ExternalResourceMatch{}))
+	assert.False(t, hasAnnotation(resources[2].Annotations, &v2.ExternalResourceMatchID{}))
+
+	t.Run("get", func(t *testing.T) {
+		builder := newGroupBuilder(singleInstance(mockCli))
+		r, _, err := builder.Get(context.Background(), &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "my-org:platform"}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.True(t, hasAnnotation(r.Annotations, &v2.ExternalResourceMatch{}))
+
+		r, _, err = builder.Get(context.Background(), &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "my-org:unbound"}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, r)
+	})
+
+	t.Run("role grants to groups", func(t *testing.T) {
+		role := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "developer"}}
+		grants, _, _, err := newRoleBuilder(singleInstance(mockCli)).Grants(context.Background(), role, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, grants, 2)
+
+		assert.Equal(t, groupResourceType.Id, grants[0].Principal.Id.ResourceType)
+		assert.Equal(t, "my-org:platform", grants[0].Principal.Id.Resource)
+		assert.True(t, hasAnnotation(grants[0].Annotations, &v2.ExternalResourceMatch{}))
+
+		assert.Equal(t, "contractors", grants[1].Principal.Id.Resource)
+		assert.False(t, hasAnnotation(grants[1].Annotations, &v2.ExternalResourceMatch{}))
+		assert.False(t, hasAnnotation(grants[1].Annotations, &v2.ExternalResourceMatchID{}))
+	})
+}
@@ -1,37 +1,92 @@
 package connector
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/conductorone/baton-argo-cd/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
-	"github.com/conductorone/baton-sdk/pkg/crypto"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const PasswordMinLength = 12
 
-// parseAccountResource creates a resource for an account with comprehensive user traits.
-func parseAccountResource(account *client.Account) (*v2.Resource, error) {
-	tokensStr := ""
-	if len(account.Tokens) > 0 {
-		b, err := json.Marshal(account.Tokens)
-		if err == nil {
-			tokensStr = string(b)
+const (
+	managedByConductorOne = "conductorone"
+	managedByManual       = "manual"
+)
+
+// Values of the account_type profile field of users.
+const (
+	accountTypeLocal = "local"
+	accountTypeDex   = "dex"
+)
+
+// Capabilities of local accounts.
+const (
+	apiKeyCapability = "apiKey"
+	loginCapability  = "login"
+)
+
+// parseAccountResource creates a resource for a local account. Accounts that can log in are HUMAN, apiKey-only
+// accounts SERVICE. The email of the account's owner, when known, is its primary email.
+// owner is the GitOps controller managing the accounts, if any.
+func parseAccountResource(account *client.Account, owner *client.ControllerOwner) (*v2.Resource, error) {
+	capabilities := make([]interface{}, 0, len(account.Capabilities))
+	for _, capability := range account.Capabilities {
+		capabilities = append(capabilities, capability)
+	}
+
+	now := time.Now().Unix()
+	expiredTokens := 0
+	for _, token := range account.Tokens {
+		if token.ExpiresAt > 0 && token.ExpiresAt <= now {
+			expiredTokens++
 		}
 	}
 
+	canLogin := slices.Contains(account.Capabilities, loginCapability)
 	profile := map[string]interface{}{
-		"name":         account.Name,
-		"enabled":      account.Enabled,
-		"capabilities": strings.Join(account.Capabilities, ","),
-		"tokens":       tokensStr,
+		"name":                account.Name,
+		"account_type":        accountTypeLocal,
+		"enabled":             account.Enabled,
+		"capabilities":        capabilities,
+		"api_key":             slices.Contains(account.Capabilities, apiKeyCapability),
+		"login":               canLogin,
+		"token_count":         len(account.Tokens),
+		"expired_token_count": expiredTokens,
+	}
+	if account.Email != "" {
+		profile["email"] = account.Email
+	}
+	if !account.PasswordMtime.IsZero() {
+		profile["password_changed_at"] = account.PasswordMtime.Format(time.RFC3339)
+	}
+	addControllerOwner(profile, owner)
+
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	if !account.Enabled {
+		status = v2.UserTrait_Status_STATUS_DISABLED
+	}
+	accountType := v2.UserTrait_ACCOUNT_TYPE_SERVICE
+	if canLogin {
+		accountType = v2.UserTrait_ACCOUNT_TYPE_HUMAN
 	}
 
 	accountTraits := []resource.UserTraitOption{
 		resource.WithUserProfile(profile),
+		resource.WithStatus(status),
+		resource.WithAccountType(accountType),
+		resource.WithUserLogin(account.Name),
+	}
+	if account.Email != "" {
+		accountTraits = append(accountTraits, resource.WithEmail(account.Email, true))
 	}
 
 	return resource.NewUserResource(
@@ -39,27 +94,153 @@ func parseAccountResource(account *client.Account) (*v2.Resource, error) {
 		userResourceType,
 		account.Name,
 		accountTraits,
+		resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: accountTokenResourceType.Id}),
 	)
 }
 
-// generateCredentials generates a random password based on the credential options.
-func generateCredentials(credentialOptions *v2.CredentialOptions) (string, error) {
-	if credentialOptions == nil || credentialOptions.GetRandomPassword() == nil {
-		return "", errors.New("unsupported credential option: only random password is supported")
+// parseDexUserResource creates a resource for a Dex static user. Its ID is the email, which is
+// the subject Argo CD's RBAC binds it by.
+func parseDexUserResource(user *client.DexUser) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"name":         user.Username,
+		"account_type": accountTypeDex,
+		"email":        user.Email,
+		"username":     user.Username,
+		"user_id":      user.UserID,
+		"enabled":      user.Enabled,
+	}
+
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	if !user.Enabled {
+		status = v2.UserTrait_Status_STATUS_DISABLED
+	}
+
+	traits := []resource.UserTraitOption{
+		resource.WithUserProfile(profile),
+		resource.WithEmail(user.Email, true),
+		resource.WithStatus(status),
+	}
+	if user.Username != "" {
+		traits = append(traits, resource.WithUserLogin(user.Username))
 	}
 
-	length := credentialOptions.GetRandomPassword().GetLength()
-	if length < PasswordMinLength {
-		length = PasswordMinLength
+	displayName := user.Username
+	if displayName == "" {
+		displayName = user.Email
 	}
 
-	password, err := crypto.GenerateRandomPassword(
-		&v2.CredentialOptions_RandomPassword{
-			Length: length,
-		},
+	return resource.NewUserResource(
+		displayName,
+		userResourceType,
+		user.Email,
+		traits,
 	)
+}
+
+// generateCredentials generates a random password based on the credential options, matching the instance's
+// passwordPattern so that Argo CD accepts it.
+func generateCredentials(credentialOptions *v2.CredentialOptions, passwordPattern string) (string, error) {
+	if credentialOptions == nil || credentialOptions.GetRandomPassword() == nil {
+		return "", errors.New("unsupported credential option: only random password and no password are supported")
+	}
+
+	policy, err := newPasswordPolicy(passwordPattern)
+	if err != nil {
+		return "", err
+	}
+	length, err := policy.length(credentialOptions.GetRandomPassword().GetLength())
 	if err != nil {
 		return "", err
 	}
-	return password, nil
+	return policy.generate(length)
+}
+
+// newChangeRecord builds the provenance record for a write, taking the request ID from
+// a RequestId or ExternalTicketRef annotation when one is attached to the request's objects.
+func newChangeRecord(grantID string, annos ...annotations.Annotations) *client.ChangeRecord {
+	record := &client.ChangeRecord{
+		GrantID: grantID,
+		Time:    time.Now().UTC(),
+	}
+
+	for _, a := range annos {
+		requestID := &v2.RequestId{}
+		if ok, err := a.Pick(requestID); err == nil && ok && requestID.GetRequestId() != "" {
+			record.RequestID = requestID.GetRequestId()
+			return record
+		}
+		ticketRef := &v2.ExternalTicketRef{}
+		if ok, err := a.Pick(ticketRef); err == nil && ok && ticketRef.GetId() != "" {
+			record.RequestID = ticketRef.GetId()
+			return record
+		}
+	}
+
+	return record
+}
+
+// bindingMetadata describes whether a policy binding is connector-managed or hand-maintained.
+func bindingMetadata(binding *client.PolicyBinding) map[string]interface{} {
+	if binding == nil || !binding.Managed {
+		return map[string]interface{}{"managed_by": managedByManual}
+	}
+
+	metadata := map[string]interface{}{"managed_by": managedByConductorOne}
+	if binding.Change != nil {
+		if binding.Change.GrantID != "" {
+			metadata["grant_id"] = binding.Change.GrantID
+		}
+		if binding.Change.RequestID != "" {
+			metadata["request_id"] = binding.Change.RequestID
+		}
+		if !binding.Change.Time.IsZero() {
+			metadata["changed_at"] = binding.Change.Time.Format(time.RFC3339)
+		}
+	}
+	return metadata
+}
+
+// getControllerOwner returns the GitOps controller managing an object, or nil if there is none.
+// Failures are only logged, since the owner is informational and reading it needs cluster access.
+func getControllerOwner(ctx context.Context, c ArgoCdClient, kind string, name string) *client.ControllerOwner {
+	owner, err := c.GetControllerOwner(ctx, kind, name)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to check whether a GitOps controller manages the object",
+			zap.String("resource", kind+"/"+name),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return owner
+}
+
+// gitopsManagedKey is the key of the struct annotation Validate returns for each object the connector writes
+// that a GitOps controller manages.
+const gitopsManagedKey = "baton_argo_cd_gitops_managed"
+
+// gitopsManagedAnnotation describes the GitOps controller managing an object of an instance, with the fields
+// addControllerOwner records on resources.
+func gitopsManagedAnnotation(inst *instance, owner *client.ControllerOwner) *structpb.Struct {
+	fields := map[string]*structpb.Value{
+		"gitops_controller":     structpb.NewStringValue(owner.Controller),
+		"gitops_application":    structpb.NewStringValue(owner.Application),
+		"gitops_managed_object": structpb.NewStringValue(owner.Object),
+		"gitops_source":         structpb.NewStringValue(owner.Source),
+	}
+	if inst.name != "" {
+		fields["instance"] = structpb.NewStringValue(inst.name)
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		gitopsManagedKey: structpb.NewStructValue(&structpb.Struct{Fields: fields}),
+	}}
+}
+
+// addControllerOwner records the GitOps controller managing a resource's source object in its profile.
+func addControllerOwner(profile map[string]interface{}, owner *client.ControllerOwner) {
+	if owner == nil {
+		return
+	}
+	profile["gitops_controller"] = owner.Controller
+	profile["gitops_application"] = owner.Application
+	profile["gitops_managed_object"] = owner.Object
 }
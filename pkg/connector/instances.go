@@ -0,0 +1,390 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/pkg/gitops"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"gopkg.in/yaml.v3"
+)
+
+// instanceIDSeparator separates the instance name from the ID of a resource within it. Instance names
+// can't contain it, so IDs that do themselves, like cluster server URLs, are split correctly.
+const instanceIDSeparator = ":"
+
+// unnamedInstanceID is the ID of the instance resource of a single-instance connector.
+const unnamedInstanceID = "argocd"
+
+// defaultGitOpsBranch is the branch of a configuration repository changes are committed to when none is set.
+const defaultGitOpsBranch = "main"
+
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// InstanceConfig configures one of the Argo CD installations a multi-instance connector syncs.
+type InstanceConfig struct {
+	Name     string `yaml:"name"`
+	APIURL   string `yaml:"apiUrl"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// PasswordEnv names the environment variable holding the password, to keep it out of the file.
+	PasswordEnv string `yaml:"passwordEnv"`
+	// KubeContext is the kubeconfig context of the cluster the instance runs in, the current one if empty.
+	KubeContext string `yaml:"kubeContext"`
+	Namespace   string `yaml:"namespace"`
+	InstallType string `yaml:"installType"`
+	ArgoCDName  string `yaml:"argocdName"`
+	// GitOps commits changes to the instance's ConfigMaps to their configuration repository, as the gitops-*
+	// flags do for a single instance.
+	GitOps *GitOpsConfig `yaml:"gitops"`
+	// Discovered marks installations found by scanning a cluster rather than configured, which have
+	// no credentials and are synced read-only.
+	Discovered bool `yaml:"-"`
+}
+
+// GitOpsConfig names the Git repository, branch and manifest paths of an instance's argocd-rbac-cm and argocd-cm.
+type GitOpsConfig struct {
+	RepoURL            string `yaml:"repoUrl"`
+	Branch             string `yaml:"branch"`
+	RBACConfigMapPath  string `yaml:"rbacCmPath"`
+	ConfigMapPath      string `yaml:"cmPath"`
+	ReviewBranchPrefix string `yaml:"reviewBranchPrefix"`
+}
+
+// Repository returns the configuration repository, on the main branch unless another is set.
+func (g *GitOpsConfig) Repository() *gitops.Repository {
+	branch := g.Branch
+	if branch == "" {
+		branch = defaultGitOpsBranch
+	}
+	return gitops.NewRepository(g.RepoURL, branch, g.ReviewBranchPrefix, map[string]string{
+		client.RBACConfigMapName:   g.RBACConfigMapPath,
+		client.ArgoCDConfigMapName: g.ConfigMapPath,
+	})
+}
+
+// ReadInstances reads the instances of a multi-instance connector from a YAML file with an `instances` list.
+func ReadInstances(path string) ([]*InstanceConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instances file: %w", err)
+	}
+
+	var file struct {
+		Instances []*InstanceConfig `yaml:"instances"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse instances file %s: %w", path, err)
+	}
+	if len(file.Instances) == 0 {
+		return nil, fmt.Errorf("instances file %s lists no instances", path)
+	}
+
+	seen := map[string]struct{}{}
+	for _, instance := range file.Instances {
+		if !instanceNamePattern.MatchString(instance.Name) {
+			return nil, fmt.Errorf("invalid instance name %q: use lowercase letters, digits and dashes", instance.Name)
+		}
+		if _, ok := seen[instance.Name]; ok {
+			return nil, fmt.Errorf("instance %s is listed more than once", instance.Name)
+		}
+		seen[instance.Name] = struct{}{}
+
+		if instance.PasswordEnv != "" {
+			instance.Password = os.Getenv(instance.PasswordEnv)
+		}
+		if instance.APIURL == "" || instance.Username == "" || instance.Password == "" {
+			return nil, fmt.Errorf("instance %s needs an apiUrl, a username and a password", instance.Name)
+		}
+		switch client.InstallType(instance.InstallType) {
+		case "", client.InstallAuto, client.InstallConfigMap, client.InstallOperator:
+		default:
+			return nil, fmt.Errorf("instance %s has unknown installType %q", instance.Name, instance.InstallType)
+		}
+		if g := instance.GitOps; g != nil && (g.RepoURL == "" || g.RBACConfigMapPath == "" && g.ConfigMapPath == "") {
+			return nil, fmt.Errorf("instance %s needs a gitops repoUrl and an rbacCmPath or cmPath", instance.Name)
+		}
+	}
+	return file.Instances, nil
+}
+
+// DiscoverInstances scans the clusters of the configured instances for `argocd-rbac-cm` and `argocd-cm` ConfigMaps
+// labelled as part of Argo CD and returns the configured instances followed by one for each installation found in
+// another namespace. Discovered instances are named after their namespace, with a numeric suffix if the name is taken.
+func DiscoverInstances(ctx context.Context, configs []*InstanceConfig, opts ...client.Option) ([]*InstanceConfig, error) {
+	found := map[string][]string{}
+	for _, config := range configs {
+		if _, ok := found[config.KubeContext]; ok {
+			continue
+		}
+		cli := client.NewClient(ctx, "", "", "", append(slices.Clone(opts), client.WithKubeContext(config.KubeContext))...)
+		namespaces, err := cli.DiscoverInstallations(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover Argo CD installations in the cluster of instance %s: %w", config.Name, err)
+		}
+		found[config.KubeContext] = namespaces
+	}
+	return addDiscoveredInstances(configs, found), nil
+}
+
+// addDiscoveredInstances appends to the configured instances those found in the namespaces of each kubeconfig
+// context that no configured instance of that context is installed in.
+func addDiscoveredInstances(configs []*InstanceConfig, found map[string][]string) []*InstanceConfig {
+	names := map[string]struct{}{}
+	configured := map[[2]string]struct{}{}
+	for _, config := range configs {
+		names[config.Name] = struct{}{}
+		configured[[2]string{config.KubeContext, instanceNamespace(config)}] = struct{}{}
+	}
+
+	all := slices.Clone(configs)
+	for _, config := range configs {
+		for _, namespace := range found[config.KubeContext] {
+			key := [2]string{config.KubeContext, namespace}
+			if _, ok := configured[key]; ok {
+				continue
+			}
+			configured[key] = struct{}{}
+
+			name := namespace
+			for n := 2; ; n++ {
+				if _, taken := names[name]; !taken {
+					break
+				}
+				name = fmt.Sprintf("%s-%d", namespace, n)
+			}
+			names[name] = struct{}{}
+
+			all = append(all, &InstanceConfig{
+				Name:        name,
+				KubeContext: config.KubeContext,
+				Namespace:   namespace,
+				Discovered:  true,
+			})
+		}
+	}
+	return all
+}
+
+// instanceNamespace returns the namespace an instance is installed in.
+func instanceNamespace(config *InstanceConfig) string {
+	if config.Namespace == "" {
+		return client.ArgocdNamespace
+	}
+	return config.Namespace
+}
+
+// instance is an Argo CD installation synced by the connector.
+type instance struct {
+	name   string
+	apiURL string
+	client ArgoCdClient
+	// namespace is set for discovered instances, which are synced read-only.
+	namespace  string
+	discovered bool
+	cache      syncCache
+}
+
+// instances are the Argo CD installations synced by the connector. A single unnamed instance keeps
+// resources top-level with their plain IDs. Named instances are each synced as an instance resource
+// parenting their users, roles, projects, ApplicationSets, clusters and repositories, and the IDs of
+// their resources are prefixed with `<instance>:`.
+type instances []*instance
+
+// singleInstance returns the instances of a connector syncing one Argo CD installation.
+func singleInstance(c ArgoCdClient) instances {
+	return instances{{client: c}}
+}
+
+// named reports whether the connector syncs named instances.
+func (is instances) named() bool {
+	return len(is) != 1 || is[0].name != ""
+}
+
+// get returns the named instance.
+func (is instances) get(name string) (*instance, error) {
+	for _, i := range is {
+		if i.name == name {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown Argo CD instance %q", name)
+}
+
+// forParent returns the instance whose top-level resources are listed under parentResourceID: the unnamed
+// instance at the top level, or the instance resource's. It returns nil when nothing is listed there.
+func (is instances) forParent(parentResourceID *v2.ResourceId) *instance {
+	if !is.named() {
+		if parentResourceID == nil {
+			return is[0]
+		}
+		return nil
+	}
+	if parentResourceID == nil || parentResourceID.ResourceType != instanceResourceType.Id {
+		return nil
+	}
+	i, err := is.get(parentResourceID.Resource)
+	if err != nil {
+		return nil
+	}
+	return i
+}
+
+// resolve returns the instance a resource ID belongs to and the ID of the resource within it.
+func (is instances) resolve(id string) (*instance, string, error) {
+	if !is.named() {
+		return is[0], id, nil
+	}
+	name, local, ok := strings.Cut(id, instanceIDSeparator)
+	if !ok {
+		return nil, "", fmt.Errorf("resource id %q doesn't name an Argo CD instance", id)
+	}
+	i, err := is.get(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return i, local, nil
+}
+
+// id returns the ID of a resource of the instance from its ID within it.
+func (i *instance) id(local string) string {
+	if i.name == "" {
+		return local
+	}
+	return i.name + instanceIDSeparator + local
+}
+
+// writable returns an error for discovered instances, which have no credentials and aren't provisioned.
+func (i *instance) writable() error {
+	if i.discovered {
+		return fmt.Errorf("instance %s was discovered in namespace %s and is synced read-only", i.name, i.namespace)
+	}
+	return nil
+}
+
+// scope moves a top-level resource, built with its ID within the instance, under the instance resource.
+func (i *instance) scope(r *v2.Resource) *v2.Resource {
+	if i.name == "" {
+		return r
+	}
+	r.Id.Resource = i.id(r.Id.Resource)
+	r.ParentResourceId = &v2.ResourceId{ResourceType: instanceResourceType.Id, Resource: i.name}
+	return r
+}
+
+// instanceBuilder implements the ResourceSyncer interface for the Argo CD installations synced by the connector,
+// with their security posture. Named instances parent everything synced from them.
+type instanceBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for instances.
+func (b *instanceBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return instanceResourceType
+}
+
+// List returns the instances as app resources whose profile records the access-relevant settings of the installation
+// and the risks they pose. Discovered instances are described as such. The instance of a single-instance connector
+// is listed with a fixed ID and doesn't parent its resources, which keep their plain IDs.
+func (b *instanceBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	var resources []*v2.Resource
+	for _, i := range b.instances {
+		posture, err := i.client.GetSecurityPosture(ctx)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to get security posture of instance %s: %w", i.name, err)
+		}
+
+		name, id := i.name, i.name
+		var options []resource.ResourceOption
+		if b.instances.named() {
+			for _, child := range instanceChildResourceTypes {
+				options = append(options, resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: child.Id}))
+			}
+		} else {
+			name, id = "Argo CD", unnamedInstanceID
+		}
+		options = append(options, resource.WithDescription(instanceDescription(i, posture.Risks())))
+
+		instanceResource, err := resource.NewAppResource(name, instanceResourceType, id,
+			[]resource.AppTraitOption{resource.WithAppProfile(postureProfile(posture))}, options...)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create instance resource %s: %w", id, err)
+		}
+		resources = append(resources, instanceResource)
+	}
+	return resources, "", nil, nil
+}
+
+// instanceDescription describes where an instance runs, followed by the risks of its settings.
+func instanceDescription(i *instance, risks []client.Risk) string {
+	description := "Argo CD at " + i.apiURL
+	if i.discovered {
+		description = fmt.Sprintf("Argo CD discovered in namespace %s, synced read-only", i.namespace)
+	}
+	if len(risks) == 0 {
+		return description
+	}
+	descriptions := make([]string, 0, len(risks))
+	for _, risk := range risks {
+		descriptions = append(descriptions, risk.Description)
+	}
+	return description + ". Risks: " + strings.Join(descriptions, "; ")
+}
+
+// postureProfile records the security posture of an instance as profile fields, with `risks` listing the IDs
+// of the risks it poses and `risk_count` their number.
+func postureProfile(posture *client.SecurityPosture) map[string]interface{} {
+	risks := posture.Risks()
+	riskIDs := make([]string, 0, len(risks))
+	for _, risk := range risks {
+		riskIDs = append(riskIDs, risk.ID)
+	}
+
+	return map[string]interface{}{
+		"namespace":         posture.Namespace,
+		"version":           posture.Version,
+		"anonymous_enabled": posture.AnonymousEnabled,
+		"admin_enabled":     posture.AdminEnabled,
+		"session_duration":  posture.SessionDuration,
+		"exec_enabled":      posture.ExecEnabled,
+		"auth_disabled":     posture.AuthDisabled,
+		"default_policy":    posture.DefaultPolicy,
+		"oidc_configured":   posture.OIDCConfigured,
+		"dex_configured":    posture.DexConfigured,
+		"api_key_accounts":  strings.Join(posture.APIKeyAccounts, ","),
+		"login_accounts":    strings.Join(posture.LoginAccounts, ","),
+		"risks":             strings.Join(riskIDs, ","),
+		"risk_count":        len(risks),
+	}
+}
+
+// Entitlements returns an empty slice, access is granted on the instance's resources.
+func (b *instanceBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty slice, access is granted on the instance's resources.
+func (b *instanceBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// newInstanceBuilder creates a new instanceBuilder.
+func newInstanceBuilder(instances instances) *instanceBuilder {
+	return &instanceBuilder{
+		resourceType: instanceResourceType,
+		instances:    instances,
+	}
+}
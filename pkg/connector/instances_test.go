@@ -0,0 +1,277 @@
+package connector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadInstances tests reading and validating an instances file.
+func TestReadInstances(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "instances.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("ARGOCD_EU_PASSWORD", "from-env")
+		instances, err := ReadInstances(write(t, `
+instances:
+  - name: eu-prod
+    apiUrl: argocd.eu.example.com
+    username: admin
+    passwordEnv: ARGOCD_EU_PASSWORD
+    kubeContext: eu-prod
+  - name: us-dev
+    apiUrl: argocd.us.example.com
+    username: admin
+    password: secret
+    namespace: gitops
+    installType: operator
+    gitops:
+      repoUrl: https://git.example.com/platform/argocd.git
+      rbacCmPath: us-dev/argocd-rbac-cm.yaml
+      reviewBranchPrefix: baton/
+`))
+		require.NoError(t, err)
+		require.Len(t, instances, 2)
+		assert.Equal(t, "from-env", instances[0].Password)
+		assert.Equal(t, "eu-prod", instances[0].KubeContext)
+		assert.Nil(t, instances[0].GitOps)
+		assert.Equal(t, "gitops", instances[1].Namespace)
+		assert.Equal(t, "operator", instances[1].InstallType)
+		require.NotNil(t, instances[1].GitOps)
+		repo := instances[1].GitOps.Repository()
+		assert.True(t, repo.Manages(client.RBACConfigMapName))
+		assert.False(t, repo.Manages(client.ArgoCDConfigMapName))
+		assert.True(t, repo.ReviewBranches())
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"empty", "instances: []\n", "lists no instances"},
+		{"invalid name", "instances:\n  - name: EU:prod\n", `invalid instance name "EU:prod"`},
+		{"duplicate name", "instances:\n  - {name: eu, apiUrl: a, username: u, password: p}\n  - {name: eu, apiUrl: b, username: u, password: p}\n",
+			"instance eu is listed more than once"},
+		{"missing password", "instances:\n  - {name: eu, apiUrl: a, username: u, passwordEnv: ARGOCD_UNSET_PASSWORD}\n",
+			"instance eu needs an apiUrl, a username and a password"},
+		{"unknown install type", "instances:\n  - {name: eu, apiUrl: a, username: u, password: p, installType: helm}\n",
+			`instance eu has unknown installType "helm"`},
+		{"gitops without manifest paths", "instances:\n  - {name: eu, apiUrl: a, username: u, password: p, gitops: {repoUrl: r}}\n",
+			"instance eu needs a gitops repoUrl and an rbacCmPath or cmPath"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadInstances(write(t, tt.content))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestInstances tests that resources of named instances are parented by and prefixed with their instance,
+// and that provisioning is routed to the instance the resources belong to.
+func TestInstances(t *testing.T) {
+	newInstanceClient := func(updates *[]string) *test.MockClient {
+		return &test.MockClient{
+			GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+				return []*client.Account{{Name: "alice", Enabled: true}}, nil
+			},
+			GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+				return []*client.Role{{Name: "deployer"}}, nil, nil
+			},
+			GetRoleUsersFunc: func(ctx context.Context, roleID string) ([]*client.Account, error) {
+				return []*client.Account{{Name: "alice"}}, nil
+			},
+			UpdateUserRoleFunc: func(ctx context.Context, userID string, roleID string) (annotations.Annotations, error) {
+				*updates = append(*updates, userID+"/"+roleID)
+				return nil, nil
+			},
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
+				*updates = append(*updates, "create/"+username)
+				return &client.Account{Name: username}, nil, nil
+			},
+		}
+	}
+	var euUpdates, usUpdates []string
+	is := instances{
+		{name: "eu", apiURL: "argocd.eu.example.com", client: newInstanceClient(&euUpdates)},
+		{name: "us", apiURL: "argocd.us.example.com", client: newInstanceClient(&usUpdates)},
+	}
+	c := &Connector{instances: is}
+	ctx := context.Background()
+
+	instanceResources, _, _, err := newInstanceBuilder(is).List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, instanceResources, 2)
+	assert.Equal(t, "eu", instanceResources[0].Id.Resource)
+
+	users := newUserBuilder(is)
+	topLevel, _, _, err := users.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	assert.Empty(t, topLevel)
+
+	usUsers, _, _, err := users.List(ctx, instanceResources[1].Id, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, usUsers, 1)
+	assert.Equal(t, "us:alice", usUsers[0].Id.Resource)
+	assert.Equal(t, "us", usUsers[0].ParentResourceId.Resource)
+
+	roles := newRoleBuilder(is)
+	euRoles, _, _, err := roles.List(ctx, instanceResources[0].Id, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, euRoles, 1)
+	assert.Equal(t, "eu:deployer", euRoles[0].Id.Resource)
+
+	grants, _, _, err := roles.Grants(ctx, euRoles[0], &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, "eu:alice", grants[0].Principal.Id.Resource)
+
+	ents, _, _, err := roles.Entitlements(ctx, euRoles[0], &pagination.Token{})
+	require.NoError(t, err)
+	euAlice := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "eu:alice"}}
+	_, _, err = roles.Grant(ctx, euAlice, ents[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice/deployer"}, euUpdates)
+	assert.Empty(t, usUpdates)
+
+	_, _, err = roles.Grant(ctx, usUsers[0], ents[0])
+	assert.ErrorContains(t, err, "user us:alice and role eu:deployer belong to different Argo CD instances")
+
+	credentialOptions := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 16}},
+	}
+	_, _, _, err = users.CreateAccount(ctx, &v2.AccountInfo{Login: "bob"}, credentialOptions)
+	assert.ErrorContains(t, err, "instance is required")
+
+	resp, _, _, err := users.CreateAccount(ctx, &v2.AccountInfo{
+		Login:   "bob",
+		Profile: createProfile(map[string]interface{}{"instance": "us"}),
+	}, credentialOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "us:bob", resp.(*v2.CreateAccountResponse_SuccessResult).Resource.Id.Resource)
+	assert.Equal(t, []string{"create/bob"}, usUpdates)
+
+	metadata, err := c.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, metadata.AccountCreationSchema.FieldMap, "instance")
+}
+
+// TestAddDiscoveredInstances tests naming the installations discovered next to the configured instances.
+func TestAddDiscoveredInstances(t *testing.T) {
+	configs := []*InstanceConfig{
+		{Name: "argocd", APIURL: "argocd.example.com", Namespace: "argocd"},
+		{Name: "team-a", APIURL: "argocd.team-a.example.com", KubeContext: "staging"},
+	}
+	found := map[string][]string{
+		"":        {"argocd", "team-a", "team-b"},
+		"staging": {"argocd", "team-b"},
+	}
+
+	all := addDiscoveredInstances(configs, found)
+	require.Len(t, all, 5)
+	assert.Equal(t, configs, all[:2])
+	assert.Equal(t, []*InstanceConfig{
+		{Name: "team-a-2", Namespace: "team-a", Discovered: true},
+		{Name: "team-b", Namespace: "team-b", Discovered: true},
+		{Name: "team-b-2", KubeContext: "staging", Namespace: "team-b", Discovered: true},
+	}, all[2:])
+}
+
+// TestDiscoveredInstances tests that discovered instances are synced but never provisioned.
+func TestDiscoveredInstances(t *testing.T) {
+	var updates []string
+	shadow := &test.MockClient{
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "admin"}}, nil, nil
+		},
+		UpdateUserRoleFunc: func(ctx context.Context, userID string, roleID string) (annotations.Annotations, error) {
+			updates = append(updates, userID+"/"+roleID)
+			return nil, nil
+		},
+	}
+	is := instances{
+		{name: "argocd", apiURL: "argocd.example.com", client: &test.MockClient{}},
+		{name: "shadow", client: shadow, namespace: "shadow", discovered: true},
+	}
+	ctx := context.Background()
+
+	instanceResources, _, _, err := newInstanceBuilder(is).List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, instanceResources, 2)
+	assert.Equal(t, "Argo CD discovered in namespace shadow, synced read-only", instanceResources[1].Description)
+
+	roles := newRoleBuilder(is)
+	shadowRoles, _, _, err := roles.List(ctx, instanceResources[1].Id, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, shadowRoles, 1)
+	assert.Equal(t, "shadow:admin", shadowRoles[0].Id.Resource)
+
+	ents, _, _, err := roles.Entitlements(ctx, shadowRoles[0], &pagination.Token{})
+	require.NoError(t, err)
+	alice := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "shadow:alice"}}
+	_, _, err = roles.Grant(ctx, alice, ents[0])
+	assert.ErrorContains(t, err, "instance shadow was discovered in namespace shadow and is synced read-only")
+	assert.Empty(t, updates)
+
+	_, _, _, err = newUserBuilder(is).CreateAccount(ctx, &v2.AccountInfo{
+		Login:   "bob",
+		Profile: createProfile(map[string]interface{}{"instance": "shadow"}),
+	}, &v2.CredentialOptions{})
+	assert.ErrorContains(t, err, "is synced read-only")
+}
+
+// TestInstanceBuilder_SecurityPosture tests syncing the instance of a single-instance connector with its security posture.
+func TestInstanceBuilder_SecurityPosture(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetSecurityPostureFunc: func(ctx context.Context) (*client.SecurityPosture, error) {
+			return &client.SecurityPosture{
+				Namespace:        "argocd",
+				Version:          "v2.13.1",
+				AnonymousEnabled: true,
+				SessionDuration:  "24h",
+				DefaultPolicy:    "role:readonly",
+				OIDCConfigured:   true,
+				APIKeyAccounts:   []string{"ci", "deployer"},
+			}, nil
+		},
+	}
+	is := instances{{apiURL: "argocd.example.com", client: mockCli}}
+
+	resources, _, _, err := newInstanceBuilder(is).List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, unnamedInstanceID, resources[0].Id.Resource)
+	assert.Equal(t, "Argo CD at argocd.example.com. Risks: anonymous users are enabled and get the default policy role:readonly",
+		resources[0].Description)
+
+	appTrait := &v2.AppTrait{}
+	annos := annotations.Annotations(resources[0].Annotations)
+	ok, err := annos.Pick(appTrait)
+	require.NoError(t, err)
+	require.True(t, ok)
+	profile := appTrait.GetProfile().AsMap()
+	assert.Equal(t, "v2.13.1", profile["version"])
+	assert.Equal(t, true, profile["anonymous_enabled"])
+	assert.Equal(t, "ci,deployer", profile["api_key_accounts"])
+	assert.Equal(t, "anonymous-access", profile["risks"])
+	assert.Equal(t, float64(1), profile["risk_count"])
+
+	children, _, _, err := newInstanceBuilder(is).List(context.Background(), resources[0].Id, &pagination.Token{})
+	require.NoError(t, err)
+	assert.Empty(t, children)
+}
@@ -0,0 +1,392 @@
+package connector
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/crypto"
+)
+
+const (
+	// maxPasswordLength is the longest password generated.
+	maxPasswordLength = 256
+	// passwordAttempts is how many passwords each generator gets to produce one matching passwordPattern.
+	passwordAttempts = 20
+	// passwordAlphabet is what the parts of a pattern matching any character are generated from.
+	passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}<>?"
+)
+
+// passwordPolicy is the passwordPattern of an Argo CD instance, which new local account passwords must match.
+type passwordPolicy struct {
+	pattern string
+	re      *regexp.Regexp
+	// tree is the pattern as a whole-string match: unanchored ends accept any text.
+	tree *syntax.Regexp
+	gen  *patternGenerator
+	// lengths are the lengths of the passwords matching the pattern, up to maxPasswordLength.
+	lengths  lengthSet
+	min, max int
+}
+
+// newPasswordPolicy parses a passwordPattern, Argo CD's default if it's empty. Argo CD uses Go's regexp syntax.
+func newPasswordPolicy(pattern string) (*passwordPolicy, error) {
+	if pattern == "" {
+		pattern = client.DefaultPasswordPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passwordPattern %q: %w", pattern, err)
+	}
+	tree, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passwordPattern %q: %w", pattern, err)
+	}
+
+	// Argo CD matches the pattern anywhere in the password, so any text may surround an unanchored pattern.
+	anyText := &syntax.Regexp{Op: syntax.OpStar, Sub: []*syntax.Regexp{{Op: syntax.OpAnyChar}}}
+	parts := []*syntax.Regexp{tree}
+	if first := edgeOp(tree, 0); first != syntax.OpBeginText && first != syntax.OpBeginLine {
+		parts = append([]*syntax.Regexp{anyText}, parts...)
+	}
+	if last := edgeOp(tree, -1); last != syntax.OpEndText && last != syntax.OpEndLine {
+		parts = append(parts, anyText)
+	}
+	if len(parts) > 1 {
+		tree = &syntax.Regexp{Op: syntax.OpConcat, Sub: parts}
+	}
+
+	policy := &passwordPolicy{pattern: pattern, re: re, tree: tree, gen: newPatternGenerator()}
+	policy.lengths = policy.gen.matchLengths(tree)
+	policy.min, policy.max = -1, -1
+	for n, ok := range policy.lengths {
+		if ok {
+			if policy.min < 0 {
+				policy.min = n
+			}
+			policy.max = n
+		}
+	}
+	if policy.min < 0 {
+		return nil, fmt.Errorf("passwordPattern %q matches no password of up to %d characters", pattern, maxPasswordLength)
+	}
+	return policy, nil
+}
+
+// length returns the length of a password to generate: the shortest one the pattern allows of at least the requested
+// length and PasswordMinLength. It fails when the requested length exceeds the pattern's maximum, to which
+// PasswordMinLength is otherwise lowered.
+func (p *passwordPolicy) length(requested int64) (int, error) {
+	if requested > int64(p.max) {
+		return 0, fmt.Errorf("requested password length %d exceeds the maximum of %d allowed by passwordPattern %q", requested, p.max, p.pattern)
+	}
+	for n := max(int(requested), PasswordMinLength); n <= p.max; n++ {
+		if p.lengths[n] {
+			return n, nil
+		}
+	}
+	return p.max, nil
+}
+
+// generate returns a random password of the given length matching the pattern. Passwords from the SDK's generator,
+// which mixes character classes, are preferred; patterns restricting characters or their positions are generated
+// from instead.
+func (p *passwordPolicy) generate(length int) (string, error) {
+	if length >= 8 {
+		for range passwordAttempts {
+			password, err := crypto.GenerateRandomPassword(&v2.CredentialOptions_RandomPassword{Length: int64(length)})
+			if err != nil {
+				return "", err
+			}
+			if p.re.MatchString(password) {
+				return password, nil
+			}
+		}
+	}
+
+	for range passwordAttempts {
+		var b strings.Builder
+		if err := p.gen.generate(&b, p.tree, length); err != nil {
+			return "", fmt.Errorf("failed to generate a %d character password matching passwordPattern %q: %w", length, p.pattern, err)
+		}
+		// Word boundaries aren't taken into account when generating, so the result is checked.
+		if p.re.MatchString(b.String()) {
+			return b.String(), nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a %d character password matching passwordPattern %q", length, p.pattern)
+}
+
+// edgeOp returns the operator at the start, for index 0, or the end, for index -1, of a pattern.
+func edgeOp(re *syntax.Regexp, index int) syntax.Op {
+	for (re.Op == syntax.OpConcat || re.Op == syntax.OpCapture) && len(re.Sub) > 0 {
+		if index < 0 {
+			re = re.Sub[len(re.Sub)-1]
+		} else {
+			re = re.Sub[0]
+		}
+	}
+	return re.Op
+}
+
+// lengthSet reports, for each length up to maxPasswordLength, whether a pattern matches a string of that length.
+type lengthSet []bool
+
+func newLengthSet(lengths ...int) lengthSet {
+	s := make(lengthSet, maxPasswordLength+1)
+	for _, n := range lengths {
+		s[n] = true
+	}
+	return s
+}
+
+// concat returns the lengths of the strings made of one of s followed by one of t.
+func (s lengthSet) concat(t lengthSet) lengthSet {
+	var tLengths []int
+	for j, ok := range t {
+		if ok {
+			tLengths = append(tLengths, j)
+		}
+	}
+
+	c := newLengthSet()
+	for i, ok := range s {
+		if !ok {
+			continue
+		}
+		for _, j := range tLengths {
+			if i+j > maxPasswordLength {
+				break
+			}
+			c[i+j] = true
+		}
+	}
+	return c
+}
+
+// patternGenerator generates random strings matching a pattern, remembering the lengths each part of it matches.
+type patternGenerator struct {
+	lengths     map[*syntax.Regexp]lengthSet
+	repeatCount map[*syntax.Regexp]map[int]lengthSet
+}
+
+func newPatternGenerator() *patternGenerator {
+	return &patternGenerator{
+		lengths:     map[*syntax.Regexp]lengthSet{},
+		repeatCount: map[*syntax.Regexp]map[int]lengthSet{},
+	}
+}
+
+// matchLengths returns the lengths of the strings a part of a pattern matches.
+func (g *patternGenerator) matchLengths(re *syntax.Regexp) lengthSet {
+	if s, ok := g.lengths[re]; ok {
+		return s
+	}
+
+	var s lengthSet
+	switch re.Op {
+	case syntax.OpNoMatch:
+		s = newLengthSet()
+	case syntax.OpLiteral:
+		s = newLengthSet()
+		if len(re.Rune) <= maxPasswordLength {
+			s[len(re.Rune)] = true
+		}
+	case syntax.OpCharClass, syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		s = newLengthSet(1)
+	case syntax.OpCapture:
+		s = g.matchLengths(re.Sub[0])
+	case syntax.OpConcat:
+		s = newLengthSet(0)
+		for _, sub := range re.Sub {
+			s = s.concat(g.matchLengths(sub))
+		}
+	case syntax.OpAlternate:
+		s = newLengthSet()
+		for _, sub := range re.Sub {
+			for n, ok := range g.matchLengths(sub) {
+				s[n] = s[n] || ok
+			}
+		}
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		s = newLengthSet()
+		for _, repeated := range g.repetitions(re) {
+			for n, ok := range repeated {
+				s[n] = s[n] || ok
+			}
+		}
+	default:
+		// Empty matches, anchors and word boundaries.
+		s = newLengthSet(0)
+	}
+	g.lengths[re] = s
+	return s
+}
+
+// repetitions returns, for each number of times a repetition may repeat its sub-pattern, the lengths of the strings
+// it then matches. Counts whose strings are all longer than maxPasswordLength are left out.
+func (g *patternGenerator) repetitions(re *syntax.Regexp) map[int]lengthSet {
+	if repeated, ok := g.repeatCount[re]; ok {
+		return repeated
+	}
+
+	minCount, maxCount := re.Min, re.Max
+	switch re.Op {
+	case syntax.OpStar:
+		minCount, maxCount = 0, -1
+	case syntax.OpPlus:
+		minCount, maxCount = 1, -1
+	case syntax.OpQuest:
+		minCount, maxCount = 0, 1
+	}
+	if maxCount < 0 {
+		maxCount = max(minCount, maxPasswordLength)
+	}
+
+	sub := g.matchLengths(re.Sub[0])
+	repeated := map[int]lengthSet{}
+	s := newLengthSet(0)
+	for count := 0; count <= maxCount; count++ {
+		if count > 0 {
+			s = s.concat(sub)
+		}
+		if !slices.Contains(s, true) {
+			// Every further repetition is longer than maxPasswordLength.
+			break
+		}
+		if count >= minCount {
+			repeated[count] = s
+		}
+	}
+	g.repeatCount[re] = repeated
+	return repeated
+}
+
+// generate writes a random string of length n matching a part of a pattern.
+func (g *patternGenerator) generate(b *strings.Builder, re *syntax.Regexp, n int) error {
+	if !g.matchLengths(re)[n] {
+		return fmt.Errorf("no match of length %d", n)
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+		return nil
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		i, err := randomInt(len(passwordAlphabet))
+		if err != nil {
+			return err
+		}
+		b.WriteByte(passwordAlphabet[i])
+		return nil
+	case syntax.OpCharClass:
+		r, err := randomClassRune(re.Rune)
+		if err != nil {
+			return err
+		}
+		b.WriteRune(r)
+		return nil
+	case syntax.OpCapture:
+		return g.generate(b, re.Sub[0], n)
+	case syntax.OpConcat:
+		return g.generateConcat(b, re.Sub, n)
+	case syntax.OpAlternate:
+		var candidates []*syntax.Regexp
+		for _, sub := range re.Sub {
+			if g.matchLengths(sub)[n] {
+				candidates = append(candidates, sub)
+			}
+		}
+		i, err := randomInt(len(candidates))
+		if err != nil {
+			return err
+		}
+		return g.generate(b, candidates[i], n)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		var counts []int
+		for count, lengths := range g.repetitions(re) {
+			if lengths[n] {
+				counts = append(counts, count)
+			}
+		}
+		i, err := randomInt(len(counts))
+		if err != nil {
+			return err
+		}
+		subs := make([]*syntax.Regexp, counts[i])
+		for j := range subs {
+			subs[j] = re.Sub[0]
+		}
+		return g.generateConcat(b, subs, n)
+	default:
+		return nil
+	}
+}
+
+// generateConcat writes random strings matching each of the parts, whose lengths add up to n.
+func (g *patternGenerator) generateConcat(b *strings.Builder, parts []*syntax.Regexp, n int) error {
+	// rest[i] are the lengths the parts from i on can add up to.
+	rest := make([]lengthSet, len(parts)+1)
+	rest[len(parts)] = newLengthSet(0)
+	for i := len(parts) - 1; i >= 0; i-- {
+		rest[i] = g.matchLengths(parts[i]).concat(rest[i+1])
+	}
+
+	for i, part := range parts {
+		var candidates []int
+		for length, ok := range g.matchLengths(part) {
+			if ok && length <= n && rest[i+1][n-length] {
+				candidates = append(candidates, length)
+			}
+		}
+		c, err := randomInt(len(candidates))
+		if err != nil {
+			return err
+		}
+		if err := g.generate(b, part, candidates[c]); err != nil {
+			return err
+		}
+		n -= candidates[c]
+	}
+	return nil
+}
+
+// randomClassRune returns a random rune of a character class, given as pairs of inclusive ranges,
+// preferring printable ASCII characters.
+func randomClassRune(ranges []rune) (rune, error) {
+	var printable []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := max(ranges[i], '!'); r <= min(ranges[i+1], '~'); r++ {
+			printable = append(printable, r)
+		}
+	}
+	if len(printable) == 0 {
+		if len(ranges) < 2 {
+			return 0, errors.New("empty character class")
+		}
+		return ranges[0], nil
+	}
+	i, err := randomInt(len(printable))
+	if err != nil {
+		return 0, err
+	}
+	return printable[i], nil
+}
+
+func randomInt(n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("nothing to choose from")
+	}
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,91 @@
+package connector
+
+import (
+	"regexp"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateCredentials tests generating passwords that match the instance's passwordPattern.
+func TestGenerateCredentials(t *testing.T) {
+	randomPassword := func(length int64) *v2.CredentialOptions {
+		return &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_RandomPassword_{
+				RandomPassword: &v2.CredentialOptions_RandomPassword{Length: length},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		pattern    string
+		length     int64
+		wantLength int
+	}{
+		{"default pattern", "", 16, 16},
+		{"raised to the minimum length", "", 0, PasswordMinLength},
+		{"raised to the pattern's minimum", "^.{20,64}$", 16, 20},
+		{"minimum length lowered to the pattern's maximum", "^.{8,10}$", 0, 10},
+		{"restricted characters", "^[a-z0-9]{12,40}$", 24, 24},
+		{"required character positions", "^[A-Z][a-z]+[0-9]{2}$", 16, 16},
+		{"required character classes", "^(.*[A-Z].*[0-9].*|.*[0-9].*[A-Z].*)$", 14, 14},
+		{"unanchored pattern", "[0-9]{3}", 16, 16},
+		{"alternatives", "^(pin-[0-9]{6}|[a-f0-9]{32})$", 0, 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := tt.pattern
+			if pattern == "" {
+				pattern = "^.{8,32}$"
+			}
+			re := regexp.MustCompile(pattern)
+			for range 20 {
+				password, err := generateCredentials(randomPassword(tt.length), tt.pattern)
+				require.NoError(t, err)
+				assert.Len(t, password, tt.wantLength)
+				assert.Regexp(t, re, password)
+			}
+		})
+	}
+
+	t.Run("requested length above the pattern's maximum", func(t *testing.T) {
+		_, err := generateCredentials(randomPassword(40), "")
+		assert.EqualError(t, err, `requested password length 40 exceeds the maximum of 32 allowed by passwordPattern "^.{8,32}$"`)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := generateCredentials(randomPassword(16), "^[a-z$")
+		assert.ErrorContains(t, err, `invalid passwordPattern "^[a-z$"`)
+	})
+
+	t.Run("unsupported credential option", func(t *testing.T) {
+		_, err := generateCredentials(&v2.CredentialOptions{}, "")
+		assert.ErrorContains(t, err, "unsupported credential option")
+	})
+}
+
+// TestPasswordPolicyLengths tests computing the shortest and longest passwords a pattern allows.
+func TestPasswordPolicyLengths(t *testing.T) {
+	tests := []struct {
+		pattern string
+		min     int
+		max     int
+	}{
+		{"^.{8,32}$", 8, 32},
+		{"^[a-z]+$", 1, maxPasswordLength},
+		{"^(ab|cde)?x{2}$", 2, 5},
+		{"^a{3,}$", 3, maxPasswordLength},
+		{"[0-9]", 1, maxPasswordLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			policy, err := newPasswordPolicy(tt.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tt.min, policy.min)
+			assert.Equal(t, tt.max, policy.max)
+		})
+	}
+}
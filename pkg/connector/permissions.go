@@ -0,0 +1,148 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// permissionsProfileField is the role profile field holding the permission template of a role to create.
+const permissionsProfileField = "permissions"
+
+// rbacActions are the actions Argo CD's RBAC defines for each resource.
+var rbacActions = map[string][]string{
+	"applications":       {"get", "create", "update", "delete", "sync", "override", "action"},
+	"applicationsets":    {"get", "create", "update", "delete"},
+	"clusters":           {"get", "create", "update", "delete"},
+	"projects":           {"get", "create", "update", "delete"},
+	"repositories":       {"get", "create", "update", "delete"},
+	"write-repositories": {"get", "create", "update", "delete"},
+	"certificates":       {"get", "create", "update", "delete"},
+	"gpgkeys":            {"get", "create", "delete"},
+	"accounts":           {"get", "update"},
+	"logs":               {"get"},
+	"exec":               {"create"},
+	"extensions":         {"invoke"},
+}
+
+// applicationSubActions are the application actions that take a sub-resource, as in `action/<group>/<kind>/<action>`
+// or `update/<group>/<kind>/<namespace>/<name>`.
+var applicationSubActions = []string{"action", "update", "delete"}
+
+// projectScopedResources are the resources whose objects are `<project>/<name>`.
+var projectScopedResources = []string{"applications", "applicationsets", "logs", "exec"}
+
+// parsePermissionTemplate returns the permissions of a role to create from its profile's `permissions` list.
+// Each entry is either a struct with `resource`, `action`, `object` and `effect` fields, or a string in the
+// `resource, action, object, effect` form of a policy.csv line. The effect defaults to allow.
+func parsePermissionTemplate(profile *structpb.Struct) ([]*client.PolicyDefinition, error) {
+	value, ok := profile.GetFields()[permissionsProfileField]
+	if !ok {
+		return nil, fmt.Errorf("a %s list is required", permissionsProfileField)
+	}
+	list := value.GetListValue()
+	if list == nil {
+		return nil, fmt.Errorf("%s must be a list", permissionsProfileField)
+	}
+
+	policies := make([]*client.PolicyDefinition, 0, len(list.GetValues()))
+	for i, entry := range list.GetValues() {
+		var policy *client.PolicyDefinition
+		switch kind := entry.GetKind().(type) {
+		case *structpb.Value_StringValue:
+			policy = parsePermissionString(kind.StringValue)
+		case *structpb.Value_StructValue:
+			fields := kind.StructValue.GetFields()
+			policy = &client.PolicyDefinition{
+				Resource: strings.TrimSpace(fields["resource"].GetStringValue()),
+				Action:   strings.TrimSpace(fields["action"].GetStringValue()),
+				Object:   strings.TrimSpace(fields["object"].GetStringValue()),
+				Effect:   strings.TrimSpace(fields["effect"].GetStringValue()),
+			}
+		default:
+			return nil, fmt.Errorf("%s[%d] must be a string or a struct", permissionsProfileField, i)
+		}
+		if err := validatePermission(policy); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", permissionsProfileField, i, err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// parsePermissionString parses a permission in the `resource, action, object[, effect]` form.
+func parsePermissionString(s string) *client.PolicyDefinition {
+	fields := strings.Split(s, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	fields = append(fields, make([]string, max(0, 4-len(fields)))...)
+	if len(fields) > 4 {
+		// Leave the extra fields in the effect so validation rejects them.
+		fields[3] = strings.Join(fields[3:], ",")
+	}
+	return &client.PolicyDefinition{Resource: fields[0], Action: fields[1], Object: fields[2], Effect: fields[3]}
+}
+
+// validatePermission checks a permission against the resources and actions Argo CD's RBAC knows, and
+// defaults its effect to allow. Objects of project-scoped resources must name a project, as in
+// `<project>/<application>`, unless they match everything.
+func validatePermission(policy *client.PolicyDefinition) error {
+	if policy.Effect == "" {
+		policy.Effect = client.PolicyEffectAllow
+	}
+	switch {
+	case policy.Resource == "":
+		return fmt.Errorf("resource is required")
+	case policy.Action == "":
+		return fmt.Errorf("action is required")
+	case policy.Object == "":
+		return fmt.Errorf("object is required")
+	case policy.Effect != client.PolicyEffectAllow && policy.Effect != client.PolicyEffectDeny:
+		return fmt.Errorf("invalid effect %q: must be %s or %s", policy.Effect, client.PolicyEffectAllow, client.PolicyEffectDeny)
+	case strings.ContainsAny(policy.Resource+policy.Action+policy.Object, ",\"\r\n"):
+		return fmt.Errorf("resource, action and object can't contain commas, quotes or line breaks")
+	}
+
+	if policy.Resource == "*" {
+		return nil
+	}
+	actions, ok := rbacActions[policy.Resource]
+	if !ok {
+		return fmt.Errorf("unknown resource %q: must be one of %s", policy.Resource, strings.Join(rbacResources(), ", "))
+	}
+	if !validRBACAction(policy.Resource, policy.Action, actions) {
+		return fmt.Errorf("invalid action %q for %s: must be one of %s", policy.Action, policy.Resource, strings.Join(actions, ", "))
+	}
+	if slices.Contains(projectScopedResources, policy.Resource) && policy.Object != "*" && !strings.Contains(policy.Object, "/") {
+		return fmt.Errorf("invalid object %q for %s: must be <project>/<name>, such as %s/*", policy.Object, policy.Resource, policy.Object)
+	}
+	return nil
+}
+
+// validRBACAction reports whether an action is valid for a resource: one of its actions, `*`, or for
+// applications, a sub-action such as `action/apps/Deployment/restart` or `update/*`.
+func validRBACAction(resource string, action string, actions []string) bool {
+	if action == "*" || slices.Contains(actions, action) {
+		return true
+	}
+	if resource != "applications" {
+		return false
+	}
+	prefix, _, ok := strings.Cut(action, "/")
+	return ok && slices.Contains(applicationSubActions, prefix)
+}
+
+// rbacResources returns the resources of Argo CD's RBAC, sorted.
+func rbacResources() []string {
+	resources := make([]string, 0, len(rbacActions))
+	for resource := range rbacActions {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	return resources
+}
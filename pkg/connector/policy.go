@@ -0,0 +1,61 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// policyAction is an Argo CD RBAC action on a resource, exposed as a permission entitlement.
+type policyAction struct {
+	slug        string
+	resource    string
+	action      string
+	description string
+}
+
+// policyEntitlements returns a permission entitlement for each action, grantable to roles and users.
+func policyEntitlements(res *v2.Resource, actions []policyAction) []*v2.Entitlement {
+	entitlements := make([]*v2.Entitlement, 0, len(actions))
+	for _, action := range actions {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			res,
+			action.slug,
+			entitlement.WithGrantableTo(roleResourceType, userResourceType),
+			entitlement.WithDisplayName(fmt.Sprintf("%s %s %s", res.DisplayName, res.Id.ResourceType, action.slug)),
+			entitlement.WithDescription(fmt.Sprintf("Can %s %s %s", action.description, res.Id.ResourceType, res.DisplayName)),
+		))
+	}
+	return entitlements
+}
+
+// policyGrants evaluates the RBAC policy of an instance for every role and action against an object and returns
+// a grant for each allowed combination, expandable to the members of the role. The policy is loaded once per sync.
+func policyGrants(ctx context.Context, inst *instance, res *v2.Resource, object string, actions []policyAction) ([]*v2.Grant, error) {
+	evaluator, roles, err := inst.rbacPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []*v2.Grant
+	for _, role := range roles {
+		roleResource := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: inst.id(role.Name)}}
+		for _, action := range actions {
+			if !evaluator.RoleAllowed(role.Name, action.resource, action.action, object) {
+				continue
+			}
+			grants = append(grants, grant.NewGrant(
+				res,
+				action.slug,
+				roleResource.Id,
+				grant.WithAnnotation(&v2.GrantExpandable{
+					EntitlementIds: []string{entitlement.NewEntitlementID(roleResource, assignedEntitlement)},
+				}),
+			))
+		}
+	}
+	return grants, nil
+}
@@ -0,0 +1,98 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// projectRoleBuilder implements the ResourceSyncer interface for the roles defined in AppProjects,
+// the parents of project role tokens.
+type projectRoleBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for project roles.
+func (p *projectRoleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return projectRoleResourceType
+}
+
+// List returns the roles of a project, identified by `<project>/<role>`.
+func (p *projectRoleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	inst, project, err := p.instances.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	roles, err := findProjectRoles(ctx, inst.client, project)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var resources []*v2.Resource
+	for _, role := range roles {
+		description := role.Description
+		if len(role.Groups) > 0 {
+			if description != "" {
+				description += ", "
+			}
+			description += "groups " + strings.Join(role.Groups, ", ")
+		}
+		roleResource, err := resource.NewResource(
+			role.Name,
+			projectRoleResourceType,
+			inst.id(role.Project+"/"+role.Name),
+			resource.WithParentResourceID(parentResourceID),
+			resource.WithDescription(description),
+			resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: projectTokenResourceType.Id}),
+		)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create project role resource %s: %w", role.Name, err)
+		}
+		resources = append(resources, roleResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns an empty slice, project roles are synced as the parents of their tokens.
+func (p *projectRoleBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty slice, project roles are synced as the parents of their tokens.
+func (p *projectRoleBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// findProjectRoles returns the roles of the named project.
+func findProjectRoles(ctx context.Context, c ArgoCdClient, projectName string) ([]*client.ProjectRole, error) {
+	projects, err := c.GetProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch projects: %w", err)
+	}
+	for _, project := range projects {
+		if project.Name == projectName {
+			return project.Roles, nil
+		}
+	}
+	return nil, nil
+}
+
+// newProjectRoleBuilder creates a new projectRoleBuilder.
+func newProjectRoleBuilder(instances instances) *projectRoleBuilder {
+	return &projectRoleBuilder{
+		resourceType: projectRoleResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,100 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// projectBuilder implements the ResourceSyncer and ResourceTargetedSyncer interfaces for Argo CD projects, the parents of applications
+// and project roles.
+type projectBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for projects.
+func (p *projectBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return projectResourceType
+}
+
+// List returns all AppProjects as resource objects.
+func (p *projectBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	inst := p.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	projects, err := inst.client.GetProjects(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch projects: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, project := range projects {
+		projectResource, err := parseProjectResource(project)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create project resource %s: %w", project.Name, err)
+		}
+		resources = append(resources, inst.scope(projectResource))
+	}
+
+	return resources, "", nil, nil
+}
+
+// Get returns a single AppProject, or nil if there is no such project.
+func (p *projectBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, _ *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	inst, name, err := p.instances.resolve(resourceID.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project, err := inst.client.GetProject(ctx, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch project %s: %w", name, err)
+	}
+	if project == nil {
+		return nil, nil, nil
+	}
+
+	projectResource, err := parseProjectResource(project)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create project resource %s: %w", name, err)
+	}
+	return inst.scope(projectResource), nil, nil
+}
+
+// parseProjectResource creates a resource for an AppProject, parenting its applications and roles.
+func parseProjectResource(project *client.Project) (*v2.Resource, error) {
+	return resource.NewResource(
+		project.Name,
+		projectResourceType,
+		project.Name,
+		resource.WithDescription(project.Description),
+		resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: applicationResourceType.Id}),
+		resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: projectRoleResourceType.Id}),
+	)
+}
+
+// Entitlements returns an empty slice, access is granted on the project's applications.
+func (p *projectBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty slice, access is granted on the project's applications.
+func (p *projectBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// newProjectBuilder creates a new projectBuilder.
+func newProjectBuilder(instances instances) *projectBuilder {
+	return &projectBuilder{
+		resourceType: projectResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,215 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// projectTokenBuilder implements the ResourceSyncer, ResourceDeleter and CredentialManager interfaces for the
+// JWT tokens of project roles.
+type projectTokenBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for project role tokens.
+func (p *projectTokenBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return projectTokenResourceType
+}
+
+// List returns the tokens of a project role, identified by `<project>/<role>/<issued at>`.
+func (p *projectTokenBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+	inst, parentID, err := p.instances.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	projectName, roleName, _ := strings.Cut(parentID, "/")
+
+	roles, err := findProjectRoles(ctx, inst.client, projectName)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var resources []*v2.Resource
+	for _, role := range roles {
+		if role.Name != roleName {
+			continue
+		}
+		for _, token := range role.Tokens {
+			issuedAt := time.Unix(token.IssuedAt, 0).UTC()
+			options := []resource.SecretTraitOption{
+				resource.WithSecretCreatedAt(issuedAt),
+				resource.WithSecretIdentityID(parentResourceID),
+			}
+			description := "Issued " + issuedAt.Format(time.RFC3339)
+			if token.ExpiresAt > 0 {
+				expiresAt := time.Unix(token.ExpiresAt, 0).UTC()
+				options = append(options, resource.WithSecretExpiresAt(expiresAt))
+				description += ", expires " + expiresAt.Format(time.RFC3339)
+			} else {
+				description += ", never expires"
+			}
+
+			name := token.ID
+			if name == "" {
+				name = strconv.FormatInt(token.IssuedAt, 10)
+			}
+			tokenResource, err := resource.NewSecretResource(
+				name,
+				projectTokenResourceType,
+				inst.id(parentID+"/"+strconv.FormatInt(token.IssuedAt, 10)),
+				options,
+				resource.WithParentResourceID(parentResourceID),
+				resource.WithDescription(description),
+			)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("failed to create project role token resource %s: %w", name, err)
+			}
+			resources = append(resources, tokenResource)
+		}
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns an empty slice, tokens carry the access of their project role.
+func (p *projectTokenBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty slice, tokens carry the access of their project role.
+func (p *projectTokenBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Delete revokes a project role token.
+func (p *projectTokenBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	inst, project, role, issuedAt, err := p.resolveToken(resourceId)
+	if err != nil {
+		return nil, err
+	}
+	annos, err := inst.client.DeleteProjectToken(ctx, project, role, issuedAt)
+	if err != nil {
+		return annos, fmt.Errorf("failed to revoke project role token: %w", err)
+	}
+	return annos, nil
+}
+
+// RotateCapabilityDetails declares support for issuing tokens, which Argo CD generates itself.
+func (p *projectTokenBuilder) RotateCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+// Rotate issues a new token for the role of an existing one, with the same lifetime, returns it, and then revokes
+// the existing token. Argo CD refuses an ID a role's token already uses, so the new token's ID is the existing one
+// with the issue time as suffix, replacing that of a previous rotation. A failure to revoke the existing token is
+// only logged, since the new token can't be returned again.
+func (p *projectTokenBuilder) Rotate(ctx context.Context, resourceId *v2.ResourceId, _ *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	inst, projectName, roleName, issuedAt, err := p.resolveToken(resourceId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles, err := findProjectRoles(ctx, inst.client, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var expiresIn time.Duration
+	var id string
+	found := false
+	for _, role := range roles {
+		if role.Name != roleName {
+			continue
+		}
+		for _, token := range role.Tokens {
+			if token.IssuedAt != issuedAt {
+				continue
+			}
+			found = true
+			id = token.ID
+			if token.ExpiresAt > 0 {
+				expiresIn = time.Duration(token.ExpiresAt-token.IssuedAt) * time.Second
+			}
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("token %s not found", resourceId.Resource)
+	}
+
+	token, annos, err := inst.client.CreateProjectToken(ctx, projectName, roleName, expiresIn, rotatedTokenID(id, time.Now()))
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to issue project role token: %w", err)
+	}
+	if client.GetNotApplied(annos) != nil {
+		return nil, annos, nil
+	}
+
+	if _, err := inst.client.DeleteProjectToken(ctx, projectName, roleName, issuedAt); err != nil {
+		ctxzap.Extract(ctx).Warn("issued a new project role token but failed to revoke the one it replaces",
+			zap.String("token", resourceId.Resource),
+			zap.Error(err),
+		)
+	}
+	return []*v2.PlaintextData{{Name: "token", Bytes: []byte(token)}}, nil, nil
+}
+
+// rotationSuffix matches the issue time rotatedTokenID appends to a token ID.
+var rotationSuffix = regexp.MustCompile(`-[0-9]{10,}$`)
+
+// rotatedTokenID returns the ID of a token replacing one with the given ID: the ID without the suffix of a previous
+// rotation, followed by the Unix issue time. Tokens without an ID are replaced by tokens without one.
+func rotatedTokenID(id string, now time.Time) string {
+	if id == "" {
+		return ""
+	}
+	return rotationSuffix.ReplaceAllString(id, "") + "-" + strconv.FormatInt(now.Unix(), 10)
+}
+
+// resolveToken splits a token resource ID into its instance, project, role and issue time.
+// Tokens of discovered instances can't be revoked or rotated.
+func (p *projectTokenBuilder) resolveToken(resourceId *v2.ResourceId) (*instance, string, string, int64, error) {
+	inst, id, err := p.instances.resolve(resourceId.Resource)
+	if err != nil {
+		return nil, "", "", 0, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, "", "", 0, err
+	}
+	parts := strings.Split(id, "/")
+	if len(parts) != 3 {
+		return nil, "", "", 0, fmt.Errorf("invalid project role token id %q", resourceId.Resource)
+	}
+	issuedAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, "", "", 0, fmt.Errorf("invalid project role token id %q: %w", resourceId.Resource, err)
+	}
+	return inst, parts[0], parts[1], issuedAt, nil
+}
+
+// newProjectTokenBuilder creates a new projectTokenBuilder.
+func newProjectTokenBuilder(instances instances) *projectTokenBuilder {
+	return &projectTokenBuilder{
+		resourceType: projectTokenResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,106 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProjectTokenBuilder tests listing project role tokens as secrets, revoking them and issuing new ones.
+func TestProjectTokenBuilder(t *testing.T) {
+	var deleted []int64
+	var issued []string
+	mockCli := &test.MockClient{
+		GetProjectsFunc: func(ctx context.Context) ([]*client.Project, error) {
+			return []*client.Project{{
+				Name: "prod",
+				Roles: []*client.ProjectRole{{
+					Project: "prod",
+					Name:    "ci",
+					Groups:  []string{"my-org:ci"},
+					Tokens: []*client.ProjectToken{
+						{ID: "deploy", IssuedAt: 1700000000, ExpiresAt: 1700086400},
+						{IssuedAt: 1720000000},
+					},
+				}},
+			}}, nil
+		},
+		CreateProjectTokenFunc: func(ctx context.Context, project string, role string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+			issued = append(issued, project+"/"+role+"/"+id+"/"+expiresIn.String())
+			return "eyJhbGciOi.payload.signature", nil, nil
+		},
+		DeleteProjectTokenFunc: func(ctx context.Context, project string, role string, issuedAt int64) (annotations.Annotations, error) {
+			deleted = append(deleted, issuedAt)
+			return nil, nil
+		},
+	}
+
+	roles, _, _, err := newProjectRoleBuilder(singleInstance(mockCli)).List(context.Background(),
+		&v2.ResourceId{ResourceType: projectResourceType.Id, Resource: "prod"}, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, "prod/ci", roles[0].Id.Resource)
+	assert.Equal(t, "groups my-org:ci", roles[0].Description)
+
+	builder := newProjectTokenBuilder(singleInstance(mockCli))
+	tokens, _, _, err := builder.List(context.Background(), roles[0].Id, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "deploy", tokens[0].DisplayName)
+	assert.Equal(t, "prod/ci/1700000000", tokens[0].Id.Resource)
+	assert.Equal(t, "Issued 2023-11-14T22:13:20Z, expires 2023-11-15T22:13:20Z", tokens[0].Description)
+	assert.Equal(t, "1720000000", tokens[1].DisplayName)
+	assert.Contains(t, tokens[1].Description, "never expires")
+
+	_, err = builder.Delete(context.Background(), tokens[1].Id)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1720000000}, deleted)
+
+	plaintexts, _, err := builder.Rotate(context.Background(), tokens[0].Id, nil)
+	require.NoError(t, err)
+	require.Len(t, plaintexts, 1)
+	assert.Equal(t, "eyJhbGciOi.payload.signature", string(plaintexts[0].Bytes))
+	require.Len(t, issued, 1)
+	assert.Regexp(t, `^prod/ci/deploy-[0-9]{10}/24h0m0s$`, issued[0])
+	assert.NotEqual(t, "prod/ci/deploy/24h0m0s", issued[0], "the new token must not reuse the existing token's ID")
+	// The replaced token is revoked once the new one is issued.
+	assert.Equal(t, []int64{1720000000, 1700000000}, deleted)
+
+	issued = nil
+	_, _, err = builder.Rotate(context.Background(), tokens[1].Id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod/ci//0s"}, issued)
+
+	// In dry-run mode nothing is issued or revoked, and the result says so.
+	deleted = nil
+	mockCli.CreateProjectTokenFunc = func(ctx context.Context, project string, role string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+		return "", annotations.New((&client.NotApplied{Reason: client.NotAppliedDryRun}).Annotation()), nil
+	}
+	plaintexts, annos, err := builder.Rotate(context.Background(), tokens[0].Id, nil)
+	require.NoError(t, err)
+	assert.Empty(t, plaintexts)
+	assert.NotNil(t, client.GetNotApplied(annos))
+	assert.Empty(t, deleted)
+
+	_, _, err = builder.Rotate(context.Background(), &v2.ResourceId{ResourceType: projectTokenResourceType.Id, Resource: "prod/ci/1"}, nil)
+	assert.ErrorContains(t, err, "token prod/ci/1 not found")
+	_, err = builder.Delete(context.Background(), &v2.ResourceId{ResourceType: projectTokenResourceType.Id, Resource: "prod/ci"})
+	assert.ErrorContains(t, err, "invalid project role token id")
+}
+
+// TestRotatedTokenID tests the IDs of tokens issued by rotation.
+func TestRotatedTokenID(t *testing.T) {
+	now := time.Unix(1730000000, 0)
+	assert.Equal(t, "deploy-1730000000", rotatedTokenID("deploy", now))
+	assert.Equal(t, "deploy-1730000000", rotatedTokenID("deploy-1720000000", now))
+	assert.Equal(t, "build-42-1730000000", rotatedTokenID("build-42", now))
+	assert.Equal(t, "", rotatedTokenID("", now))
+}
@@ -0,0 +1,122 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// repositoryActions are the actions on a repository that are exposed as entitlements.
+var repositoryActions = []policyAction{
+	{"get", "repositories", "get", "view"},
+	{"create", "repositories", "create", "create"},
+	{"update", "repositories", "update", "update"},
+	{"delete", "repositories", "delete", "delete"},
+}
+
+// repositoryBuilder implements the ResourceSyncer interface for the repositories Argo CD pulls from.
+type repositoryBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for repositories.
+func (r *repositoryBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return repositoryResourceType
+}
+
+// List returns all repositories as resource objects. A repository is identified by its RBAC object,
+// its URL optionally prefixed by its project.
+func (r *repositoryBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	inst := r.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	repositories, err := inst.client.GetRepositories(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch repositories: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, repo := range repositories {
+		name := repo.Name
+		if name == "" {
+			name = repo.URL
+		}
+
+		repositoryResource, err := resource.NewResource(
+			name,
+			repositoryResourceType,
+			client.RepositoryRBACObject(repo.Project, repo.URL),
+			resource.WithDescription(repositoryDescription(repo)),
+		)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create repository resource %s: %w", repo.URL, err)
+		}
+		resources = append(resources, inst.scope(repositoryResource))
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns a permission entitlement for each Argo CD action on the repository.
+func (r *repositoryBuilder) Entitlements(_ context.Context, repositoryResource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return policyEntitlements(repositoryResource, repositoryActions), "", nil, nil
+}
+
+// Grants returns a grant to each role whose policy allows an action on the repository.
+func (r *repositoryBuilder) Grants(ctx context.Context, repositoryResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	inst, object, err := r.instances.resolve(repositoryResource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	grants, err := policyGrants(ctx, inst, repositoryResource, object, repositoryActions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return grants, "", nil, nil
+}
+
+// repositoryDescription describes a repository's type, scope and where its credentials come from,
+// flagging credentials shared with repositories of other projects.
+func repositoryDescription(repo *client.Repository) string {
+	description := "Repository " + repo.URL
+	if repo.Type != "" {
+		description = strings.ToUpper(repo.Type[:1]) + repo.Type[1:] + " repository " + repo.URL
+	}
+	if repo.Project != "" {
+		description += ", project " + repo.Project
+	}
+	switch {
+	case repo.CredentialTemplate != "" && repo.HasCredentials:
+		description += ", credentials from template " + repo.CredentialTemplate
+	case !repo.HasCredentials:
+		description += ", no credentials"
+	}
+	if len(repo.SharedWithProjects) > 0 {
+		scopes := make([]string, 0, len(repo.SharedWithProjects))
+		for _, project := range repo.SharedWithProjects {
+			if project == "" {
+				project = "(global)"
+			}
+			scopes = append(scopes, project)
+		}
+		description += "; credentials shared across projects " + strings.Join(scopes, ", ")
+	}
+	return description
+}
+
+// newRepositoryBuilder creates a new repositoryBuilder.
+func newRepositoryBuilder(instances instances) *repositoryBuilder {
+	return &repositoryBuilder{
+		resourceType: repositoryResourceType,
+		instances:    instances,
+	}
+}
@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRepositoryBuilder tests listing repositories and deriving grants from the policy.
+func TestRepositoryBuilder(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetRepositoriesFunc: func(ctx context.Context) ([]*client.Repository, error) {
+			return []*client.Repository{
+				{URL: "https://charts.example.com", Name: "charts", Type: "helm", HasCredentials: true},
+				{
+					URL:                "https://github.com/org/team-a",
+					Type:               "git",
+					Project:            "team-a",
+					HasCredentials:     true,
+					CredentialTemplate: "https://github.com/org",
+					SharedWithProjects: []string{"", "team-a"},
+				},
+			}, nil
+		},
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "platform"}, {Name: "team-a"}}, nil, nil
+		},
+		GetPolicyEvaluatorFunc: func(ctx context.Context) (*client.PolicyEvaluator, error) {
+			return client.NewPolicyEvaluator(
+				"p, role:platform, repositories, *, *, allow\np, role:team-a, repositories, get, team-a/*, allow\n", "")
+		},
+	}
+	builder := newRepositoryBuilder(singleInstance(mockCli))
+
+	resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "charts", resources[0].DisplayName)
+	assert.Equal(t, "https://charts.example.com", resources[0].Id.Resource)
+	assert.Equal(t, "Helm repository https://charts.example.com", resources[0].Description)
+	assert.Equal(t, "https://github.com/org/team-a", resources[1].DisplayName)
+	assert.Equal(t, "team-a/https://github.com/org/team-a", resources[1].Id.Resource)
+	assert.Equal(t,
+		"Git repository https://github.com/org/team-a, project team-a, credentials from template https://github.com/org; "+
+			"credentials shared across projects (global), team-a",
+		resources[1].Description)
+
+	ents, _, _, err := builder.Entitlements(context.Background(), resources[0], &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, ents, len(repositoryActions))
+
+	principals := func(i int) map[string]int {
+		grants, _, _, err := builder.Grants(context.Background(), resources[i], &pagination.Token{})
+		require.NoError(t, err)
+		counts := map[string]int{}
+		for _, g := range grants {
+			counts[g.Principal.Id.Resource]++
+		}
+		return counts
+	}
+	assert.Equal(t, map[string]int{"platform": 4}, principals(0))
+	assert.Equal(t, map[string]int{"platform": 4, "team-a": 1}, principals(1))
+}
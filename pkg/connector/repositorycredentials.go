@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// repositoryCredentialBuilder implements the ResourceSyncer interface for repository credential templates.
+// Argo CD authorizes access to them as repositories, so they carry the same entitlements.
+type repositoryCredentialBuilder struct {
+	resourceType *v2.ResourceType
+	instances    instances
+}
+
+// ResourceType returns the resource type for repository credential templates.
+func (r *repositoryCredentialBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return repositoryCredentialResourceType
+}
+
+// List returns all repository credential templates as resource objects. A template is identified by its RBAC
+// object, its URL prefix optionally prefixed by its project.
+func (r *repositoryCredentialBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	inst := r.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	templates, err := inst.client.GetRepositoryCredentials(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch repository credential templates: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, template := range templates {
+		templateResource, err := resource.NewResource(
+			template.URL,
+			repositoryCredentialResourceType,
+			client.RepositoryRBACObject(template.Project, template.URL),
+			resource.WithDescription(repositoryCredentialDescription(template)),
+		)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create repository credential template resource %s: %w", template.URL, err)
+		}
+		resources = append(resources, inst.scope(templateResource))
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns a permission entitlement for each Argo CD action on the template.
+func (r *repositoryCredentialBuilder) Entitlements(_ context.Context, templateResource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return policyEntitlements(templateResource, repositoryActions), "", nil, nil
+}
+
+// Grants returns a grant to each role whose policy allows an action on the template.
+func (r *repositoryCredentialBuilder) Grants(ctx context.Context, templateResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	inst, object, err := r.instances.resolve(templateResource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	grants, err := policyGrants(ctx, inst, templateResource, object, repositoryActions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return grants, "", nil, nil
+}
+
+// repositoryCredentialDescription describes a template's type and scope and the repositories it serves,
+// flagging templates serving repositories of several projects.
+func repositoryCredentialDescription(template *client.RepositoryCredential) string {
+	description := "Credential template for " + template.URL
+	if template.Type != "" {
+		description = strings.ToUpper(template.Type[:1]) + template.Type[1:] + " credential template for " + template.URL
+	}
+	if template.Project != "" {
+		description += ", project " + template.Project
+	}
+	if !template.HasCredentials {
+		description += ", no credentials"
+	}
+	switch len(template.Repositories) {
+	case 0:
+		description += ", used by no repository"
+	case 1:
+		description += ", used by 1 repository"
+	default:
+		description += ", used by " + strconv.Itoa(len(template.Repositories)) + " repositories"
+	}
+	if len(template.Projects) > 1 {
+		scopes := make([]string, 0, len(template.Projects))
+		for _, project := range template.Projects {
+			if project == "" {
+				project = "(global)"
+			}
+			scopes = append(scopes, project)
+		}
+		description += "; shared across projects " + strings.Join(scopes, ", ")
+	}
+	return description
+}
+
+// newRepositoryCredentialBuilder creates a new repositoryCredentialBuilder.
+func newRepositoryCredentialBuilder(instances instances) *repositoryCredentialBuilder {
+	return &repositoryCredentialBuilder{
+		resourceType: repositoryCredentialResourceType,
+		instances:    instances,
+	}
+}
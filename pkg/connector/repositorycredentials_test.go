@@ -0,0 +1,68 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRepositoryCredentialBuilder tests listing credential templates and deriving grants from the policy.
+func TestRepositoryCredentialBuilder(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetRepositoryCredentialsFunc: func(ctx context.Context) ([]*client.RepositoryCredential, error) {
+			return []*client.RepositoryCredential{
+				{
+					URL:            "https://github.com/org",
+					Type:           "git",
+					HasCredentials: true,
+					Repositories:   []string{"https://github.com/org/infra", "https://github.com/org/team-a"},
+					Projects:       []string{"", "team-a"},
+				},
+				{URL: "https://charts.example.com", Type: "helm", Project: "team-a"},
+			}, nil
+		},
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "platform"}, {Name: "team-a"}}, nil, nil
+		},
+		GetPolicyEvaluatorFunc: func(ctx context.Context) (*client.PolicyEvaluator, error) {
+			return client.NewPolicyEvaluator(
+				"p, role:platform, repositories, *, *, allow\np, role:team-a, repositories, get, team-a/*, allow\n", "")
+		},
+	}
+	builder := newRepositoryCredentialBuilder(singleInstance(mockCli))
+
+	resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "https://github.com/org", resources[0].DisplayName)
+	assert.Equal(t, "https://github.com/org", resources[0].Id.Resource)
+	assert.Equal(t,
+		"Git credential template for https://github.com/org, used by 2 repositories; shared across projects (global), team-a",
+		resources[0].Description)
+	assert.Equal(t, "team-a/https://charts.example.com", resources[1].Id.Resource)
+	assert.Equal(t,
+		"Helm credential template for https://charts.example.com, project team-a, no credentials, used by no repository",
+		resources[1].Description)
+
+	ents, _, _, err := builder.Entitlements(context.Background(), resources[0], &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, ents, len(repositoryActions))
+
+	principals := func(i int) map[string]int {
+		grants, _, _, err := builder.Grants(context.Background(), resources[i], &pagination.Token{})
+		require.NoError(t, err)
+		counts := map[string]int{}
+		for _, g := range grants {
+			counts[g.Principal.Id.Resource]++
+		}
+		return counts
+	}
+	assert.Equal(t, map[string]int{"platform": 4}, principals(0))
+	assert.Equal(t, map[string]int{"platform": 4, "team-a": 1}, principals(1))
+}
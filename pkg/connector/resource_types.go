@@ -6,14 +6,74 @@ import (
 
 // The user resource type is for all user objects from the database.
 var (
+	instanceResourceType = &v2.ResourceType{
+		Id:          "instance",
+		DisplayName: "Instance",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
 	userResourceType = &v2.ResourceType{
 		Id:          "user",
 		DisplayName: "User",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
 	}
+	groupResourceType = &v2.ResourceType{
+		Id:          "group",
+		DisplayName: "Group",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+	}
 	roleResourceType = &v2.ResourceType{
 		Id:          "role",
 		DisplayName: "Role",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
 	}
+	projectResourceType = &v2.ResourceType{
+		Id:          "project",
+		DisplayName: "Project",
+	}
+	projectRoleResourceType = &v2.ResourceType{
+		Id:          "project_role",
+		DisplayName: "Project Role",
+	}
+	accountTokenResourceType = &v2.ResourceType{
+		Id:          "account_token",
+		DisplayName: "Account Token",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+	}
+	projectTokenResourceType = &v2.ResourceType{
+		Id:          "project_role_token",
+		DisplayName: "Project Role Token",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+	}
+	applicationResourceType = &v2.ResourceType{
+		Id:          "application",
+		DisplayName: "Application",
+	}
+	applicationSetResourceType = &v2.ResourceType{
+		Id:          "applicationset",
+		DisplayName: "ApplicationSet",
+	}
+	clusterResourceType = &v2.ResourceType{
+		Id:          "cluster",
+		DisplayName: "Cluster",
+	}
+	repositoryResourceType = &v2.ResourceType{
+		Id:          "repository",
+		DisplayName: "Repository",
+	}
+	repositoryCredentialResourceType = &v2.ResourceType{
+		Id:          "repository_credential",
+		DisplayName: "Repository Credential Template",
+	}
 )
+
+// instanceChildResourceTypes are the resource types parented by an instance in a multi-instance connector.
+var instanceChildResourceTypes = []*v2.ResourceType{
+	userResourceType,
+	groupResourceType,
+	roleResourceType,
+	projectResourceType,
+	applicationSetResourceType,
+	clusterResourceType,
+	repositoryResourceType,
+	repositoryCredentialResourceType,
+}
@@ -0,0 +1,155 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	configv1 "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	cloneRoleAction          = "clone_role"
+	renameRoleAction         = "rename_role"
+	migrateRoleMembersAction = "migrate_role_members"
+)
+
+// newRoleChangeAction returns the action cloning a role, renaming it, or migrating its members to another role.
+// Each is a single conditional write of argocd-rbac-cm, and returns the records of the roles it touched before
+// and after it.
+func newRoleChangeAction(instances instances, name string) *action {
+	schema := &v2.BatonActionSchema{
+		Name: name,
+		ReturnTypes: []*configv1.Field{
+			stringField("role", "Role", "The ID of the role resource the members or permissions are now on.", false),
+			stringListField("before", "Before", "The policy.csv records of the roles before the change."),
+			stringListField("after", "After", "The policy.csv records of the roles after the change."),
+			boolField("changed", "Changed", "Whether policy.csv was changed."),
+		},
+	}
+	switch name {
+	case cloneRoleAction:
+		schema.DisplayName = "Clone role"
+		schema.Description = "Create a role with a copy of the permissions and inherited roles of another. Members aren't copied."
+		schema.Arguments = []*configv1.Field{
+			stringField("role", "Role", "The ID of the role resource to clone.", true),
+			stringField("name", "Name", "The name of the new role.", true),
+		}
+	case renameRoleAction:
+		schema.DisplayName = "Rename role"
+		schema.Description = "Rename a role, rewriting its permissions and every binding referencing it."
+		schema.Arguments = []*configv1.Field{
+			stringField("role", "Role", "The ID of the role resource to rename.", true),
+			stringField("name", "Name", "The new name of the role.", true),
+		}
+	case migrateRoleMembersAction:
+		schema.DisplayName = "Migrate role members"
+		schema.Description = "Move every user, group and role bound to a role to another role."
+		schema.Arguments = []*configv1.Field{
+			stringField("role", "Role", "The ID of the role resource whose members are moved.", true),
+			stringField("target_role", "Target role", "The ID of the role resource the members are moved to.", true),
+		}
+	}
+
+	return &action{
+		schema: schema,
+		invoke: func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, error) {
+			return changeRole(ctx, instances, name, args)
+		},
+	}
+}
+
+// changeRole runs the role change named by the action with its arguments.
+func changeRole(ctx context.Context, instances instances, name string, args *structpb.Struct) (*structpb.Struct, error) {
+	roleID, err := stringArg(args, "role")
+	if err != nil {
+		return nil, err
+	}
+	roleID = strings.TrimSpace(roleID)
+	if roleID == "" {
+		return nil, fmt.Errorf("role is required")
+	}
+	inst, role, err := instances.resolve(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, err
+	}
+	role = strings.TrimPrefix(role, client.RolePrefix)
+
+	var target, targetID string
+	if name == migrateRoleMembersAction {
+		targetID, err = stringArg(args, "target_role")
+		if err != nil {
+			return nil, err
+		}
+		targetID = strings.TrimSpace(targetID)
+		if targetID == "" {
+			return nil, fmt.Errorf("target_role is required")
+		}
+		var targetInst *instance
+		targetInst, target, err = instances.resolve(targetID)
+		if err != nil {
+			return nil, err
+		}
+		if targetInst != inst {
+			return nil, fmt.Errorf("roles %s and %s belong to different Argo CD instances", roleID, targetID)
+		}
+	} else {
+		target, err = stringArg(args, "name")
+		if err != nil {
+			return nil, err
+		}
+		target = strings.TrimSpace(target)
+		if err := client.ValidateRoleName(target); err != nil {
+			return nil, err
+		}
+		targetID = inst.id(strings.TrimPrefix(target, client.RolePrefix))
+	}
+	target = strings.TrimPrefix(target, client.RolePrefix)
+
+	ctx = client.WithChangeRecord(ctx, newChangeRecord(""))
+	var change *client.RoleChange
+	switch name {
+	case cloneRoleAction:
+		change, err = inst.client.CloneRole(ctx, role, target)
+	case renameRoleAction:
+		change, err = inst.client.RenameRole(ctx, role, target)
+	default:
+		change, err = inst.client.MigrateRoleMembers(ctx, role, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctxzap.Extract(ctx).Info("changed roles",
+		zap.String("action", name),
+		zap.String("role", roleID),
+		zap.String("target", targetID),
+		zap.Strings("before", change.Before),
+		zap.Strings("after", change.After),
+		zap.Bool("changed", change.Changed),
+	)
+
+	return structpb.NewStruct(map[string]interface{}{
+		"role":    targetID,
+		"before":  stringList(change.Before),
+		"after":   stringList(change.After),
+		"changed": change.Changed,
+	})
+}
+
+// stringList converts strings to the list form structpb.NewStruct accepts.
+func stringList(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+	return list
+}
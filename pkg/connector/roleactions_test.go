@@ -0,0 +1,117 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestRoleChangeActions tests cloning, renaming and migrating roles through the custom actions.
+func TestRoleChangeActions(t *testing.T) {
+	var calls [][2]string
+	mockCli := &test.MockClient{
+		CloneRoleFunc: func(ctx context.Context, role string, newRole string) (*client.RoleChange, error) {
+			calls = append(calls, [2]string{role, newRole})
+			return &client.RoleChange{
+				After:   []string{"p, role:" + newRole + ", applications, get, */*, allow"},
+				Changed: true,
+			}, nil
+		},
+		RenameRoleFunc: func(ctx context.Context, role string, newRole string) (*client.RoleChange, error) {
+			calls = append(calls, [2]string{role, newRole})
+			return &client.RoleChange{
+				Before:  []string{"g, alice, role:" + role},
+				After:   []string{"g, alice, role:" + newRole},
+				Changed: true,
+			}, nil
+		},
+		MigrateRoleMembersFunc: func(ctx context.Context, role string, newRole string) (*client.RoleChange, error) {
+			calls = append(calls, [2]string{role, newRole})
+			return &client.RoleChange{}, nil
+		},
+	}
+	manager := newActionManager(singleInstance(mockCli))
+
+	invoke := func(manager *actionManager, name string, args map[string]interface{}) (*structpb.Struct, error) {
+		s, err := structpb.NewStruct(args)
+		require.NoError(t, err)
+		_, _, response, _, err := manager.InvokeAction(context.Background(), name, s)
+		return response, err
+	}
+
+	t.Run("clone", func(t *testing.T) {
+		calls = nil
+		response, err := invoke(manager, cloneRoleAction, map[string]interface{}{"role": "readonly", "name": " auditor "})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]string{{"readonly", "auditor"}}, calls)
+
+		fields := response.AsMap()
+		assert.Equal(t, "auditor", fields["role"])
+		assert.Equal(t, []interface{}{}, fields["before"])
+		assert.Equal(t, []interface{}{"p, role:auditor, applications, get, */*, allow"}, fields["after"])
+		assert.Equal(t, true, fields["changed"])
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		calls = nil
+		response, err := invoke(manager, renameRoleAction, map[string]interface{}{"role": "role:team-a", "name": "role:team-b"})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]string{{"team-a", "team-b"}}, calls)
+
+		fields := response.AsMap()
+		assert.Equal(t, "team-b", fields["role"])
+		assert.Equal(t, []interface{}{"g, alice, role:team-a"}, fields["before"])
+		assert.Equal(t, []interface{}{"g, alice, role:team-b"}, fields["after"])
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		calls = nil
+		response, err := invoke(manager, migrateRoleMembersAction, map[string]interface{}{"role": "team-a", "target_role": "team-b"})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]string{{"team-a", "team-b"}}, calls)
+		assert.Equal(t, false, response.AsMap()["changed"])
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		calls = nil
+		tests := []struct {
+			name string
+			args map[string]interface{}
+			err  string
+		}{
+			{cloneRoleAction, map[string]interface{}{"role": "team-a"}, "role name is required"},
+			{renameRoleAction, map[string]interface{}{"role": "team-a", "name": "team b"}, `invalid role name "team b"`},
+			{renameRoleAction, map[string]interface{}{"role": "team-a", "name": "admin"}, "role admin is a built-in Argo CD role"},
+			{migrateRoleMembersAction, map[string]interface{}{"role": "team-a"}, "target_role is required"},
+			{migrateRoleMembersAction, map[string]interface{}{"target_role": "team-b"}, "role is required"},
+		}
+		for _, tt := range tests {
+			_, err := invoke(manager, tt.name, tt.args)
+			assert.ErrorContains(t, err, tt.err)
+		}
+		assert.Empty(t, calls)
+	})
+
+	t.Run("instances", func(t *testing.T) {
+		manager := newActionManager(instances{
+			{name: "prod", client: mockCli},
+			{name: "staging", client: mockCli, discovered: true},
+		})
+		_, err := invoke(manager, migrateRoleMembersAction, map[string]interface{}{"role": "prod:team-a", "target_role": "staging:team-b"})
+		assert.ErrorContains(t, err, "roles prod:team-a and staging:team-b belong to different Argo CD instances")
+
+		_, err = invoke(manager, renameRoleAction, map[string]interface{}{"role": "staging:team-a", "name": "team-b"})
+		assert.ErrorContains(t, err, "synced read-only")
+
+		calls = nil
+		response, err := invoke(manager, cloneRoleAction, map[string]interface{}{"role": "prod:team-a", "name": "team-b"})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]string{{"team-a", "team-b"}}, calls)
+		assert.Equal(t, "prod:team-b", response.AsMap()["role"])
+	})
+}
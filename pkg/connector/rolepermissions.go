@@ -0,0 +1,111 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	configv1 "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	addRolePermissionAction    = "add_role_permission"
+	removeRolePermissionAction = "remove_role_permission"
+)
+
+// newRolePermissionAction returns the action adding a `p,` line to a role, or removing it, after validating
+// the permission against the resources and actions Argo CD's RBAC knows.
+func newRolePermissionAction(instances instances, name string) *action {
+	schema := &v2.BatonActionSchema{
+		Name: name,
+		Arguments: []*configv1.Field{
+			stringField("role", "Role", "The ID of the role resource.", true),
+			stringField("resource", "Resource", "The Argo CD resource, such as applications, clusters or repositories.", true),
+			stringField("action", "Action", "The action on the resource, such as get, sync or action/apps/Deployment/restart.", true),
+			stringField("object", "Object", "The objects the permission applies to, such as staging/* for the applications of the staging project.", true),
+			stringField("effect", "Effect", "allow or deny, allow if empty.", false),
+		},
+		ReturnTypes: []*configv1.Field{
+			stringField("role", "Role", "The ID of the role resource.", false),
+			stringField("policy", "Policy", "The policy.csv line of the permission.", false),
+			boolField("changed", "Changed", "Whether policy.csv was changed, false if it already was as requested."),
+		},
+	}
+	if name == addRolePermissionAction {
+		schema.DisplayName = "Add role permission"
+		schema.Description = "Add a permission to a role of policy.csv."
+	} else {
+		schema.DisplayName = "Remove role permission"
+		schema.Description = "Remove a permission from a role of policy.csv."
+	}
+
+	return &action{
+		schema: schema,
+		invoke: func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, error) {
+			return editRolePermission(ctx, instances, name, args)
+		},
+	}
+}
+
+// editRolePermission adds or removes the permission described by the arguments.
+func editRolePermission(ctx context.Context, instances instances, name string, args *structpb.Struct) (*structpb.Struct, error) {
+	values := make(map[string]string)
+	for _, arg := range []string{"role", "resource", "action", "object", "effect"} {
+		value, err := stringArg(args, arg)
+		if err != nil {
+			return nil, err
+		}
+		values[arg] = strings.TrimSpace(value)
+	}
+	if values["role"] == "" {
+		return nil, fmt.Errorf("role is required")
+	}
+
+	inst, roleName, err := instances.resolve(values["role"])
+	if err != nil {
+		return nil, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, err
+	}
+
+	policy := &client.PolicyDefinition{
+		Role:     strings.TrimPrefix(roleName, client.RolePrefix),
+		Resource: values["resource"],
+		Action:   values["action"],
+		Object:   values["object"],
+		Effect:   values["effect"],
+	}
+	if err := validatePermission(policy); err != nil {
+		return nil, err
+	}
+
+	ctx = client.WithChangeRecord(ctx, newChangeRecord(""))
+	var changed bool
+	if name == addRolePermissionAction {
+		changed, err = inst.client.AddRolePolicy(ctx, policy)
+	} else {
+		changed, err = inst.client.RemoveRolePolicy(ctx, policy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctxzap.Extract(ctx).Info("edited role permission",
+		zap.String("action", name),
+		zap.String("role", values["role"]),
+		zap.String("policy", policy.String()),
+		zap.Bool("changed", changed),
+	)
+
+	return structpb.NewStruct(map[string]interface{}{
+		"role":    values["role"],
+		"policy":  policy.String(),
+		"changed": changed,
+	})
+}
@@ -0,0 +1,96 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+	"github.com/conductorone/baton-argo-cd/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestRolePermissionActions tests adding and removing role permissions through the custom actions.
+func TestRolePermissionActions(t *testing.T) {
+	var added, removed []*client.PolicyDefinition
+	mockCli := &test.MockClient{
+		AddRolePolicyFunc: func(ctx context.Context, policy *client.PolicyDefinition) (bool, error) {
+			added = append(added, policy)
+			return true, nil
+		},
+		RemoveRolePolicyFunc: func(ctx context.Context, policy *client.PolicyDefinition) (bool, error) {
+			removed = append(removed, policy)
+			return false, nil
+		},
+	}
+	manager := newActionManager(singleInstance(mockCli))
+
+	invoke := func(name string, args map[string]interface{}) (*structpb.Struct, error) {
+		s, err := structpb.NewStruct(args)
+		require.NoError(t, err)
+		_, _, response, _, err := manager.InvokeAction(context.Background(), name, s)
+		return response, err
+	}
+
+	t.Run("add", func(t *testing.T) {
+		response, err := invoke(addRolePermissionAction, map[string]interface{}{
+			"role":     "team-a",
+			"resource": "applications",
+			"action":   "sync",
+			"object":   "staging/*",
+		})
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+		assert.Equal(t, &client.PolicyDefinition{Role: "team-a", Resource: "applications", Action: "sync", Object: "staging/*", Effect: "allow"}, added[0])
+
+		fields := response.AsMap()
+		assert.Equal(t, "team-a", fields["role"])
+		assert.Equal(t, "p, role:team-a, applications, sync, staging/*, allow", fields["policy"])
+		assert.Equal(t, true, fields["changed"])
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		response, err := invoke(removeRolePermissionAction, map[string]interface{}{
+			"role":     "role:team-a",
+			"resource": "applications",
+			"action":   "action/apps/Deployment/restart",
+			"object":   "*",
+			"effect":   "deny",
+		})
+		require.NoError(t, err)
+		require.Len(t, removed, 1)
+		assert.Equal(t, "team-a", removed[0].Role)
+		assert.Equal(t, "deny", removed[0].Effect)
+		assert.Equal(t, false, response.AsMap()["changed"])
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			args map[string]interface{}
+			err  string
+		}{
+			{map[string]interface{}{"role": "team-a", "resource": "pods", "action": "get", "object": "*"}, `unknown resource "pods"`},
+			{map[string]interface{}{"role": "team-a", "resource": "clusters", "action": "sync", "object": "*"}, `invalid action "sync" for clusters`},
+			{map[string]interface{}{"role": "team-a", "resource": "applications", "action": "sync", "object": "staging"}, `must be <project>/<name>, such as staging/*`},
+			{map[string]interface{}{"role": "team-a", "resource": "logs", "action": "get", "object": "*/*", "effect": "permit"}, `invalid effect "permit"`},
+			{map[string]interface{}{"role": "team-a", "resource": "applications", "action": "get"}, "object is required"},
+		}
+		for _, tt := range tests {
+			_, err := invoke(addRolePermissionAction, tt.args)
+			assert.ErrorContains(t, err, tt.err)
+		}
+		assert.Len(t, added, 1)
+	})
+
+	t.Run("discovered instance", func(t *testing.T) {
+		manager := newActionManager(instances{
+			{name: "prod", client: mockCli},
+			{name: "staging", client: mockCli, discovered: true},
+		})
+		s, err := structpb.NewStruct(map[string]interface{}{"role": "staging:team-a", "resource": "logs", "action": "get", "object": "*/*"})
+		require.NoError(t, err)
+		_, _, _, _, err = manager.InvokeAction(context.Background(), addRolePermissionAction, s)
+		assert.ErrorContains(t, err, "synced read-only")
+	})
+}
@@ -3,7 +3,10 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/conductorone/baton-argo-cd/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
@@ -16,9 +19,11 @@ import (
 
 const assignedEntitlement = "assigned"
 
+// roleBuilder implements the ResourceSyncer, ResourceTargetedSyncer, ResourceManager and ResourceProvisioner
+// interfaces for the roles of policy.csv.
 type roleBuilder struct {
 	resourceType *v2.ResourceType
-	client       ArgoCdClient
+	instances    instances
 }
 
 func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -27,37 +32,74 @@ func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 
 // List returns a list of roles.
 func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	roles, annos, err := r.client.GetRoles(ctx)
+	inst := r.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	roles, annos, err := inst.client.GetRoles(ctx)
 	if err != nil {
 		return nil, "", annos, err
 	}
 
+	owner := getControllerOwner(ctx, inst.client, client.ConfigMapResource, client.RBACConfigMapName)
+
 	var resources []*v2.Resource
 	for _, role := range roles {
-		profile := map[string]interface{}{
-			"name": role.Name,
-		}
-		roleResource, err := resource.NewRoleResource(
-			role.Name,
-			roleResourceType,
-			role.Name,
-			[]resource.RoleTraitOption{resource.WithRoleProfile(profile)},
-		)
+		roleResource, err := parseRoleResource(role, owner)
 		if err != nil {
 			return nil, "", annos, err
 		}
-		resources = append(resources, roleResource)
+		resources = append(resources, inst.scope(roleResource))
 	}
 
 	return resources, "", annos, nil
 }
 
+// Get returns a single role, or nil if it is neither bound in policy.csv nor the default role.
+func (r *roleBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, _ *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	inst, roleName, err := r.instances.resolve(resourceID.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles, annos, err := inst.client.GetRoles(ctx)
+	if err != nil {
+		return nil, annos, err
+	}
+	i := slices.IndexFunc(roles, func(role *client.Role) bool { return role.Name == roleName })
+	if i < 0 {
+		return nil, annos, nil
+	}
+
+	owner := getControllerOwner(ctx, inst.client, client.ConfigMapResource, client.RBACConfigMapName)
+	roleResource, err := parseRoleResource(roles[i], owner)
+	if err != nil {
+		return nil, annos, err
+	}
+	return inst.scope(roleResource), annos, nil
+}
+
+// parseRoleResource creates a resource for a role, recording the GitOps controller owning argocd-rbac-cm.
+func parseRoleResource(role *client.Role, owner *client.ControllerOwner) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"name": role.Name,
+	}
+	addControllerOwner(profile, owner)
+	return resource.NewRoleResource(
+		role.Name,
+		roleResourceType,
+		role.Name,
+		[]resource.RoleTraitOption{resource.WithRoleProfile(profile)},
+	)
+}
+
 // Entitlements returns the entitlements for a role.
 func (r *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var annos annotations.Annotations
 
 	assigmentOptions := []entitlement.EntitlementOption{
-		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithGrantableTo(userResourceType, groupResourceType),
 		entitlement.WithDescription(fmt.Sprintf("%s to %s role", assignedEntitlement, resource.DisplayName)),
 		entitlement.WithDisplayName(fmt.Sprintf("%s role %s", resource.DisplayName, assignedEntitlement)),
 	}
@@ -71,23 +113,38 @@ func (r *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _
 	return []*v2.Entitlement{ent}, "", annos, nil
 }
 
-// Grants returns the grants for a role.
+// Grants returns the grants for a role: to the users and SSO groups bound to it. Grants to groups mapped to the
+// identity provider carry the group's external match, so they expand to its members.
 func (r *roleBuilder) Grants(ctx context.Context, roleResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
-	roleName := roleResource.Id.Resource
+	inst, roleName, err := r.instances.resolve(roleResource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
 
-	users, err := r.client.GetRoleUsers(ctx, roleName)
+	users, err := inst.client.GetRoleUsers(ctx, roleName)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("failed to get users for role %s: %w", roleName, err)
 	}
 
+	bindings, err := inst.client.GetRoleBindings(ctx, roleName)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to get bindings for role %s: %w", roleName, err)
+	}
+	bindingsBySubject := make(map[string]*client.PolicyBinding, len(bindings))
+	for _, binding := range bindings {
+		if existing, ok := bindingsBySubject[binding.Subject]; !ok || !existing.Managed {
+			bindingsBySubject[binding.Subject] = binding
+		}
+	}
+
 	var allGrants []*v2.Grant
 	var annos annotations.Annotations
 	for _, user := range users {
 		userResource, err := resource.NewUserResource(
 			user.Name,
 			userResourceType,
-			user.Name,
+			inst.id(user.Name),
 			nil,
 		)
 		if err != nil {
@@ -102,22 +159,40 @@ func (r *roleBuilder) Grants(ctx context.Context, roleResource *v2.Resource, _ *
 			roleResource,
 			assignedEntitlement,
 			userResource.Id,
+			grant.WithGrantMetadata(bindingMetadata(bindingsBySubject[user.Name])),
 		)
 		allGrants = append(allGrants, grant)
 	}
 
+	groups, err := inst.client.GetGroups(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to get groups for role %s: %w", roleName, err)
+	}
+	for _, group := range groups {
+		if !slices.Contains(group.Roles, strings.TrimPrefix(roleName, client.RolePrefix)) {
+			continue
+		}
+		options := []grant.GrantOption{grant.WithGrantMetadata(bindingMetadata(bindingsBySubject[group.Name]))}
+		if match := groupExternalMatch(group); match != nil {
+			options = append(options, grant.WithAnnotation(match))
+		}
+		allGrants = append(allGrants, grant.NewGrant(
+			roleResource,
+			assignedEntitlement,
+			&v2.ResourceId{ResourceType: groupResourceType.Id, Resource: inst.id(group.Name)},
+			options...,
+		))
+	}
+
 	return allGrants, "", annos, nil
 }
 
-// Grant assigns a role to a user, adding it to any existing roles.
+// Grant assigns a role to a user or an SSO group, adding it to any existing roles.
 // If the user only has a default role, it will be made explicit.
 func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
-	userID := principal.Id.Resource
-	roleID := entitlement.Resource.Id.Resource
-
-	annos, err := r.client.UpdateUserRole(ctx, userID, roleID)
+	inst, userID, roleID, err := r.resolveAssignment(principal.Id, entitlement.Resource.Id)
 	if err != nil {
-		return nil, annos, fmt.Errorf("failed to update user role: %w", err)
+		return nil, nil, err
 	}
 
 	grantObj := grant.NewGrant(
@@ -126,15 +201,24 @@ func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitle
 		principal.Id,
 	)
 
+	ctx = client.WithChangeRecord(ctx, newChangeRecord(grantObj.Id, entitlement.Annotations, principal.Annotations))
+	annos, err := inst.client.UpdateUserRole(ctx, userID, roleID)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to update user role: %w", err)
+	}
+
 	return []*v2.Grant{grantObj}, annos, nil
 }
 
-// Revoke removes a role from a user.
+// Revoke removes a role from a user or an SSO group.
 func (r *roleBuilder) Revoke(ctx context.Context, g *v2.Grant) (annotations.Annotations, error) {
-	userID := g.Principal.Id.Resource
-	roleID := g.Entitlement.Resource.Id.Resource
+	inst, userID, roleID, err := r.resolveAssignment(g.Principal.Id, g.Entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
 
-	annos, err := r.client.RemoveUserRole(ctx, userID, roleID)
+	ctx = client.WithChangeRecord(ctx, newChangeRecord(g.Id, g.Annotations))
+	annos, err := inst.client.RemoveUserRole(ctx, userID, roleID)
 	if err != nil {
 		return annos, fmt.Errorf("failed to remove user role: %w", err)
 	}
@@ -142,10 +226,104 @@ func (r *roleBuilder) Revoke(ctx context.Context, g *v2.Grant) (annotations.Anno
 	return annos, nil
 }
 
+// Create adds a role to policy.csv from its name and the permission template of its profile's `permissions`
+// list. With several instances, the role is created in the instance named by its ID prefix or parent.
+func (r *roleBuilder) Create(ctx context.Context, roleResource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	inst, roleName, err := r.resolveNewRole(roleResource)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, nil, err
+	}
+
+	trait, err := resource.GetRoleTrait(roleResource)
+	if err != nil {
+		return nil, nil, fmt.Errorf("role %s has no permission template: %w", roleName, err)
+	}
+	policies, err := parsePermissionTemplate(trait.GetProfile())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid permission template for role %s: %w", roleName, err)
+	}
+
+	ctx = client.WithChangeRecord(ctx, newChangeRecord("", roleResource.Annotations))
+	annos, err := inst.client.CreateRole(ctx, roleName, policies)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to create role %s: %w", roleName, err)
+	}
+
+	created, err := parseRoleResource(&client.Role{Name: roleName}, nil)
+	if err != nil {
+		return nil, annos, err
+	}
+	return inst.scope(created), annos, nil
+}
+
+// Delete removes a role from policy.csv with every binding referencing it. Built-in roles and the default
+// role are refused.
+func (r *roleBuilder) Delete(ctx context.Context, resourceID *v2.ResourceId) (annotations.Annotations, error) {
+	inst, roleName, err := r.instances.resolve(resourceID.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, err
+	}
+
+	ctx = client.WithChangeRecord(ctx, newChangeRecord(""))
+	annos, err := inst.client.DeleteRole(ctx, roleName)
+	if err != nil {
+		return annos, fmt.Errorf("failed to delete role %s: %w", roleName, err)
+	}
+	return annos, nil
+}
+
+// resolveNewRole returns the instance a role is created in and its name: its ID, or its display name when
+// it has no ID, in which case named instances are chosen by its parent.
+func (r *roleBuilder) resolveNewRole(roleResource *v2.Resource) (*instance, string, error) {
+	if id := roleResource.GetId().GetResource(); id != "" {
+		inst, name, err := r.instances.resolve(id)
+		if err != nil {
+			return nil, "", err
+		}
+		return inst, strings.TrimPrefix(name, client.RolePrefix), nil
+	}
+
+	inst := r.instances.forParent(roleResource.GetParentResourceId())
+	if inst == nil {
+		return nil, "", fmt.Errorf("instance is required")
+	}
+	name := strings.TrimPrefix(strings.TrimSpace(roleResource.GetDisplayName()), client.RolePrefix)
+	if name == "" {
+		return nil, "", fmt.Errorf("role name is required")
+	}
+	return inst, name, nil
+}
+
+// resolveAssignment returns the instance of a role and the IDs, within it, of a user and the role.
+// Users can only be assigned roles of their own instance, and roles of discovered instances can't be assigned.
+func (r *roleBuilder) resolveAssignment(userResourceID *v2.ResourceId, roleResourceID *v2.ResourceId) (*instance, string, string, error) {
+	inst, roleID, err := r.instances.resolve(roleResourceID.Resource)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, "", "", err
+	}
+	userInst, userID, err := r.instances.resolve(userResourceID.Resource)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if userInst != inst {
+		return nil, "", "", fmt.Errorf("user %s and role %s belong to different Argo CD instances", userResourceID.Resource, roleResourceID.Resource)
+	}
+	return inst, userID, roleID, nil
+}
+
 // newRoleBuilder creates a new roleBuilder.
-func newRoleBuilder(client ArgoCdClient) *roleBuilder {
+func newRoleBuilder(instances instances) *roleBuilder {
 	return &roleBuilder{
 		resourceType: roleResourceType,
-		client:       client,
+		instances:    instances,
 	}
 }
@@ -10,6 +10,7 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -28,7 +29,7 @@ func TestRoleBuilder_List(t *testing.T) {
 			},
 		}
 
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		resources, nextPage, annos, err := builder.List(context.Background(), nil, &pagination.Token{})
 		require.NoError(t, err)
 		assert.Empty(t, nextPage)
@@ -44,13 +45,33 @@ func TestRoleBuilder_List(t *testing.T) {
 			},
 		}
 
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		_, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
 		require.Error(t, err)
 		assert.EqualError(t, err, "client error")
 	})
 }
 
+// TestRoleBuilder_Get tests fetching a single role for targeted syncs.
+func TestRoleBuilder_Get(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetRolesFunc: func(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
+			return []*client.Role{{Name: "admin"}, {Name: "readonly"}}, nil, nil
+		},
+	}
+	builder := newRoleBuilder(singleInstance(mockCli))
+
+	r, _, err := builder.Get(context.Background(), &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "readonly"}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "readonly", r.Id.Resource)
+	assert.Equal(t, "readonly", r.DisplayName)
+
+	r, _, err = builder.Get(context.Background(), &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "deleted"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, r)
+}
+
 func hasAnnotation(annos annotations.Annotations, target protoreflect.ProtoMessage) bool {
 	for _, a := range annos {
 		if a.TypeUrl == "type.googleapis.com/"+string(target.ProtoReflect().Descriptor().FullName()) {
@@ -63,7 +84,7 @@ func hasAnnotation(annos annotations.Annotations, target protoreflect.ProtoMessa
 // TestRoleBuilder_Entitlements tests the Entitlements method of the RoleBuilder.
 func TestRoleBuilder_Entitlements(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
-		builder := newRoleBuilder(nil)
+		builder := newRoleBuilder(singleInstance(nil))
 		resource := &v2.Resource{
 			Id:          &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "test-role"},
 			DisplayName: "Test Role",
@@ -92,7 +113,7 @@ func TestRoleBuilder_Grants(t *testing.T) {
 			},
 		}
 
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		grants, _, _, err := builder.Grants(context.Background(), roleResource, &pagination.Token{})
 		require.NoError(t, err)
 		assert.Len(t, grants, 1)
@@ -112,13 +133,49 @@ func TestRoleBuilder_Grants(t *testing.T) {
 			},
 		}
 
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		grants, _, _, err := builder.Grants(context.Background(), roleResource, &pagination.Token{})
 		require.NoError(t, err)
 		assert.Len(t, grants, 1)
 		assert.Equal(t, "user1", grants[0].Principal.Id.Resource)
 	})
 
+	t.Run("grants note whether the binding is connector-managed", func(t *testing.T) {
+		roleResource := &v2.Resource{
+			Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "role1"},
+		}
+		mockCli := &test.MockClient{
+			GetRoleUsersFunc: func(ctx context.Context, roleID string) ([]*client.Account, error) {
+				return []*client.Account{{Name: "user1"}, {Name: "user2"}}, nil
+			},
+			GetRoleBindingsFunc: func(ctx context.Context, roleID string) ([]*client.PolicyBinding, error) {
+				return []*client.PolicyBinding{
+					{Subject: "user1", Role: "role1", Managed: true, Change: &client.ChangeRecord{GrantID: "grant-1", RequestID: "req-1"}},
+					{Subject: "user2", Role: "role1"},
+				}, nil
+			},
+		}
+
+		builder := newRoleBuilder(singleInstance(mockCli))
+		grants, _, _, err := builder.Grants(context.Background(), roleResource, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, grants, 2)
+
+		metadata := &v2.GrantMetadata{}
+		managedAnnos := annotations.Annotations(grants[0].Annotations)
+		ok, err := managedAnnos.Pick(metadata)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "conductorone", metadata.Metadata.AsMap()["managed_by"])
+		assert.Equal(t, "req-1", metadata.Metadata.AsMap()["request_id"])
+
+		manualAnnos := annotations.Annotations(grants[1].Annotations)
+		ok, err = manualAnnos.Pick(metadata)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "manual", metadata.Metadata.AsMap()["managed_by"])
+	})
+
 	t.Run("error getting all user data", func(t *testing.T) {
 		roleResource := &v2.Resource{
 			Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "some-role"},
@@ -129,7 +186,7 @@ func TestRoleBuilder_Grants(t *testing.T) {
 			},
 		}
 
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		_, _, _, err := builder.Grants(context.Background(), roleResource, &pagination.Token{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to get users for role")
@@ -161,11 +218,11 @@ func TestRoleBuilder_Grant(t *testing.T) {
 				return nil, nil
 			},
 		}
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		grants, annos, err := builder.Grant(context.Background(), principal, entitlement)
 		require.NoError(t, err)
 		assert.Nil(t, annos)
-		assert.Len(t, grants, 1)
+		require.Len(t, grants, 1)
 		assert.Equal(t, "new-role", grants[0].Entitlement.Resource.Id.Resource)
 		assert.Equal(t, "test-user", grants[0].Principal.Id.Resource)
 	})
@@ -186,7 +243,7 @@ func TestRoleBuilder_Grant(t *testing.T) {
 				return nil, nil
 			},
 		}
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		grants, annos, err := builder.Grant(context.Background(), principal, entitlement)
 		require.NoError(t, err)
 		assert.NotNil(t, grants)
@@ -200,7 +257,7 @@ func TestRoleBuilder_Grant(t *testing.T) {
 				return nil, errors.New("update error")
 			},
 		}
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		_, _, err := builder.Grant(context.Background(), principal, entitlement)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to update user role")
@@ -234,7 +291,7 @@ func TestRoleBuilder_Revoke(t *testing.T) {
 				return nil, nil
 			},
 		}
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		annos, err := builder.Revoke(context.Background(), grantToRevoke)
 		require.NoError(t, err)
 		assert.Nil(t, annos)
@@ -246,7 +303,7 @@ func TestRoleBuilder_Revoke(t *testing.T) {
 				return annotations.New(&v2.GrantAlreadyRevoked{}), nil
 			},
 		}
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		annos, err := builder.Revoke(context.Background(), grantToRevoke)
 		require.NoError(t, err)
 		assert.NotNil(t, annos)
@@ -259,9 +316,88 @@ func TestRoleBuilder_Revoke(t *testing.T) {
 				return nil, errors.New("remove error")
 			},
 		}
-		builder := newRoleBuilder(mockCli)
+		builder := newRoleBuilder(singleInstance(mockCli))
 		_, err := builder.Revoke(context.Background(), grantToRevoke)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to remove user role")
 	})
 }
+
+// TestRoleBuilder_Create tests creating a role from a permission template.
+func TestRoleBuilder_Create(t *testing.T) {
+	newRole := func(t *testing.T, name string, profile map[string]interface{}) *v2.Resource {
+		r, err := resource.NewRoleResource(name, roleResourceType, name, []resource.RoleTraitOption{resource.WithRoleProfile(profile)})
+		require.NoError(t, err)
+		return r
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var created []*client.PolicyDefinition
+		mockCli := &test.MockClient{
+			CreateRoleFunc: func(ctx context.Context, role string, policies []*client.PolicyDefinition) (annotations.Annotations, error) {
+				assert.Equal(t, "team-a", role)
+				created = policies
+				return nil, nil
+			},
+		}
+		role := newRole(t, "team-a", map[string]interface{}{
+			"permissions": []interface{}{
+				"applications, get, team-a/*",
+				map[string]interface{}{"resource": "applications", "action": "delete", "object": "team-a/*", "effect": "deny"},
+			},
+		})
+
+		r, _, err := newRoleBuilder(singleInstance(mockCli)).Create(context.Background(), role)
+		require.NoError(t, err)
+		assert.Equal(t, "team-a", r.Id.Resource)
+		assert.Equal(t, []*client.PolicyDefinition{
+			{Resource: "applications", Action: "get", Object: "team-a/*", Effect: "allow"},
+			{Resource: "applications", Action: "delete", Object: "team-a/*", Effect: "deny"},
+		}, created)
+	})
+
+	t.Run("named instance", func(t *testing.T) {
+		mockCli := &test.MockClient{}
+		role := newRole(t, "prod:team-a", map[string]interface{}{"permissions": []interface{}{"logs, get, */*"}})
+
+		r, _, err := newRoleBuilder(instances{{name: "prod", client: mockCli}, {name: "staging", client: mockCli}}).Create(context.Background(), role)
+		require.NoError(t, err)
+		assert.Equal(t, "prod:team-a", r.Id.Resource)
+		assert.Equal(t, "prod", r.ParentResourceId.Resource)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		builder := newRoleBuilder(singleInstance(&test.MockClient{}))
+
+		_, _, err := builder.Create(context.Background(), newRole(t, "team-a", map[string]interface{}{}))
+		assert.ErrorContains(t, err, "a permissions list is required")
+
+		_, _, err = builder.Create(context.Background(), newRole(t, "team-a", map[string]interface{}{"permissions": []interface{}{"applications, get"}}))
+		assert.ErrorContains(t, err, "permissions[0]: object is required")
+
+		_, _, err = builder.Create(context.Background(), newRole(t, "team-a", map[string]interface{}{"permissions": []interface{}{"applications, get, */*, maybe"}}))
+		assert.ErrorContains(t, err, `invalid effect "maybe"`)
+	})
+}
+
+// TestRoleBuilder_Delete tests deleting a role.
+func TestRoleBuilder_Delete(t *testing.T) {
+	var deleted string
+	mockCli := &test.MockClient{
+		DeleteRoleFunc: func(ctx context.Context, role string) (annotations.Annotations, error) {
+			if role == "readonly" {
+				return nil, errors.New("role readonly is a built-in Argo CD role and can't be deleted")
+			}
+			deleted = role
+			return nil, nil
+		},
+	}
+	builder := newRoleBuilder(singleInstance(mockCli))
+
+	_, err := builder.Delete(context.Background(), &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "team-a"})
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", deleted)
+
+	_, err = builder.Delete(context.Background(), &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "readonly"})
+	assert.ErrorContains(t, err, "failed to delete role readonly")
+}
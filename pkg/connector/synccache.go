@@ -0,0 +1,66 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/conductorone/baton-argo-cd/pkg/client"
+)
+
+// syncCache holds what a sync reads once from an instance and reuses for each of its resources: the Applications,
+// listed once rather than for each project and ApplicationSet, and the RBAC policy, loaded once rather than for each
+// resource it grants on. Validate resets it, since the SDK validates the connector at the start of every sync.
+type syncCache struct {
+	mu           sync.Mutex
+	applications []*client.Application
+	evaluator    *client.PolicyEvaluator
+	roles        []*client.Role
+}
+
+// resetCache drops what the previous sync read from the instance.
+func (i *instance) resetCache() {
+	i.cache.mu.Lock()
+	defer i.cache.mu.Unlock()
+
+	i.cache.applications = nil
+	i.cache.evaluator = nil
+	i.cache.roles = nil
+}
+
+// applications returns the Applications of the instance, listed on the first call of the sync.
+func (i *instance) applications(ctx context.Context) ([]*client.Application, error) {
+	i.cache.mu.Lock()
+	defer i.cache.mu.Unlock()
+
+	if i.cache.applications == nil {
+		applications, err := i.client.GetApplications(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch applications: %w", err)
+		}
+		if applications == nil {
+			applications = []*client.Application{}
+		}
+		i.cache.applications = applications
+	}
+	return i.cache.applications, nil
+}
+
+// rbacPolicy returns the RBAC policy evaluator and the roles of the instance, loaded on the first call of the sync.
+func (i *instance) rbacPolicy(ctx context.Context) (*client.PolicyEvaluator, []*client.Role, error) {
+	i.cache.mu.Lock()
+	defer i.cache.mu.Unlock()
+
+	if i.cache.evaluator == nil {
+		evaluator, err := i.client.GetPolicyEvaluator(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load rbac policy: %w", err)
+		}
+		roles, _, err := i.client.GetRoles(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch roles: %w", err)
+		}
+		i.cache.evaluator, i.cache.roles = evaluator, roles
+	}
+	return i.cache.evaluator, i.cache.roles, nil
+}
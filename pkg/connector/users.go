@@ -3,18 +3,22 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/conductorone/baton-argo-cd/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
-// userBuilder implements the ResourceSyncer and AccountManager interfaces for Argo CD users.
+// userBuilder implements the ResourceSyncer, ResourceTargetedSyncer and AccountManager interfaces for Argo CD users.
 type userBuilder struct {
 	resourceType *v2.ResourceType
-	client       ArgoCdClient
+	instances    instances
 }
 
 // ResourceType returns the resource type for users.
@@ -22,24 +26,86 @@ func (u *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return userResourceType
 }
 
-// List returns all users from Argo CD as resource objects.
+// List returns all users from Argo CD as resource objects: local accounts and Dex static users.
 func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	accounts, err := u.client.GetAccounts(ctx)
+	l := ctxzap.Extract(ctx)
+
+	inst := u.instances.forParent(parentResourceID)
+	if inst == nil {
+		return nil, "", nil, nil
+	}
+
+	accounts, err := inst.client.GetAccounts(ctx)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("failed to fetch user data: %w", err)
 	}
 
+	owner := getControllerOwner(ctx, inst.client, client.ConfigMapResource, client.ArgoCDConfigMapName)
+
 	var resources []*v2.Resource
 	for _, account := range accounts {
-		accountResource, err := parseAccountResource(account)
+		accountResource, err := parseAccountResource(account, owner)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("failed to parse account %s: %w", account.Name, err)
 		}
-		resources = append(resources, accountResource)
+		resources = append(resources, inst.scope(accountResource))
+	}
+
+	dexUsers, err := inst.client.GetDexUsers(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch dex users: %w", err)
 	}
+	for _, user := range dexUsers {
+		if slices.ContainsFunc(accounts, func(account *client.Account) bool { return account.Name == user.Email }) {
+			l.Warn("skipping dex user whose email is also a local account name", zap.String("email", user.Email))
+			continue
+		}
+		dexResource, err := parseDexUserResource(user)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to parse dex user %s: %w", user.Email, err)
+		}
+		resources = append(resources, inst.scope(dexResource))
+	}
+
 	return resources, "", nil, nil
 }
 
+// Get returns a single user: the local account with the resource's name, or else the Dex static user with it
+// as email. It returns nil if there is neither.
+func (u *userBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, _ *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	inst, name, err := u.instances.resolve(resourceID.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accounts, err := inst.client.GetAccounts(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch user data: %w", err)
+	}
+	if i := slices.IndexFunc(accounts, func(account *client.Account) bool { return account.Name == name }); i >= 0 {
+		owner := getControllerOwner(ctx, inst.client, client.ConfigMapResource, client.ArgoCDConfigMapName)
+		accountResource, err := parseAccountResource(accounts[i], owner)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse account %s: %w", name, err)
+		}
+		return inst.scope(accountResource), nil, nil
+	}
+
+	dexUsers, err := inst.client.GetDexUsers(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch dex users: %w", err)
+	}
+	if i := slices.IndexFunc(dexUsers, func(user *client.DexUser) bool { return user.Email == name }); i >= 0 {
+		dexResource, err := parseDexUserResource(dexUsers[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse dex user %s: %w", name, err)
+		}
+		return inst.scope(dexResource), nil, nil
+	}
+
+	return nil, nil, nil
+}
+
 // Entitlements returns an empty slice as users don't have entitlements.
 func (u *userBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	return nil, "", nil, nil
@@ -50,17 +116,21 @@ func (u *userBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 	return nil, "", nil, nil
 }
 
-// CreateAccountCapabilityDetails declares support for account provisioning with random password generation.
+// CreateAccountCapabilityDetails declares support for account provisioning with random password generation,
+// and without a password for service accounts that only use API tokens.
 func (u *userBuilder) CreateAccountCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
 	return &v2.CredentialDetailsAccountProvisioning{
 		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
 			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
 		},
 		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
 	}, nil, nil
 }
 
-// CreateAccount provisions a new Argo CD user based on AccountInfo and CredentialOptions.
+// CreateAccount provisions a new Argo CD user based on AccountInfo and CredentialOptions, recording the email of
+// the identity it is created for as its owner's.
+// With the no-password option, the account can only use API tokens and no password is returned.
 func (u *userBuilder) CreateAccount(
 	ctx context.Context,
 	accountInfo *v2.AccountInfo,
@@ -76,29 +146,47 @@ func (u *userBuilder) CreateAccount(
 		return nil, nil, nil, err
 	}
 
-	password, err := generateCredentials(credentialOptions)
+	inst, err := u.extractInstance(accountInfo)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to generate password: %w", err)
+		return nil, nil, nil, err
 	}
 
-	newUser, annos, err := u.client.CreateAccount(ctx, username, password)
+	var password string
+	if credentialOptions.GetNoPassword() == nil {
+		passwordPattern, err := inst.client.GetPasswordPattern(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get password pattern: %w", err)
+		}
+
+		password, err = generateCredentials(credentialOptions, passwordPattern)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+	}
+
+	newUser, annos, err := inst.client.CreateAccount(ctx, username, password, u.extractEmail(accountInfo))
 	if err != nil {
 		return nil, nil, annos, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	userResource, err := parseAccountResource(newUser)
+	userResource, err := parseAccountResource(newUser, nil)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to parse created user: %w", err)
 	}
 
-	passwordResult := &v2.PlaintextData{
-		Name:  "password",
-		Bytes: []byte(password),
+	// In dry-run mode the account doesn't exist, so its generated password is never handed out. An account
+	// pending review gets its password once the branch is merged, since its hash is already in argocd-secret.
+	var plaintextData []*v2.PlaintextData
+	if notApplied := client.GetNotApplied(annos); password != "" && (notApplied == nil || notApplied.Reason != client.NotAppliedDryRun) {
+		plaintextData = append(plaintextData, &v2.PlaintextData{
+			Name:  "password",
+			Bytes: []byte(password),
+		})
 	}
 
 	return &v2.CreateAccountResponse_SuccessResult{
-		Resource: userResource,
-	}, []*v2.PlaintextData{passwordResult}, annos, nil
+		Resource: inst.scope(userResource),
+	}, plaintextData, annos, nil
 }
 
 // extractUsername safely retrieves the username from the AccountInfo protobuf message.
@@ -122,10 +210,50 @@ func (u *userBuilder) extractUsername(accountInfo *v2.AccountInfo) (string, erro
 	return "", fmt.Errorf("username is required")
 }
 
+// extractEmail returns the email of the identity an account is created for: its primary email, or else its
+// first one or the `email` profile field. It returns "" if there is none.
+func (u *userBuilder) extractEmail(accountInfo *v2.AccountInfo) string {
+	emails := accountInfo.GetEmails()
+	for _, email := range emails {
+		if email.GetIsPrimary() && strings.TrimSpace(email.GetAddress()) != "" {
+			return strings.TrimSpace(email.GetAddress())
+		}
+	}
+	for _, email := range emails {
+		if address := strings.TrimSpace(email.GetAddress()); address != "" {
+			return address
+		}
+	}
+	email, _ := accountInfo.GetProfile().AsMap()["email"].(string)
+	return strings.TrimSpace(email)
+}
+
+// extractInstance returns the Argo CD instance an account is created in. With several instances, it is
+// named by the `instance` profile field, and can't be a discovered instance.
+func (u *userBuilder) extractInstance(accountInfo *v2.AccountInfo) (*instance, error) {
+	if !u.instances.named() {
+		return u.instances[0], nil
+	}
+
+	name, _ := accountInfo.GetProfile().AsMap()["instance"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("instance is required")
+	}
+	inst, err := u.instances.get(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := inst.writable(); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
 // newUserBuilder creates a new userBuilder instance.
-func newUserBuilder(client ArgoCdClient) *userBuilder {
+func newUserBuilder(instances instances) *userBuilder {
 	return &userBuilder{
 		resourceType: userResourceType,
-		client:       client,
+		instances:    instances,
 	}
 }
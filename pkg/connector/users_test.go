@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/conductorone/baton-argo-cd/pkg/client"
 	"github.com/conductorone/baton-argo-cd/test"
@@ -28,7 +29,7 @@ func TestUserBuilder_List(t *testing.T) {
 			},
 		}
 
-		builder := newUserBuilder(mockCli)
+		builder := newUserBuilder(singleInstance(mockCli))
 		resources, nextPage, annos, err := builder.List(context.Background(), nil, &pagination.Token{})
 		require.NoError(t, err)
 		assert.Empty(t, nextPage)
@@ -37,6 +38,133 @@ func TestUserBuilder_List(t *testing.T) {
 		assert.Equal(t, "user1", resources[0].DisplayName)
 	})
 
+	t.Run("local account traits", func(t *testing.T) {
+		passwordMtime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		mockCli := &test.MockClient{
+			GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+				return []*client.Account{
+					{Name: "alice", Enabled: true, Capabilities: []string{"apiKey", "login"}, PasswordMtime: passwordMtime},
+					{Name: "ci", Enabled: false, Capabilities: []string{"apiKey"}, Tokens: []client.AccountToken{
+						{ID: "deploy", IssuedAt: 1700000000, ExpiresAt: 1700086400},
+						{ID: "nightly", IssuedAt: 1720000000},
+					}},
+				}, nil
+			},
+		}
+
+		resources, _, _, err := newUserBuilder(singleInstance(mockCli)).List(context.Background(), nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+
+		traits := make([]*v2.UserTrait, 2)
+		for i, r := range resources {
+			traits[i] = &v2.UserTrait{}
+			annos := annotations.Annotations(r.Annotations)
+			ok, err := annos.Pick(traits[i])
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+
+		human := traits[0]
+		assert.Equal(t, v2.UserTrait_ACCOUNT_TYPE_HUMAN, human.GetAccountType())
+		assert.Equal(t, v2.UserTrait_Status_STATUS_ENABLED, human.GetStatus().GetStatus())
+		assert.Equal(t, "alice", human.GetLogin())
+		// The password change time isn't the creation time, which Argo CD doesn't record.
+		assert.Nil(t, human.GetCreatedAt())
+		profile := human.GetProfile().AsMap()
+		assert.Equal(t, []interface{}{"apiKey", "login"}, profile["capabilities"])
+		assert.Equal(t, true, profile["login"])
+		assert.Equal(t, "2024-03-01T10:00:00Z", profile["password_changed_at"])
+		assert.Equal(t, float64(0), profile["token_count"])
+
+		service := traits[1]
+		assert.Equal(t, v2.UserTrait_ACCOUNT_TYPE_SERVICE, service.GetAccountType())
+		assert.Equal(t, v2.UserTrait_Status_STATUS_DISABLED, service.GetStatus().GetStatus())
+		assert.Nil(t, service.GetCreatedAt())
+		profile = service.GetProfile().AsMap()
+		assert.Equal(t, true, profile["api_key"])
+		assert.Equal(t, false, profile["login"])
+		assert.Equal(t, float64(2), profile["token_count"])
+		assert.Equal(t, float64(1), profile["expired_token_count"])
+		assert.NotContains(t, profile, "password_changed_at")
+	})
+
+	t.Run("dex static users", func(t *testing.T) {
+		mockCli := &test.MockClient{
+			GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+				return []*client.Account{{Name: "admin", Enabled: true}, {Name: "shadow@example.com", Enabled: true}}, nil
+			},
+			GetDexUsersFunc: func(ctx context.Context) ([]*client.DexUser, error) {
+				return []*client.DexUser{
+					{Email: "breakglass@example.com", Username: "breakglass", UserID: "08a8684b", Enabled: true},
+					{Email: "shadow@example.com", Username: "shadow", Enabled: true},
+				}, nil
+			},
+		}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 3)
+
+		dexUser := resources[2]
+		assert.Equal(t, "breakglass@example.com", dexUser.Id.Resource)
+		assert.Equal(t, "breakglass", dexUser.DisplayName)
+
+		userTrait := &v2.UserTrait{}
+		annos := annotations.Annotations(dexUser.Annotations)
+		ok, err := annos.Pick(userTrait)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "dex", userTrait.GetProfile().AsMap()["account_type"])
+		assert.Equal(t, "breakglass", userTrait.GetLogin())
+		assert.Equal(t, "breakglass@example.com", userTrait.GetEmails()[0].GetAddress())
+		assert.Equal(t, v2.UserTrait_Status_STATUS_ENABLED, userTrait.GetStatus().GetStatus())
+	})
+
+	t.Run("reports the GitOps controller managing accounts", func(t *testing.T) {
+		mockCli := &test.MockClient{
+			GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+				return []*client.Account{{Name: "user1", Enabled: true}}, nil
+			},
+			GetControllerOwnerFunc: func(ctx context.Context, kind string, name string) (*client.ControllerOwner, error) {
+				assert.Equal(t, client.ArgoCDConfigMapName, name)
+				return &client.ControllerOwner{Object: kind + "/" + name, Controller: client.ControllerArgoCD, Application: "argocd-config"}, nil
+			},
+		}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+
+		userTrait := &v2.UserTrait{}
+		annos := annotations.Annotations(resources[0].Annotations)
+		ok, err := annos.Pick(userTrait)
+		require.NoError(t, err)
+		require.True(t, ok)
+		profile := userTrait.GetProfile().AsMap()
+		assert.Equal(t, "argocd", profile["gitops_controller"])
+		assert.Equal(t, "argocd-config", profile["gitops_application"])
+		assert.Equal(t, "cm/argocd-cm", profile["gitops_managed_object"])
+	})
+
+	t.Run("owner lookup failure does not fail the sync", func(t *testing.T) {
+		mockCli := &test.MockClient{
+			GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+				return []*client.Account{{Name: "user1", Enabled: true}}, nil
+			},
+			GetControllerOwnerFunc: func(ctx context.Context, kind string, name string) (*client.ControllerOwner, error) {
+				return nil, errors.New("forbidden")
+			},
+		}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		resources, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
+		require.NoError(t, err)
+		assert.Len(t, resources, 1)
+	})
+
 	t.Run("client error", func(t *testing.T) {
 		mockCli := &test.MockClient{
 			GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
@@ -44,16 +172,63 @@ func TestUserBuilder_List(t *testing.T) {
 			},
 		}
 
-		builder := newUserBuilder(mockCli)
+		builder := newUserBuilder(singleInstance(mockCli))
 		_, _, _, err := builder.List(context.Background(), nil, &pagination.Token{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to fetch user data")
 	})
 }
 
+// TestUserBuilder_Get tests fetching a single user for targeted syncs.
+func TestUserBuilder_Get(t *testing.T) {
+	mockCli := &test.MockClient{
+		GetAccountsFunc: func(ctx context.Context) ([]*client.Account, error) {
+			return []*client.Account{{Name: "alice", Enabled: true, Capabilities: []string{"login"}}}, nil
+		},
+		GetDexUsersFunc: func(ctx context.Context) ([]*client.DexUser, error) {
+			return []*client.DexUser{{Email: "bob@example.com", Username: "bob", Enabled: true}}, nil
+		},
+	}
+	builder := newUserBuilder(singleInstance(mockCli))
+
+	t.Run("local account", func(t *testing.T) {
+		r, _, err := builder.Get(context.Background(), &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "alice"}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "alice", r.Id.Resource)
+		assert.Equal(t, "alice", r.DisplayName)
+	})
+
+	t.Run("dex user", func(t *testing.T) {
+		r, _, err := builder.Get(context.Background(), &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "bob@example.com"}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "bob@example.com", r.Id.Resource)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		r, _, err := builder.Get(context.Background(), &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "carol"}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, r)
+	})
+
+	t.Run("named instance", func(t *testing.T) {
+		builder := newUserBuilder(instances{{name: "prod", client: mockCli}, {name: "staging", client: &test.MockClient{}}})
+		r, _, err := builder.Get(context.Background(), &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "prod:alice"}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "prod:alice", r.Id.Resource)
+		assert.Equal(t, "prod", r.ParentResourceId.Resource)
+
+		r, _, err = builder.Get(context.Background(), &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "staging:alice"}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, r)
+	})
+}
+
 // TestUserBuilder_Entitlements tests the Entitlements method.
 func TestUserBuilder_Entitlements(t *testing.T) {
-	builder := newUserBuilder(nil)
+	builder := newUserBuilder(singleInstance(nil))
 	resource := &v2.Resource{
 		Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "test-user"},
 	}
@@ -67,7 +242,7 @@ func TestUserBuilder_Entitlements(t *testing.T) {
 
 // TestUserBuilder_Grants tests the Grants method.
 func TestUserBuilder_Grants(t *testing.T) {
-	builder := newUserBuilder(nil)
+	builder := newUserBuilder(singleInstance(nil))
 	resource := &v2.Resource{
 		Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "test-user"},
 	}
@@ -81,7 +256,7 @@ func TestUserBuilder_Grants(t *testing.T) {
 
 // TestUserBuilder_CreateAccountCapabilityDetails tests capability details.
 func TestUserBuilder_CreateAccountCapabilityDetails(t *testing.T) {
-	builder := newUserBuilder(nil)
+	builder := newUserBuilder(singleInstance(nil))
 
 	details, annos, err := builder.CreateAccountCapabilityDetails(context.Background())
 	require.NoError(t, err)
@@ -89,6 +264,7 @@ func TestUserBuilder_CreateAccountCapabilityDetails(t *testing.T) {
 	assert.Nil(t, annos)
 
 	assert.Contains(t, details.SupportedCredentialOptions, v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD)
+	assert.Contains(t, details.SupportedCredentialOptions, v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD)
 	assert.Equal(t, v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD, details.PreferredCredentialOption)
 }
 
@@ -96,7 +272,7 @@ func TestUserBuilder_CreateAccountCapabilityDetails(t *testing.T) {
 func TestUserBuilder_CreateAccount(t *testing.T) {
 	t.Run("success with login", func(t *testing.T) {
 		mockCli := &test.MockClient{
-			CreateAccountFunc: func(ctx context.Context, username string, password string) (*client.Account, annotations.Annotations, error) {
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
 				assert.Equal(t, "test-user", username)
 				assert.NotEmpty(t, password)
 				return &client.Account{
@@ -107,7 +283,7 @@ func TestUserBuilder_CreateAccount(t *testing.T) {
 			},
 		}
 
-		builder := newUserBuilder(mockCli)
+		builder := newUserBuilder(singleInstance(mockCli))
 		accountInfo := &v2.AccountInfo{
 			Login: "test-user",
 		}
@@ -127,8 +303,110 @@ func TestUserBuilder_CreateAccount(t *testing.T) {
 		assert.NotEmpty(t, plaintextData[0].Bytes)
 	})
 
+	t.Run("API-only account without password", func(t *testing.T) {
+		mockCli := &test.MockClient{
+			GetPasswordPatternFunc: func(ctx context.Context) (string, error) {
+				t.Fatal("no password is generated")
+				return "", nil
+			},
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
+				assert.Empty(t, password)
+				return &client.Account{Name: username, Enabled: true, Capabilities: []string{"apiKey"}}, nil, nil
+			},
+		}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		resp, plaintextData, _, err := builder.CreateAccount(context.Background(), &v2.AccountInfo{Login: "ci-bot"}, &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_NoPassword_{NoPassword: &v2.CredentialOptions_NoPassword{}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, plaintextData)
+		assert.Equal(t, "ci-bot", resp.(*v2.CreateAccountResponse_SuccessResult).Resource.Id.Resource)
+	})
+
+	t.Run("dry run returns no password for the account it didn't create", func(t *testing.T) {
+		dryRun := annotations.New((&client.NotApplied{Reason: client.NotAppliedDryRun}).Annotation())
+		mockCli := &test.MockClient{
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
+				return &client.Account{Name: username, Enabled: true, Capabilities: []string{"apiKey", "login"}}, dryRun, nil
+			},
+		}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		resp, plaintextData, annos, err := builder.CreateAccount(context.Background(), &v2.AccountInfo{Login: "test-user"}, &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 16}},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.Empty(t, plaintextData)
+		assert.Equal(t, &client.NotApplied{Reason: client.NotAppliedDryRun}, client.GetNotApplied(annos))
+	})
+
+	t.Run("password matches passwordPattern", func(t *testing.T) {
+		var created string
+		mockCli := &test.MockClient{
+			GetPasswordPatternFunc: func(ctx context.Context) (string, error) {
+				return "^[a-z0-9]{16,20}$", nil
+			},
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
+				created = password
+				return &client.Account{Name: username, Enabled: true}, nil, nil
+			},
+		}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		_, plaintextData, _, err := builder.CreateAccount(context.Background(), &v2.AccountInfo{Login: "test-user"}, &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 12}},
+		})
+		require.NoError(t, err)
+		assert.Regexp(t, "^[a-z0-9]{16}$", created)
+		assert.Equal(t, created, string(plaintextData[0].Bytes))
+
+		_, _, _, err = builder.CreateAccount(context.Background(), &v2.AccountInfo{Login: "test-user"}, &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 24}},
+		})
+		assert.ErrorContains(t, err, "requested password length 24 exceeds the maximum of 20")
+	})
+
+	t.Run("records the owner's email", func(t *testing.T) {
+		var recorded []string
+		mockCli := &test.MockClient{
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
+				recorded = append(recorded, email)
+				return &client.Account{Name: username, Enabled: true, Capabilities: []string{"apiKey"}, Email: email}, nil, nil
+			},
+		}
+		noPassword := &v2.CredentialOptions{Options: &v2.CredentialOptions_NoPassword_{NoPassword: &v2.CredentialOptions_NoPassword{}}}
+
+		builder := newUserBuilder(singleInstance(mockCli))
+		resp, _, _, err := builder.CreateAccount(context.Background(), &v2.AccountInfo{
+			Login: "alice",
+			Emails: []*v2.AccountInfo_Email{
+				{Address: "alice@personal.example"},
+				{Address: "alice@example.com", IsPrimary: true},
+			},
+		}, noPassword)
+		require.NoError(t, err)
+		_, _, _, err = builder.CreateAccount(context.Background(), &v2.AccountInfo{
+			Login:   "bob",
+			Profile: createProfile(map[string]interface{}{"email": "bob@example.com"}),
+		}, noPassword)
+		require.NoError(t, err)
+		_, _, _, err = builder.CreateAccount(context.Background(), &v2.AccountInfo{Login: "ci"}, noPassword)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice@example.com", "bob@example.com", ""}, recorded)
+
+		userTrait := &v2.UserTrait{}
+		annos := annotations.Annotations(resp.(*v2.CreateAccountResponse_SuccessResult).Resource.Annotations)
+		ok, err := annos.Pick(userTrait)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "alice@example.com", userTrait.GetEmails()[0].GetAddress())
+		assert.True(t, userTrait.GetEmails()[0].GetIsPrimary())
+	})
+
 	t.Run("error missing username", func(t *testing.T) {
-		builder := newUserBuilder(nil)
+		builder := newUserBuilder(singleInstance(nil))
 		accountInfo := &v2.AccountInfo{
 			Profile: createProfile(map[string]interface{}{
 				"email": "test@example.com",
@@ -149,12 +427,12 @@ func TestUserBuilder_CreateAccount(t *testing.T) {
 
 	t.Run("error client create fails", func(t *testing.T) {
 		mockCli := &test.MockClient{
-			CreateAccountFunc: func(ctx context.Context, username string, password string) (*client.Account, annotations.Annotations, error) {
+			CreateAccountFunc: func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
 				return nil, nil, errors.New("create account failed")
 			},
 		}
 
-		builder := newUserBuilder(mockCli)
+		builder := newUserBuilder(singleInstance(mockCli))
 		accountInfo := &v2.AccountInfo{
 			Login: "test-user",
 		}
@@ -0,0 +1,391 @@
+package gitops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configMapKind = "ConfigMap"
+	dataPrefix    = "/data/"
+)
+
+// manifestTarget locates the data of a ConfigMap inside a manifest file. Exactly one of data and ops is set:
+// data is the `data` mapping of a ConfigMap manifest or strategic-merge patch, ops is the sequence of a
+// JSON 6902 patch (kustomize `patchesJson6902` / `patches` with a JSON patch), which is assumed to target
+// the ConfigMap the file is configured for.
+type manifestTarget struct {
+	data *yaml.Node
+	ops  *yaml.Node
+}
+
+// textEdit replaces lines [start, end) of a file with the given lines.
+type textEdit struct {
+	start int
+	end   int
+	lines []string
+}
+
+// ReadData returns the data keys of the named ConfigMap as defined in the manifest content.
+func ReadData(content []byte, name string) (map[string]string, error) {
+	target, err := findTarget(content, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string)
+	if target.data != nil {
+		for i := 0; i+1 < len(target.data.Content); i += 2 {
+			data[target.data.Content[i].Value] = target.data.Content[i+1].Value
+		}
+		return data, nil
+	}
+
+	for _, op := range target.ops.Content {
+		key, ok := opDataKey(op)
+		if !ok {
+			continue
+		}
+		switch nodeValue(mappingValue(op, "op")) {
+		case "add", "replace":
+			if value := mappingValue(op, "value"); value != nil {
+				data[key] = value.Value
+			}
+		case "remove":
+			delete(data, key)
+		}
+	}
+	return data, nil
+}
+
+// EditData sets and removes data keys of the named ConfigMap in the manifest content.
+// Only the lines holding the affected keys change; comments, ordering and formatting elsewhere are kept.
+func EditData(content []byte, name string, set map[string]string, remove []string) ([]byte, error) {
+	target, err := findTarget(content, name)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+
+	var edits []textEdit
+	if target.data != nil {
+		edits = dataEdits(lines, target.data, set, remove)
+	} else {
+		edits = opsEdits(lines, target.ops, set, remove)
+	}
+
+	// Apply from the bottom up so earlier line numbers stay valid. Insertions share the
+	// position of the end of the mapping, so keep their relative order stable.
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, edit := range edits {
+		tail := append([]string{}, lines[edit.end:]...)
+		lines = append(append(lines[:edit.start], edit.lines...), tail...)
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// findTarget decodes every document of the manifest and locates the data of the named ConfigMap.
+func findTarget(content []byte, name string) (*manifestTarget, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		root := doc.Content[0]
+		switch root.Kind {
+		case yaml.MappingNode:
+			if nodeValue(mappingValue(root, "kind")) != configMapKind ||
+				nodeValue(mappingValue(mappingValue(root, "metadata"), "name")) != name {
+				continue
+			}
+			data := mappingValue(root, "data")
+			if data == nil || data.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("ConfigMap '%s' in manifest has no data section", name)
+			}
+			if data.Style&yaml.FlowStyle != 0 {
+				return nil, fmt.Errorf("ConfigMap '%s' in manifest uses a flow-style data section, which can't be edited in place", name)
+			}
+			return &manifestTarget{data: data}, nil
+
+		case yaml.SequenceNode:
+			if isJSONPatch(root) {
+				return &manifestTarget{ops: root}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("manifest does not define ConfigMap '%s'", name)
+}
+
+// dataEdits computes the line edits for a ConfigMap `data` mapping.
+func dataEdits(lines []string, data *yaml.Node, set map[string]string, remove []string) []textEdit {
+	var edits []textEdit
+	indent := ""
+	end := data.Line - 1
+	for i := 0; i+1 < len(data.Content); i += 2 {
+		key := data.Content[i]
+		indent = strings.Repeat(" ", key.Column-1)
+		start, stop := keyRegion(lines, key)
+		end = stop
+
+		if value, ok := set[key.Value]; ok {
+			if value != data.Content[i+1].Value {
+				edits = append(edits, textEdit{start: start, end: stop, lines: renderPair(indent, blockIndent(lines, start, stop, indent), renderKey(key), value)})
+			}
+		} else if contains(remove, key.Value) {
+			edits = append(edits, textEdit{start: start, end: stop})
+		}
+	}
+
+	for _, key := range sortedKeys(set) {
+		if mappingValue(data, key) != nil {
+			continue
+		}
+		edits = append(edits, textEdit{start: end, end: end, lines: renderPair(indent, indent+"  ", renderScalar(key), set[key])})
+	}
+	return edits
+}
+
+// opsEdits computes the line edits for a JSON 6902 patch.
+func opsEdits(lines []string, ops *yaml.Node, set map[string]string, remove []string) []textEdit {
+	var edits []textEdit
+	found := make(map[string]bool)
+	end := len(lines)
+	prefix := "- "
+	for _, op := range ops.Content {
+		key, ok := opDataKey(op)
+		first := lines[op.Line-1]
+		prefix = first[:op.Column-1]
+		start, stop := itemRegion(lines, op)
+		end = stop
+		if !ok {
+			continue
+		}
+
+		if value, ok := set[key]; ok {
+			found[key] = true
+			valueKey := mappingKey(op, "value")
+			if valueKey == nil || nodeValue(mappingValue(op, "op")) == "remove" {
+				edits = append(edits, textEdit{start: start, end: stop, lines: renderOp(prefix, key, value)})
+				continue
+			}
+			if value == mappingValue(op, "value").Value {
+				continue
+			}
+			valueStart, valueStop := keyRegion(lines, valueKey)
+			indent := strings.Repeat(" ", valueKey.Column-1)
+			edits = append(edits, textEdit{start: valueStart, end: valueStop, lines: renderPair(indent, blockIndent(lines, valueStart, valueStop, indent), renderKey(valueKey), value)})
+		} else if contains(remove, key) {
+			// Dropping the operation stops the patch from setting the key; the base decides whether it exists.
+			edits = append(edits, textEdit{start: start, end: stop})
+		}
+	}
+
+	for _, key := range sortedKeys(set) {
+		if !found[key] {
+			edits = append(edits, textEdit{start: end, end: end, lines: renderOp(prefix, key, set[key])})
+		}
+	}
+	return edits
+}
+
+// keyRegion returns the lines [start, end) spanned by a mapping key and its value,
+// excluding trailing blank lines.
+func keyRegion(lines []string, key *yaml.Node) (int, int) {
+	start := key.Line - 1
+	return start, blockEnd(lines, start, key.Column-1)
+}
+
+// itemRegion returns the lines [start, end) spanned by a sequence item whose content starts at node.
+func itemRegion(lines []string, node *yaml.Node) (int, int) {
+	start := node.Line - 1
+	dash := strings.LastIndex(lines[start][:node.Column-1], "-")
+	return start, blockEnd(lines, start, dash)
+}
+
+// blockEnd returns the end of the block starting at line start, i.e. the first following line
+// indented at or left of column, ignoring trailing blank lines.
+func blockEnd(lines []string, start int, column int) int {
+	end := start + 1
+	last := end
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line)-len(strings.TrimLeft(line, " ")) <= column {
+			break
+		}
+		last = end + 1
+	}
+	return last
+}
+
+// blockIndent returns the indentation of the content lines of the value spanning lines [start, end),
+// so a rewritten block scalar keeps it, defaulting to two spaces more than the key.
+func blockIndent(lines []string, start int, end int, keyIndent string) string {
+	for _, line := range lines[start+1 : end] {
+		if strings.TrimSpace(line) != "" {
+			return line[:len(line)-len(strings.TrimLeft(line, " "))]
+		}
+	}
+	return keyIndent + "  "
+}
+
+// renderPair renders `key: value` at the given indentation, using a literal block scalar
+// indented by contentIndent for multi-line values.
+func renderPair(indent string, contentIndent string, key string, value string) []string {
+	if !strings.Contains(value, "\n") {
+		return []string{indent + key + ": " + renderScalar(value)}
+	}
+
+	header := "|"
+	if strings.HasPrefix(value, " ") {
+		header += strconv.Itoa(len(contentIndent) - len(indent))
+	}
+	switch {
+	case !strings.HasSuffix(value, "\n"):
+		header += "-"
+	case strings.HasSuffix(value, "\n\n"):
+		header += "+"
+	}
+
+	out := []string{indent + key + ": " + header}
+	for _, line := range strings.Split(strings.TrimSuffix(value, "\n"), "\n") {
+		if line == "" {
+			out = append(out, "")
+			continue
+		}
+		out = append(out, contentIndent+line)
+	}
+	return out
+}
+
+// renderOp renders a JSON 6902 `add` operation for a data key as a sequence item.
+func renderOp(prefix string, key string, value string) []string {
+	indent := strings.Repeat(" ", len(prefix))
+	out := []string{
+		prefix + "op: add",
+		indent + "path: " + renderScalar(dataPrefix+escapeJSONPointer(key)),
+	}
+	return append(out, renderPair(indent, indent+"  ", "value", value)...)
+}
+
+// renderKey renders a mapping key in its original quoting style.
+func renderKey(key *yaml.Node) string {
+	switch {
+	case key.Style&yaml.DoubleQuotedStyle != 0:
+		return fmt.Sprintf("%q", key.Value)
+	case key.Style&yaml.SingleQuotedStyle != 0:
+		return "'" + strings.ReplaceAll(key.Value, "'", "''") + "'"
+	}
+	return key.Value
+}
+
+// renderScalar renders a single-line string as a YAML scalar, quoting it only when needed.
+func renderScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// isJSONPatch reports whether a sequence looks like a JSON 6902 patch.
+func isJSONPatch(seq *yaml.Node) bool {
+	if len(seq.Content) == 0 {
+		return false
+	}
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode || mappingValue(item, "op") == nil || mappingValue(item, "path") == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// opDataKey returns the data key a JSON 6902 operation targets.
+func opDataKey(op *yaml.Node) (string, bool) {
+	path, ok := strings.CutPrefix(nodeValue(mappingValue(op, "path")), dataPrefix)
+	if !ok || path == "" {
+		return "", false
+	}
+	return unescapeJSONPointer(path), true
+}
+
+// mappingKey returns the key node for key in a mapping node, or nil.
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// nodeValue returns the scalar value of a node, or "" for nil.
+func nodeValue(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.Value
+}
+
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func unescapeJSONPointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package gitops
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, got, 0o600))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+// TestReadData tests reading ConfigMap data from the supported manifest layouts.
+func TestReadData(t *testing.T) {
+	t.Run("plain manifest", func(t *testing.T) {
+		data, err := ReadData(readFixture(t, "rbac-cm.yaml"), "argocd-rbac-cm")
+		require.NoError(t, err)
+		assert.Equal(t, "role:readonly", data["policy.default"])
+		assert.Equal(t, "[groups, email]", data["scopes"])
+		assert.Contains(t, data["policy.csv"], "g, alice, role:platform\n")
+	})
+
+	t.Run("multi-document manifest", func(t *testing.T) {
+		data, err := ReadData(readFixture(t, "install.yaml"), "argocd-cm")
+		require.NoError(t, err)
+		assert.Equal(t, "apiKey", data["accounts.ci"])
+
+		data, err = ReadData(readFixture(t, "install.yaml"), "argocd-rbac-cm")
+		require.NoError(t, err)
+		assert.Equal(t, "g, ci, role:deployer", data["policy.csv"])
+	})
+
+	t.Run("json 6902 patch", func(t *testing.T) {
+		data, err := ReadData(readFixture(t, "rbac-json6902.yaml"), "argocd-rbac-cm")
+		require.NoError(t, err)
+		assert.Equal(t, "g, alice, role:admin\ng, bob, role:admin\n", data["policy.csv"])
+		assert.Equal(t, "role:readonly", data["policy.default"])
+	})
+
+	t.Run("configmap not defined", func(t *testing.T) {
+		_, err := ReadData(readFixture(t, "rbac-cm.yaml"), "argocd-cm")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not define ConfigMap 'argocd-cm'")
+	})
+}
+
+// TestEditData tests in-place edits against golden files.
+func TestEditData(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		golden    string
+		configMap string
+		set       map[string]string
+		remove    []string
+	}{
+		{
+			name:      "replace policy in plain manifest",
+			input:     "rbac-cm.yaml",
+			golden:    "rbac-cm.set.golden",
+			configMap: "argocd-rbac-cm",
+			set: map[string]string{
+				"policy.csv": "# Platform team\np, role:platform, applications, *, */*, allow\ng, my-org:platform, role:platform\n\ng, alice, role:platform\ng, bob, role:platform\n",
+			},
+		},
+		{
+			name:      "add and remove keys in multi-document manifest",
+			input:     "install.yaml",
+			golden:    "install.accounts.golden",
+			configMap: "argocd-cm",
+			set:       map[string]string{"accounts.alice": "apiKey, login"},
+			remove:    []string{"accounts.ci"},
+		},
+		{
+			name:      "strip chomping is kept",
+			input:     "install.yaml",
+			golden:    "install.policy.golden",
+			configMap: "argocd-rbac-cm",
+			set:       map[string]string{"policy.csv": "g, ci, role:deployer\ng, alice, role:deployer"},
+		},
+		{
+			name:      "quoted key and wide indentation in kustomize patch",
+			input:     "rbac-patch.yaml",
+			golden:    "rbac-patch.set.golden",
+			configMap: "argocd-rbac-cm",
+			set:       map[string]string{"policy.csv": "p, role:deployer, applications, sync, */*, allow\n"},
+		},
+		{
+			name:      "json 6902 patch",
+			input:     "rbac-json6902.yaml",
+			golden:    "rbac-json6902.set.golden",
+			configMap: "argocd-rbac-cm",
+			set: map[string]string{
+				"policy.csv": "g, alice, role:admin\n",
+				"scopes":     "[groups]",
+			},
+			remove: []string{"policy.default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := readFixture(t, tt.input)
+			out, err := EditData(input, tt.configMap, tt.set, tt.remove)
+			require.NoError(t, err)
+			assertGolden(t, tt.golden, out)
+
+			data, err := ReadData(out, tt.configMap)
+			require.NoError(t, err)
+			for key, value := range tt.set {
+				assert.Equal(t, value, data[key])
+			}
+			for _, key := range tt.remove {
+				assert.NotContains(t, data, key)
+			}
+		})
+	}
+
+	t.Run("unchanged values leave the file untouched", func(t *testing.T) {
+		input := readFixture(t, "rbac-patch.yaml")
+		data, err := ReadData(input, "argocd-rbac-cm")
+		require.NoError(t, err)
+
+		out, err := EditData(input, "argocd-rbac-cm", data, nil)
+		require.NoError(t, err)
+		assert.Equal(t, string(input), string(out))
+	})
+}
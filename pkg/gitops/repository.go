@@ -0,0 +1,203 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	gitCommand = "git"
+
+	// Identity used for commits unless GIT_AUTHOR_*/GIT_COMMITTER_* are set in the environment.
+	defaultAuthorName  = "baton-argo-cd"
+	defaultAuthorEmail = "baton-argo-cd@localhost"
+)
+
+// ErrPushRejected is returned when the remote rejects a push, typically because the branch moved
+// since the checkout. Retrying from a fresh checkout is safe.
+var ErrPushRejected = errors.New("push rejected by remote")
+
+// Repository is the Git repository holding the manifests of Argo CD's own configuration,
+// for installations whose ConfigMaps are managed by Argo CD or another GitOps controller.
+type Repository struct {
+	url                string
+	branch             string
+	reviewBranchPrefix string
+	paths              map[string]string
+}
+
+// NewRepository creates a Repository. Paths maps ConfigMap names to the manifest file, relative to the
+// repository root, that defines them. When reviewBranchPrefix is set, every change is pushed to a new
+// branch starting with it instead of to the base branch.
+func NewRepository(url string, branch string, reviewBranchPrefix string, paths map[string]string) *Repository {
+	managed := make(map[string]string, len(paths))
+	for name, path := range paths {
+		if path != "" {
+			managed[name] = path
+		}
+	}
+	return &Repository{
+		url:                url,
+		branch:             branch,
+		reviewBranchPrefix: reviewBranchPrefix,
+		paths:              managed,
+	}
+}
+
+// Manages reports whether the named ConfigMap is written through the repository.
+func (r *Repository) Manages(name string) bool {
+	_, ok := r.paths[name]
+	return ok
+}
+
+// ReviewBranches reports whether changes are pushed to review branches, taking effect only once merged.
+func (r *Repository) ReviewBranches() bool {
+	return r.reviewBranchPrefix != ""
+}
+
+// Checkout is a temporary working copy of the repository's base branch.
+type Checkout struct {
+	repo *Repository
+	dir  string
+}
+
+// Checkout clones the base branch into a temporary directory. Callers must Close it.
+func (r *Repository) Checkout(ctx context.Context) (*Checkout, error) {
+	dir, err := os.MkdirTemp("", "baton-argo-cd-gitops-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkout directory: %w", err)
+	}
+
+	if _, err := runGit(ctx, "", "clone", "--quiet", "--depth=1", "--branch", r.branch, "--", r.url, dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to clone configuration repository: %w", err)
+	}
+
+	return &Checkout{repo: r, dir: dir}, nil
+}
+
+// Close removes the working copy.
+func (c *Checkout) Close() error {
+	return os.RemoveAll(c.dir)
+}
+
+// Read returns the manifest file defining the named ConfigMap.
+func (c *Checkout) Read(name string) ([]byte, error) {
+	path, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest for %s: %w", name, err)
+	}
+	return content, nil
+}
+
+// Write replaces the manifest file defining the named ConfigMap.
+func (c *Checkout) Write(name string, content []byte) error {
+	path, err := c.path(name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat manifest for %s: %w", name, err)
+	}
+	if err := os.WriteFile(path, content, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write manifest for %s: %w", name, err)
+	}
+	return nil
+}
+
+// CommitAndPush commits all changes and pushes them, returning the branch that received the commit.
+func (c *Checkout) CommitAndPush(ctx context.Context, message string) (string, error) {
+	if _, err := runGit(ctx, c.dir, "add", "--all"); err != nil {
+		return "", fmt.Errorf("failed to stage changes: %w", err)
+	}
+
+	if _, err := runGit(ctx, c.dir,
+		"-c", "user.name="+defaultAuthorName,
+		"-c", "user.email="+defaultAuthorEmail,
+		"commit", "--quiet", "--message", message,
+	); err != nil {
+		return "", fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	branch := c.repo.branch
+	if c.repo.reviewBranchPrefix != "" {
+		suffix, err := randomSuffix()
+		if err != nil {
+			return "", err
+		}
+		branch = c.repo.reviewBranchPrefix + time.Now().UTC().Format("20060102-150405") + "-" + suffix
+	}
+
+	if output, err := runGit(ctx, c.dir, "push", "--quiet", "origin", "HEAD:refs/heads/"+branch); err != nil {
+		if strings.Contains(output, "[rejected]") || strings.Contains(output, "non-fast-forward") || strings.Contains(output, "fetch first") {
+			return "", fmt.Errorf("%w: %w", ErrPushRejected, err)
+		}
+		return "", fmt.Errorf("failed to push to %s: %w", branch, err)
+	}
+
+	return branch, nil
+}
+
+// path resolves the manifest file of a ConfigMap, refusing paths that escape the working copy.
+func (c *Checkout) path(name string) (string, error) {
+	rel, ok := c.repo.paths[name]
+	if !ok {
+		return "", fmt.Errorf("no manifest path configured for %s", name)
+	}
+	path := filepath.Join(c.dir, filepath.FromSlash(rel))
+	if !strings.HasPrefix(path, c.dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("manifest path %q for %s is outside the repository", rel, name)
+	}
+	return path, nil
+}
+
+// runGit runs a git command in dir and returns its combined stderr, which git uses for progress and errors.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, gitCommand, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return stderr.String(), fmt.Errorf("git %s failed: %w, stderr: %s", subcommand(args), err, stderr.String())
+	}
+
+	return stderr.String(), nil
+}
+
+// subcommand returns the git subcommand of args, skipping `-c key=value` options.
+// Only the subcommand is reported in errors, since arguments may contain credentials.
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+func randomSuffix() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate branch name: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
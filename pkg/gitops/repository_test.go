@@ -0,0 +1,125 @@
+package gitops
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBareRepository creates a bare repository whose main branch holds the given files.
+func newBareRepository(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath(gitCommand); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "config.git")
+	work := filepath.Join(root, "work")
+
+	git(t, root, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	git(t, root, "init", "--quiet", "--initial-branch=main", work)
+	for name, content := range files {
+		path := filepath.Join(work, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	git(t, work, "add", "--all")
+	git(t, work, "-c", "user.name=test", "-c", "user.email=test@localhost", "commit", "--quiet", "--message", "initial")
+	git(t, work, "push", "--quiet", bare, "main")
+
+	return bare
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(gitCommand, args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return string(output)
+}
+
+// TestRepository_CommitAndPush tests writing a manifest and pushing it to the base branch or a review branch.
+func TestRepository_CommitAndPush(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]string{"argocd/rbac-cm.yaml": "data:\n  policy.csv: \"\"\n"}
+
+	t.Run("base branch", func(t *testing.T) {
+		bare := newBareRepository(t, files)
+		repo := NewRepository(bare, "main", "", map[string]string{"argocd-rbac-cm": "argocd/rbac-cm.yaml", "argocd-cm": ""})
+		assert.True(t, repo.Manages("argocd-rbac-cm"))
+		assert.False(t, repo.Manages("argocd-cm"))
+
+		checkout, err := repo.Checkout(ctx)
+		require.NoError(t, err)
+		defer checkout.Close()
+
+		content, err := checkout.Read("argocd-rbac-cm")
+		require.NoError(t, err)
+		assert.Equal(t, files["argocd/rbac-cm.yaml"], string(content))
+
+		require.NoError(t, checkout.Write("argocd-rbac-cm", []byte("data:\n  policy.csv: g, alice, role:admin\n")))
+		branch, err := checkout.CommitAndPush(ctx, "grant alice\n")
+		require.NoError(t, err)
+		assert.Equal(t, "main", branch)
+
+		assert.Equal(t, "data:\n  policy.csv: g, alice, role:admin\n", git(t, bare, "show", "main:argocd/rbac-cm.yaml"))
+		assert.Equal(t, "grant alice", strings.TrimSpace(git(t, bare, "log", "-1", "--format=%B", "main")))
+	})
+
+	t.Run("review branch", func(t *testing.T) {
+		bare := newBareRepository(t, files)
+		repo := NewRepository(bare, "main", "baton/", map[string]string{"argocd-rbac-cm": "argocd/rbac-cm.yaml"})
+
+		checkout, err := repo.Checkout(ctx)
+		require.NoError(t, err)
+		defer checkout.Close()
+
+		require.NoError(t, checkout.Write("argocd-rbac-cm", []byte("data: {}\n")))
+		branch, err := checkout.CommitAndPush(ctx, "change\n")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(branch, "baton/"))
+
+		assert.Equal(t, files["argocd/rbac-cm.yaml"], git(t, bare, "show", "main:argocd/rbac-cm.yaml"))
+		assert.Equal(t, "data: {}\n", git(t, bare, "show", branch+":argocd/rbac-cm.yaml"))
+	})
+
+	t.Run("stale checkout is rejected", func(t *testing.T) {
+		bare := newBareRepository(t, files)
+		repo := NewRepository(bare, "main", "", map[string]string{"argocd-rbac-cm": "argocd/rbac-cm.yaml"})
+
+		first, err := repo.Checkout(ctx)
+		require.NoError(t, err)
+		defer first.Close()
+		second, err := repo.Checkout(ctx)
+		require.NoError(t, err)
+		defer second.Close()
+
+		require.NoError(t, first.Write("argocd-rbac-cm", []byte("data: {a: b}\n")))
+		_, err = first.CommitAndPush(ctx, "first\n")
+		require.NoError(t, err)
+
+		require.NoError(t, second.Write("argocd-rbac-cm", []byte("data: {c: d}\n")))
+		_, err = second.CommitAndPush(ctx, "second\n")
+		assert.ErrorIs(t, err, ErrPushRejected)
+	})
+
+	t.Run("path outside the repository", func(t *testing.T) {
+		bare := newBareRepository(t, files)
+		repo := NewRepository(bare, "main", "", map[string]string{"argocd-rbac-cm": "../rbac-cm.yaml"})
+
+		checkout, err := repo.Checkout(ctx)
+		require.NoError(t, err)
+		defer checkout.Close()
+
+		_, err = checkout.Read("argocd-rbac-cm")
+		assert.ErrorContains(t, err, "outside the repository")
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/conductorone/baton-argo-cd/pkg/client"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -15,15 +16,41 @@ import (
 
 // MockClient is a mock implementation of the ArgoCD client for testing.
 type MockClient struct {
-	GetAccountsFunc            func(ctx context.Context) ([]*client.Account, error)
-	GetRolesFunc               func(ctx context.Context) ([]*client.Role, annotations.Annotations, error)
-	GetDefaultRoleFunc         func(ctx context.Context) (string, error)
-	CreateAccountFunc          func(ctx context.Context, username string, password string) (*client.Account, annotations.Annotations, error)
-	UpdateUserRoleFunc         func(ctx context.Context, userID string, roleID string) (annotations.Annotations, error)
-	RemoveUserRoleFunc         func(ctx context.Context, userID string, roleID string) (annotations.Annotations, error)
-	GetSubjectsForAllRolesFunc func(ctx context.Context) (map[string][]string, error)
-	GetUserRolesFunc           func(ctx context.Context, userID string) ([]string, error)
-	GetRoleUsersFunc           func(ctx context.Context, roleID string) ([]*client.Account, error)
+	GetAccountsFunc              func(ctx context.Context) ([]*client.Account, error)
+	GetDexUsersFunc              func(ctx context.Context) ([]*client.DexUser, error)
+	GetPasswordPatternFunc       func(ctx context.Context) (string, error)
+	GetRolesFunc                 func(ctx context.Context) ([]*client.Role, annotations.Annotations, error)
+	GetGroupsFunc                func(ctx context.Context) ([]*client.Group, error)
+	CreateRoleFunc               func(ctx context.Context, role string, policies []*client.PolicyDefinition) (annotations.Annotations, error)
+	DeleteRoleFunc               func(ctx context.Context, role string) (annotations.Annotations, error)
+	AddRolePolicyFunc            func(ctx context.Context, policy *client.PolicyDefinition) (bool, error)
+	RemoveRolePolicyFunc         func(ctx context.Context, policy *client.PolicyDefinition) (bool, error)
+	CloneRoleFunc                func(ctx context.Context, role string, newRole string) (*client.RoleChange, error)
+	RenameRoleFunc               func(ctx context.Context, role string, newRole string) (*client.RoleChange, error)
+	MigrateRoleMembersFunc       func(ctx context.Context, role string, newRole string) (*client.RoleChange, error)
+	GetDefaultRoleFunc           func(ctx context.Context) (string, error)
+	CreateAccountFunc            func(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error)
+	CreateAccountTokenFunc       func(ctx context.Context, account string, expiresIn time.Duration, id string) (string, annotations.Annotations, error)
+	DeleteAccountTokenFunc       func(ctx context.Context, account string, id string) (annotations.Annotations, error)
+	UpdateUserRoleFunc           func(ctx context.Context, userID string, roleID string) (annotations.Annotations, error)
+	RemoveUserRoleFunc           func(ctx context.Context, userID string, roleID string) (annotations.Annotations, error)
+	GetSubjectsForAllRolesFunc   func(ctx context.Context) (map[string][]string, error)
+	GetUserRolesFunc             func(ctx context.Context, userID string) ([]string, error)
+	GetRoleUsersFunc             func(ctx context.Context, roleID string) ([]*client.Account, error)
+	GetRoleBindingsFunc          func(ctx context.Context, roleID string) ([]*client.PolicyBinding, error)
+	GetControllerOwnerFunc       func(ctx context.Context, kind string, name string) (*client.ControllerOwner, error)
+	DetectInstallationFunc       func(ctx context.Context) (*client.Installation, error)
+	GetProjectsFunc              func(ctx context.Context) ([]*client.Project, error)
+	GetProjectFunc               func(ctx context.Context, name string) (*client.Project, error)
+	CreateProjectTokenFunc       func(ctx context.Context, project string, role string, expiresIn time.Duration, id string) (string, annotations.Annotations, error)
+	DeleteProjectTokenFunc       func(ctx context.Context, project string, role string, issuedAt int64) (annotations.Annotations, error)
+	GetApplicationsFunc          func(ctx context.Context) ([]*client.Application, error)
+	GetPolicyEvaluatorFunc       func(ctx context.Context) (*client.PolicyEvaluator, error)
+	GetApplicationSetsFunc       func(ctx context.Context) ([]*client.ApplicationSet, error)
+	GetClustersFunc              func(ctx context.Context) ([]*client.Cluster, error)
+	GetRepositoriesFunc          func(ctx context.Context) ([]*client.Repository, error)
+	GetRepositoryCredentialsFunc func(ctx context.Context) ([]*client.RepositoryCredential, error)
+	GetSecurityPostureFunc       func(ctx context.Context) (*client.SecurityPosture, error)
 }
 
 // GetAccounts calls the mock method if it is defined.
@@ -34,6 +61,22 @@ func (m *MockClient) GetAccounts(ctx context.Context) ([]*client.Account, error)
 	return nil, nil
 }
 
+// GetDexUsers calls the mock method if it is defined.
+func (m *MockClient) GetDexUsers(ctx context.Context) ([]*client.DexUser, error) {
+	if m.GetDexUsersFunc != nil {
+		return m.GetDexUsersFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetPasswordPattern calls the mock method if it is defined.
+func (m *MockClient) GetPasswordPattern(ctx context.Context) (string, error) {
+	if m.GetPasswordPatternFunc != nil {
+		return m.GetPasswordPatternFunc(ctx)
+	}
+	return "", nil
+}
+
 // GetRoles calls the mock method if it is defined.
 func (m *MockClient) GetRoles(ctx context.Context) ([]*client.Role, annotations.Annotations, error) {
 	if m.GetRolesFunc != nil {
@@ -42,14 +85,94 @@ func (m *MockClient) GetRoles(ctx context.Context) ([]*client.Role, annotations.
 	return nil, nil, nil
 }
 
+// GetGroups calls the mock method if it is defined.
+func (m *MockClient) GetGroups(ctx context.Context) ([]*client.Group, error) {
+	if m.GetGroupsFunc != nil {
+		return m.GetGroupsFunc(ctx)
+	}
+	return nil, nil
+}
+
 // CreateAccount calls the mock method if it is defined.
-func (m *MockClient) CreateAccount(ctx context.Context, username string, password string) (*client.Account, annotations.Annotations, error) {
+func (m *MockClient) CreateAccount(ctx context.Context, username string, password string, email string) (*client.Account, annotations.Annotations, error) {
 	if m.CreateAccountFunc != nil {
-		return m.CreateAccountFunc(ctx, username, password)
+		return m.CreateAccountFunc(ctx, username, password, email)
 	}
 	return nil, nil, nil
 }
 
+// CreateAccountToken calls the mock method if it is defined.
+func (m *MockClient) CreateAccountToken(ctx context.Context, account string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+	if m.CreateAccountTokenFunc != nil {
+		return m.CreateAccountTokenFunc(ctx, account, expiresIn, id)
+	}
+	return "", nil, nil
+}
+
+// DeleteAccountToken calls the mock method if it is defined.
+func (m *MockClient) DeleteAccountToken(ctx context.Context, account string, id string) (annotations.Annotations, error) {
+	if m.DeleteAccountTokenFunc != nil {
+		return m.DeleteAccountTokenFunc(ctx, account, id)
+	}
+	return nil, nil
+}
+
+// CreateRole calls the mock method if it is defined.
+func (m *MockClient) CreateRole(ctx context.Context, role string, policies []*client.PolicyDefinition) (annotations.Annotations, error) {
+	if m.CreateRoleFunc != nil {
+		return m.CreateRoleFunc(ctx, role, policies)
+	}
+	return nil, nil
+}
+
+// DeleteRole calls the mock method if it is defined.
+func (m *MockClient) DeleteRole(ctx context.Context, role string) (annotations.Annotations, error) {
+	if m.DeleteRoleFunc != nil {
+		return m.DeleteRoleFunc(ctx, role)
+	}
+	return nil, nil
+}
+
+// AddRolePolicy calls the mock method if it is defined.
+func (m *MockClient) AddRolePolicy(ctx context.Context, policy *client.PolicyDefinition) (bool, error) {
+	if m.AddRolePolicyFunc != nil {
+		return m.AddRolePolicyFunc(ctx, policy)
+	}
+	return false, nil
+}
+
+// RemoveRolePolicy calls the mock method if it is defined.
+func (m *MockClient) RemoveRolePolicy(ctx context.Context, policy *client.PolicyDefinition) (bool, error) {
+	if m.RemoveRolePolicyFunc != nil {
+		return m.RemoveRolePolicyFunc(ctx, policy)
+	}
+	return false, nil
+}
+
+// CloneRole calls the mock method if it is defined.
+func (m *MockClient) CloneRole(ctx context.Context, role string, newRole string) (*client.RoleChange, error) {
+	if m.CloneRoleFunc != nil {
+		return m.CloneRoleFunc(ctx, role, newRole)
+	}
+	return &client.RoleChange{}, nil
+}
+
+// RenameRole calls the mock method if it is defined.
+func (m *MockClient) RenameRole(ctx context.Context, role string, newRole string) (*client.RoleChange, error) {
+	if m.RenameRoleFunc != nil {
+		return m.RenameRoleFunc(ctx, role, newRole)
+	}
+	return &client.RoleChange{}, nil
+}
+
+// MigrateRoleMembers calls the mock method if it is defined.
+func (m *MockClient) MigrateRoleMembers(ctx context.Context, role string, newRole string) (*client.RoleChange, error) {
+	if m.MigrateRoleMembersFunc != nil {
+		return m.MigrateRoleMembersFunc(ctx, role, newRole)
+	}
+	return &client.RoleChange{}, nil
+}
+
 // GetDefaultRole calls the mock method if it is defined.
 func (m *MockClient) GetDefaultRole(ctx context.Context) (string, error) {
 	if m.GetDefaultRoleFunc != nil {
@@ -90,6 +213,118 @@ func (m *MockClient) GetRoleUsers(ctx context.Context, roleID string) ([]*client
 	return nil, nil
 }
 
+// GetRoleBindings calls the mock method if it is defined.
+func (m *MockClient) GetRoleBindings(ctx context.Context, roleID string) ([]*client.PolicyBinding, error) {
+	if m.GetRoleBindingsFunc != nil {
+		return m.GetRoleBindingsFunc(ctx, roleID)
+	}
+	return nil, nil
+}
+
+// GetControllerOwner calls the mock method if it is defined.
+func (m *MockClient) GetControllerOwner(ctx context.Context, kind string, name string) (*client.ControllerOwner, error) {
+	if m.GetControllerOwnerFunc != nil {
+		return m.GetControllerOwnerFunc(ctx, kind, name)
+	}
+	return nil, nil
+}
+
+// DetectInstallation calls the mock method if it is defined.
+func (m *MockClient) DetectInstallation(ctx context.Context) (*client.Installation, error) {
+	if m.DetectInstallationFunc != nil {
+		return m.DetectInstallationFunc(ctx)
+	}
+	return &client.Installation{Type: client.InstallConfigMap, Namespace: client.ArgocdNamespace}, nil
+}
+
+// GetProjects calls the mock method if it is defined.
+func (m *MockClient) GetProjects(ctx context.Context) ([]*client.Project, error) {
+	if m.GetProjectsFunc != nil {
+		return m.GetProjectsFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetProject calls the mock method if it is defined.
+func (m *MockClient) GetProject(ctx context.Context, name string) (*client.Project, error) {
+	if m.GetProjectFunc != nil {
+		return m.GetProjectFunc(ctx, name)
+	}
+	return nil, nil
+}
+
+// CreateProjectToken calls the mock method if it is defined.
+func (m *MockClient) CreateProjectToken(ctx context.Context, project string, role string, expiresIn time.Duration, id string) (string, annotations.Annotations, error) {
+	if m.CreateProjectTokenFunc != nil {
+		return m.CreateProjectTokenFunc(ctx, project, role, expiresIn, id)
+	}
+	return "", nil, nil
+}
+
+// DeleteProjectToken calls the mock method if it is defined.
+func (m *MockClient) DeleteProjectToken(ctx context.Context, project string, role string, issuedAt int64) (annotations.Annotations, error) {
+	if m.DeleteProjectTokenFunc != nil {
+		return m.DeleteProjectTokenFunc(ctx, project, role, issuedAt)
+	}
+	return nil, nil
+}
+
+// GetApplications calls the mock method if it is defined.
+func (m *MockClient) GetApplications(ctx context.Context) ([]*client.Application, error) {
+	if m.GetApplicationsFunc != nil {
+		return m.GetApplicationsFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetPolicyEvaluator calls the mock method if it is defined.
+func (m *MockClient) GetPolicyEvaluator(ctx context.Context) (*client.PolicyEvaluator, error) {
+	if m.GetPolicyEvaluatorFunc != nil {
+		return m.GetPolicyEvaluatorFunc(ctx)
+	}
+	return client.NewPolicyEvaluator("", "")
+}
+
+// GetApplicationSets calls the mock method if it is defined.
+func (m *MockClient) GetApplicationSets(ctx context.Context) ([]*client.ApplicationSet, error) {
+	if m.GetApplicationSetsFunc != nil {
+		return m.GetApplicationSetsFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetClusters calls the mock method if it is defined.
+func (m *MockClient) GetClusters(ctx context.Context) ([]*client.Cluster, error) {
+	if m.GetClustersFunc != nil {
+		return m.GetClustersFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetRepositories calls the mock method if it is defined.
+func (m *MockClient) GetRepositories(ctx context.Context) ([]*client.Repository, error) {
+	if m.GetRepositoriesFunc != nil {
+		return m.GetRepositoriesFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetRepositoryCredentials calls the mock method if it is defined.
+func (m *MockClient) GetRepositoryCredentials(ctx context.Context) ([]*client.RepositoryCredential, error) {
+	if m.GetRepositoryCredentialsFunc != nil {
+		return m.GetRepositoryCredentialsFunc(ctx)
+	}
+	return nil, nil
+}
+
+// GetSecurityPosture calls the mock method if it is defined.
+func (m *MockClient) GetSecurityPosture(ctx context.Context) (*client.SecurityPosture, error) {
+	if m.GetSecurityPostureFunc != nil {
+		return m.GetSecurityPostureFunc(ctx)
+	}
+	return &client.SecurityPosture{}, nil
+}
+
 // GetSubjectsForAllRoles calls the mock method if it is defined.
 
 func (m *MockClient) GetSubjectsForAllRoles(ctx context.Context) (map[string][]string, error) {